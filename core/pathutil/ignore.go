@@ -0,0 +1,200 @@
+package pathutil
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/0chain/errors"
+)
+
+type ignoreRule struct {
+	pattern string
+	negate  bool
+	dirOnly bool
+	re      *regexp.Regexp
+}
+
+// IgnoreRules is a set of gitignore-style include/exclude rules.
+// Supported syntax:
+//   - blank lines and lines starting with "#" are skipped.
+//   - "*" matches anything but "/", "?" matches a single character but "/", "[a-z]" matches a character class.
+//   - "**" matches any number of directories: "**/build", "logs/**" and "a/**/b".
+//   - a leading "!" negates the rule, re-including paths excluded by previous rules.
+//   - a trailing "/" makes the rule match directories only.
+//   - a pattern containing a "/" other than a trailing one is anchored to the root, otherwise it matches at any level.
+//
+// The last matching rule wins, and the content of an excluded directory is always excluded.
+type IgnoreRules struct {
+	rules []ignoreRule
+}
+
+// NewIgnoreRules creates ignore rules from the given patterns.
+//   - patterns are the rules, one pattern per element, with the syntax of a .gitignore line.
+func NewIgnoreRules(patterns ...string) (*IgnoreRules, error) {
+	ir := &IgnoreRules{}
+	for _, p := range patterns {
+		if err := ir.Add(p); err != nil {
+			return nil, err
+		}
+	}
+	return ir, nil
+}
+
+// ParseIgnoreRules reads ignore rules, one per line, from r.
+//   - r is the reader of the rules, usually an opened .zboxignore file.
+func ParseIgnoreRules(r io.Reader) (*IgnoreRules, error) {
+	ir := &IgnoreRules{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if err := ir.Add(scanner.Text()); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read ignore rules")
+	}
+	return ir, nil
+}
+
+// Add appends a rule. Blank and comment lines are skipped.
+//   - line is the rule, with the syntax of a .gitignore line.
+func (ir *IgnoreRules) Add(line string) error {
+	line = strings.TrimRight(line, "\r")
+	if !strings.HasSuffix(line, "\\ ") {
+		line = strings.TrimRight(line, " \t")
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+
+	rule := ignoreRule{pattern: line}
+	switch {
+	case strings.HasPrefix(line, "!"):
+		rule.negate = true
+		line = line[1:]
+	case strings.HasPrefix(line, "\\!"), strings.HasPrefix(line, "\\#"):
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return nil
+	}
+
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	expr := "^"
+	if !anchored && !strings.HasPrefix(line, "**/") {
+		expr += "(?:.*/)?"
+	}
+	expr += globToRegexp(line) + "$"
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return errors.New("invalid_ignore_rule", "invalid ignore rule "+rule.pattern+": "+err.Error())
+	}
+	rule.re = re
+	ir.rules = append(ir.rules, rule)
+	return nil
+}
+
+// Len returns the number of rules.
+func (ir *IgnoreRules) Len() int {
+	if ir == nil {
+		return 0
+	}
+	return len(ir.rules)
+}
+
+// Match reports whether the path is excluded by the rules.
+// A nil IgnoreRules matches nothing.
+//   - p is the slash-separated path relative to the root of the rules. A leading "/" is ignored.
+//   - isDir tells whether the path is a directory.
+func (ir *IgnoreRules) Match(p string, isDir bool) bool {
+	if ir.Len() == 0 {
+		return false
+	}
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return false
+	}
+	// Excluded parent directories exclude their whole content.
+	for i := strings.Index(p, "/"); i >= 0; {
+		if ir.match(p[:i], true) {
+			return true
+		}
+		next := strings.Index(p[i+1:], "/")
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return ir.match(p, isDir)
+}
+
+func (ir *IgnoreRules) match(p string, isDir bool) bool {
+	ignored := false
+	for _, rule := range ir.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.re.MatchString(p) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// globToRegexp converts a glob with "**" support into a regular expression.
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				atStart := i == 0 || glob[i-1] == '/'
+				atEnd := i+2 == len(glob) || glob[i+2] == '/'
+				if atStart && atEnd {
+					i++
+					if i+1 < len(glob) {
+						// "**/" matches zero or more directories
+						i++
+						sb.WriteString("(?:.*/)?")
+					} else {
+						// trailing "**" matches everything inside
+						sb.WriteString(".*")
+					}
+					continue
+				}
+			}
+			sb.WriteString("[^/]*")
+		case '?':
+			sb.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(regexp.QuoteMeta(string(c)))
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, "\\", "\\\\") + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				sb.WriteString(regexp.QuoteMeta(string(glob[i])))
+			}
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return sb.String()
+}
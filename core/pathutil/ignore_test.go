@@ -0,0 +1,68 @@
+package pathutil
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIgnoreRules(t *testing.T) {
+	rules, err := ParseIgnoreRules(strings.NewReader(`
+# dependencies
+node_modules/
+*.tmp
+!keep.tmp
+/build
+docs/**/draft-*.md
+logs/**
+\#notes
+`))
+	require.NoError(t, err)
+	require.Equal(t, 7, rules.Len())
+
+	tests := []struct {
+		name  string
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"dir only rule matches dir", "/node_modules", true, true},
+		{"dir only rule matches nested dir", "/web/node_modules", true, true},
+		{"dir only rule skips file", "/node_modules", false, false},
+		{"content of excluded dir", "/web/node_modules/react/index.js", false, true},
+		{"glob at any level", "/a/b/c.tmp", false, true},
+		{"negation", "/a/keep.tmp", false, false},
+		{"anchored rule at root", "/build", true, true},
+		{"anchored rule not nested", "/src/build", true, false},
+		{"double star in the middle", "/docs/draft-1.md", false, true},
+		{"double star nested", "/docs/a/b/draft-2.md", false, true},
+		{"double star no match", "/docs/a/final.md", false, false},
+		{"trailing double star", "/logs/2024/app.log", false, true},
+		{"escaped hash", "/#notes", false, true},
+		{"comment is not a rule", "/dependencies", false, false},
+		{"root", "/", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, rules.Match(tt.path, tt.isDir))
+		})
+	}
+}
+
+func TestIgnoreRules_Nil(t *testing.T) {
+	var rules *IgnoreRules
+	require.False(t, rules.Match("/a.tmp", false))
+	require.Equal(t, 0, rules.Len())
+}
+
+func TestNewIgnoreRules(t *testing.T) {
+	rules, err := NewIgnoreRules("**/cache", "file-?.[ch]", "[!a]x")
+	require.NoError(t, err)
+	require.True(t, rules.Match("cache", true))
+	require.True(t, rules.Match("a/b/cache/x", false))
+	require.True(t, rules.Match("src/file-1.c", false))
+	require.False(t, rules.Match("src/file-10.c", false))
+	require.True(t, rules.Match("bx", false))
+	require.False(t, rules.Match("ax", false))
+}
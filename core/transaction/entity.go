@@ -2,6 +2,7 @@
 package transaction
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -318,6 +319,18 @@ func retriveFromTable(table map[string]map[string]int64, txnName, toAddress stri
 	return fees, nil
 }
 
+// LookupFee returns the fee of the transaction in a fee table returned by GetFeesTable.
+//   - table: the fee table.
+//   - txn: the transaction, its data must be a SmartContractTxnData.
+func LookupFee(table map[string]map[string]int64, txn *Transaction) (uint64, error) {
+	var sn SmartContractTxnData
+	err := json.Unmarshal([]byte(txn.TransactionData), &sn)
+	if err != nil {
+		return 0, err
+	}
+	return retriveFromTable(table, strings.ToLower(sn.Name), txn.ToClientID)
+}
+
 // EstimateFee estimates transaction fee
 func EstimateFee(txn *Transaction, miners []string, reqPercent ...float32) (uint64, error) {
 	const minReqNum = 3
@@ -327,18 +340,6 @@ func EstimateFee(txn *Transaction, miners []string, reqPercent ...float32) (uint
 		reqN = int(reqPercent[0] * float32(len(miners)))
 	}
 
-	txData := txn.TransactionData
-
-	var sn SmartContractTxnData
-	err := json.Unmarshal([]byte(txData), &sn)
-	if err != nil {
-		return 0, err
-	}
-
-	txnName := sn.Name
-	txnName = strings.ToLower(txnName)
-	toAddress := txn.ToClientID
-
 	reqN = util.MaxInt(minReqNum, reqN)
 	reqN = util.MinInt(reqN, len(miners))
 	randomMiners := util.Shuffle(miners)[:reqN]
@@ -349,11 +350,7 @@ func EstimateFee(txn *Transaction, miners []string, reqPercent ...float32) (uint
 		cachedObj, ok := cached.(*cachedObject)
 		if ok {
 			table := cachedObj.Value.(map[string]map[string]int64)
-			fees, err := retriveFromTable(table, txnName, toAddress)
-			if err != nil {
-				return 0, err
-			}
-			return fees, nil
+			return LookupFee(table, txn)
 		}
 	}
 
@@ -362,7 +359,7 @@ func EstimateFee(txn *Transaction, miners []string, reqPercent ...float32) (uint
 		return 0, err
 	}
 
-	fees, err := retriveFromTable(table, txnName, toAddress)
+	fees, err := LookupFee(table, txn)
 	if err != nil {
 		return 0, err
 	}
@@ -377,6 +374,11 @@ func EstimateFee(txn *Transaction, miners []string, reqPercent ...float32) (uint
 
 // GetFeesTable get fee tables
 func GetFeesTable(miners []string, reqPercent ...float32) (map[string]map[string]int64, error) {
+	return GetFeesTableContext(context.Background(), miners, reqPercent...)
+}
+
+// GetFeesTableContext get fee tables, the requests to the miners are canceled with ctx
+func GetFeesTableContext(ctx context.Context, miners []string, reqPercent ...float32) (map[string]map[string]int64, error) {
 	const minReqNum = 3
 	var reqN int
 
@@ -401,7 +403,9 @@ func GetFeesTable(miners []string, reqPercent ...float32) (map[string]map[string
 			defer wg.Done()
 
 			url := minerUrl + FEES_TABLE
-			req, err := util.NewHTTPGetRequest(url)
+			ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+			defer cancel()
+			req, err := util.NewHTTPGetRequestContext(ctx, url)
 			if err != nil {
 				errC <- fmt.Errorf("create request failed, url: %s, err: %v", url, err)
 				return
@@ -0,0 +1,211 @@
+package transaction
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/0chain/errors"
+	"github.com/0chain/gosdk/core/zcncrypto"
+)
+
+const envelopeVersion = 1
+
+// OfflineTxnParams are the fields of a transaction built without a connection to the network.
+type OfflineTxnParams struct {
+	// ClientID and PublicKey of the wallet signing the transaction.
+	ClientID  string
+	PublicKey string
+
+	// ChainID of the network the transaction is broadcast to.
+	ChainID string
+
+	// Nonce of the transaction, the nonce of the client on the network plus one.
+	Nonce int64
+
+	// Fee of the transaction, e.g. estimated with EstimateFee on a connected machine.
+	Fee uint64
+
+	// CreationDate of the transaction in unix seconds, now if zero. It's signed with the
+	// transaction, and the miners reject the transactions created too long ago, so it must
+	// be close to the time of the broadcast.
+	CreationDate int64
+}
+
+func newOfflineTxn(p OfflineTxnParams) (*Transaction, error) {
+	if p.ClientID == "" || p.PublicKey == "" || p.ChainID == "" {
+		return nil, errors.New("offline_txn", "client id, public key and chain id are required")
+	}
+	if p.Nonce < 1 {
+		return nil, errors.New("offline_txn", "the nonce must be positive")
+	}
+	txn := NewTransactionEntity(p.ClientID, p.ChainID, p.PublicKey, p.Nonce)
+	if p.CreationDate > 0 {
+		txn.CreationDate = p.CreationDate
+	}
+	return txn, nil
+}
+
+// NewOfflineSmartContractTxn creates an unsigned smart contract transaction.
+//   - p: the fields of the transaction.
+//   - address: the smart contract address.
+//   - sn: the smart contract method and input.
+//   - value: the tokens sent to the smart contract.
+func NewOfflineSmartContractTxn(p OfflineTxnParams, address string, sn SmartContractTxnData, value uint64) (*Transaction, error) {
+	txn, err := newOfflineTxn(p)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(sn)
+	if err != nil {
+		return nil, errors.Wrap(err, "create smart contract failed due to invalid data")
+	}
+	txn.TransactionType = TxnTypeSmartContract
+	txn.ToClientID = address
+	txn.TransactionData = string(data)
+	txn.setValueAndFee(value, p.Fee)
+	return txn, nil
+}
+
+// NewOfflineSendTxn creates an unsigned transaction sending tokens to another client.
+//   - p: the fields of the transaction.
+//   - toClientID: the receiver of the tokens.
+//   - value: the tokens to send.
+//   - desc: the description of the transfer.
+func NewOfflineSendTxn(p OfflineTxnParams, toClientID string, value uint64, desc string) (*Transaction, error) {
+	txn, err := newOfflineTxn(p)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(SmartContractTxnData{Name: "transfer", InputArgs: map[string]string{"note": desc}})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not serialize description to transaction_data")
+	}
+	txn.TransactionType = TxnTypeSend
+	txn.ToClientID = toClientID
+	txn.TransactionData = string(data)
+	txn.setValueAndFee(value, p.Fee)
+	return txn, nil
+}
+
+// TxnEnvelope carries a transaction between the machine building it, the air-gapped machine
+// signing it and the machine broadcasting it.
+type TxnEnvelope struct {
+	Version int `json:"v"`
+
+	// SignatureScheme of the signing wallet, "bls0chain" or "ed25519".
+	SignatureScheme string `json:"scheme"`
+
+	Txn *Transaction `json:"txn"`
+}
+
+// NewTxnEnvelope creates an envelope of the transaction.
+//   - txn: the transaction, e.g. created with NewOfflineSmartContractTxn.
+//   - signatureScheme: the signature scheme of the wallet of the transaction.
+func NewTxnEnvelope(txn *Transaction, signatureScheme string) (*TxnEnvelope, error) {
+	e := &TxnEnvelope{Version: envelopeVersion, SignatureScheme: signatureScheme, Txn: txn}
+	if err := e.validate(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// DecodeTxnEnvelope decodes an envelope encoded by Encode, or its json.
+//   - s: the encoded envelope.
+func DecodeTxnEnvelope(s string) (*TxnEnvelope, error) {
+	s = strings.TrimSpace(s)
+	data := []byte(s)
+	if !strings.HasPrefix(s, "{") {
+		var err error
+		if data, err = base64.RawURLEncoding.DecodeString(s); err != nil {
+			return nil, errors.Wrap(err, "invalid transaction envelope encoding")
+		}
+	}
+	e := &TxnEnvelope{}
+	if err := json.Unmarshal(data, e); err != nil {
+		return nil, errors.Wrap(err, "invalid transaction envelope")
+	}
+	if err := e.validate(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *TxnEnvelope) validate() error {
+	if e.Version != envelopeVersion {
+		return errors.Newf("txn_envelope", "unsupported envelope version %d", e.Version)
+	}
+	switch e.SignatureScheme {
+	case "bls0chain", "ed25519":
+	default:
+		return errors.Newf("txn_envelope", "unknown signature scheme %q", e.SignatureScheme)
+	}
+	if e.Txn == nil {
+		return errors.New("txn_envelope", "missing transaction")
+	}
+	return nil
+}
+
+// Encode returns the envelope as base64url encoded json, which fits in a QR code and
+// is safe to copy in urls and files.
+func (e *TxnEnvelope) Encode() (string, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// Sign computes the hash of the transaction and signs it with the wallet, which must be
+// the client of the transaction. It doesn't need a connection to the network.
+//   - w: the wallet of the client of the transaction.
+func (e *TxnEnvelope) Sign(w *zcncrypto.Wallet) error {
+	if w.ClientID != e.Txn.ClientID {
+		return errors.New("txn_envelope", "the wallet is not the client of the transaction")
+	}
+	if len(w.Keys) == 0 || w.Keys[0].PublicKey != e.Txn.PublicKey {
+		return errors.New("txn_envelope", "the wallet keys don't match the public key of the transaction")
+	}
+	return e.Txn.ComputeHashAndSign(func(hash string) (string, error) {
+		return w.Sign(hash, e.SignatureScheme)
+	})
+}
+
+// Verify checks the hash and the signature of the transaction.
+func (e *TxnEnvelope) Verify() error {
+	if e.Txn.Signature == "" {
+		return errors.New("txn_envelope", "the transaction isn't signed")
+	}
+	ok, err := e.Txn.VerifySigWith(e.Txn.PublicKey, func(publicKey, signature, hash string) (bool, error) {
+		ss := zcncrypto.NewSignatureScheme(e.SignatureScheme)
+		if err := ss.SetPublicKey(publicKey); err != nil {
+			return false, err
+		}
+		return ss.Verify(signature, hash)
+	})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("txn_envelope", "invalid transaction signature")
+	}
+	return nil
+}
+
+// Broadcast verifies the signed transaction and sends it to the miners. It doesn't need a wallet.
+//   - miners: the urls of the miners.
+func (e *TxnEnvelope) Broadcast(miners []string) error {
+	if err := e.Verify(); err != nil {
+		return err
+	}
+	return SendTransactionSync(e.Txn, miners)
+}
+
+// Confirm waits for the confirmation of the broadcast transaction by the sharders with VerifyTransaction.
+// The client config must be initialized, see conf.InitClientConfig.
+//   - sharders: the urls of the sharders.
+//
+// returns the transaction stored in the block, with its status and output.
+func (e *TxnEnvelope) Confirm(sharders []string) (*Transaction, error) {
+	return VerifyTransaction(e.Txn.Hash, sharders)
+}
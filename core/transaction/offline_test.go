@@ -0,0 +1,82 @@
+package transaction
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/0chain/gosdk/core/zcncrypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOfflineTxnEnvelope(t *testing.T) {
+	w, err := zcncrypto.NewSignatureScheme("ed25519").GenerateKeys()
+	require.NoError(t, err)
+
+	params := OfflineTxnParams{
+		ClientID:  w.ClientID,
+		PublicKey: w.ClientKey,
+		ChainID:   "chain",
+		Nonce:     7,
+		Fee:       10,
+	}
+	txn, err := NewOfflineSendTxn(params, "receiver", 100, "cold storage")
+	require.NoError(t, err)
+	require.Equal(t, TxnTypeSend, txn.TransactionType)
+	require.Empty(t, txn.Signature)
+
+	// online machine: build and encode the unsigned transaction
+	e, err := NewTxnEnvelope(txn, "ed25519")
+	require.NoError(t, err)
+	encoded, err := e.Encode()
+	require.NoError(t, err)
+
+	// air-gapped machine: sign the transaction
+	e, err = DecodeTxnEnvelope(encoded)
+	require.NoError(t, err)
+	require.Error(t, e.Verify())
+	require.NoError(t, e.Sign(w))
+	require.NoError(t, e.Verify())
+	encoded, err = e.Encode()
+	require.NoError(t, err)
+
+	// online machine: broadcast the signed transaction without the wallet
+	var received Transaction
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		rw.Write([]byte("{}")) //nolint: errcheck
+	}))
+	defer server.Close()
+
+	e, err = DecodeTxnEnvelope(encoded)
+	require.NoError(t, err)
+	require.NoError(t, e.Broadcast([]string{server.URL}))
+	require.Equal(t, e.Txn.Hash, received.Hash)
+	require.Equal(t, int64(7), received.TransactionNonce)
+	require.Equal(t, uint64(10), received.TransactionFee)
+
+	// a tampered transaction isn't broadcast
+	e.Txn.Value = 1000
+	require.Error(t, e.Broadcast([]string{server.URL}))
+}
+
+func TestOfflineTxnValidation(t *testing.T) {
+	_, err := NewOfflineSmartContractTxn(OfflineTxnParams{ClientID: "client", ChainID: "chain", Nonce: 1}, "address", SmartContractTxnData{Name: "lock"}, 0)
+	require.Error(t, err)
+
+	txn, err := NewOfflineSmartContractTxn(OfflineTxnParams{ClientID: "client", PublicKey: "key", ChainID: "chain", Nonce: 1, CreationDate: 100},
+		"address", SmartContractTxnData{Name: "lock"}, 5)
+	require.NoError(t, err)
+	require.Equal(t, int64(100), txn.CreationDate)
+	require.Equal(t, `{"name":"lock","input":null}`, txn.TransactionData)
+
+	_, err = NewTxnEnvelope(txn, "rsa")
+	require.Error(t, err)
+	_, err = DecodeTxnEnvelope(`{"v":2,"scheme":"ed25519","txn":{}}`)
+	require.Error(t, err)
+
+	e, err := NewTxnEnvelope(txn, "bls0chain")
+	require.NoError(t, err)
+	require.Error(t, e.Sign(&zcncrypto.Wallet{ClientID: "other"}))
+}
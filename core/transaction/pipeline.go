@@ -0,0 +1,262 @@
+package transaction
+
+import (
+	"context"
+	"sync"
+
+	"github.com/0chain/errors"
+)
+
+const (
+	defaultPipelineSigners   = 4
+	defaultPipelineInFlight  = 32
+	defaultPipelineVerifiers = 8
+)
+
+// PipelineResult is the outcome of a transaction submitted by a Pipeline.
+type PipelineResult struct {
+	// Index of the transaction in the submitted batch.
+	Index int
+
+	// Nonce assigned to the transaction.
+	Nonce int64
+
+	// Hash of the transaction.
+	Hash string
+
+	// Txn is the confirmed transaction, with its status and output, or the
+	// submitted transaction if it wasn't confirmed.
+	Txn *Transaction
+
+	// Err is nil if the transaction is confirmed successfully.
+	Err error
+}
+
+// PipelineOption configures a Pipeline.
+type PipelineOption func(*Pipeline)
+
+// WithPipelineSigners sets the number of transactions signed in parallel.
+//   - n: the number of signing workers.
+func WithPipelineSigners(n int) PipelineOption {
+	return func(p *Pipeline) {
+		if n > 0 {
+			p.signers = n
+		}
+	}
+}
+
+// WithPipelineInFlight sets the maximum number of transactions sent to the miners and not yet confirmed.
+//   - n: the maximum number of in-flight transactions.
+func WithPipelineInFlight(n int) PipelineOption {
+	return func(p *Pipeline) {
+		if n > 0 {
+			p.inFlight = n
+		}
+	}
+}
+
+// WithPipelineVerifiers sets the number of transactions verified in parallel.
+//   - n: the number of verification workers.
+func WithPipelineVerifiers(n int) PipelineOption {
+	return func(p *Pipeline) {
+		if n > 0 {
+			p.verifiers = n
+		}
+	}
+}
+
+// Pipeline submits batches of transactions of a client. The transactions are assigned
+// sequential nonces, signed in parallel, sent to the miners in nonce order with a bounded
+// number of transactions in flight, and confirmed concurrently with an OptimisticVerifier.
+type Pipeline struct {
+	miners    []string
+	signers   int
+	inFlight  int
+	verifiers int
+
+	sign   SignFunc
+	send   func(txn *Transaction, miners []string) error
+	verify func(txnHash string) (*Transaction, error)
+}
+
+// NewPipeline creates a pipeline submitting the transactions to the miners and confirming them on the sharders.
+//   - miners: the urls of the miners.
+//   - sharders: the urls of the sharders.
+//   - sign: the signing function of the client of the transactions.
+//   - opts: the options of the pipeline.
+func NewPipeline(miners, sharders []string, sign SignFunc, opts ...PipelineOption) *Pipeline {
+	p := &Pipeline{
+		miners:    miners,
+		signers:   defaultPipelineSigners,
+		inFlight:  defaultPipelineInFlight,
+		verifiers: defaultPipelineVerifiers,
+		sign:      sign,
+		send:      SendTransactionSync,
+		verify: func(txnHash string) (*Transaction, error) {
+			// the verifier keeps the sharders of the last verification, it's not shared.
+			return NewOptimisticVerifier(sharders).VerifyTransactionOptimistic(txnHash)
+		},
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// errPipelineSkipped is reported to the jobs which aren't signed since the pipeline is aborted.
+var errPipelineSkipped = errors.New("pipeline_skipped", "the transaction isn't signed")
+
+type pipelineJob struct {
+	index  int
+	txn    *Transaction
+	signed chan error
+}
+
+// Submit assigns the nonces startNonce, startNonce+1, ... to the transactions and runs them
+// through the pipeline. The fee of the transactions must be set. Since the miners don't include
+// a transaction until the previous nonces are included, a transaction failing to be signed or sent
+// aborts the transactions not sent yet, which are reported with ErrPipelineAborted.
+//   - ctx: the context of the batch, canceling it aborts the transactions not sent yet.
+//   - txns: the transactions of the batch.
+//   - startNonce: the nonce of the first transaction, usually the nonce of the client plus one.
+//
+// returns the channel of the results, one per transaction in completion order, closed once all are reported.
+func (p *Pipeline) Submit(ctx context.Context, txns []*Transaction, startNonce int64) <-chan PipelineResult {
+	results := make(chan PipelineResult, len(txns))
+
+	jobs := make([]*pipelineJob, len(txns))
+	for i, txn := range txns {
+		txn.TransactionNonce = startNonce + int64(i)
+		jobs[i] = &pipelineJob{index: i, txn: txn, signed: make(chan error, 1)}
+	}
+
+	var (
+		guard   sync.Mutex
+		aborted error
+		stop    = make(chan struct{})
+	)
+	abort := func(err error) {
+		guard.Lock()
+		defer guard.Unlock()
+		if aborted == nil {
+			aborted = err
+			close(stop)
+		}
+	}
+	abortedBy := func() error {
+		guard.Lock()
+		defer guard.Unlock()
+		if aborted == nil && ctx.Err() != nil {
+			aborted = ctx.Err()
+		}
+		return aborted
+	}
+
+	// every job receives a single value on its signed channel, so that the
+	// transaction isn't modified once the result of the job is reported.
+	signCh := make(chan *pipelineJob)
+	go func() {
+		defer close(signCh)
+		for i, job := range jobs {
+			select {
+			case signCh <- job:
+				continue
+			case <-ctx.Done():
+			case <-stop:
+			}
+			for _, skipped := range jobs[i:] {
+				skipped.signed <- errPipelineSkipped
+			}
+			return
+		}
+	}()
+	for i := 0; i < p.signers; i++ {
+		go func() {
+			for job := range signCh {
+				job.signed <- job.txn.ComputeHashAndSign(p.sign)
+			}
+		}()
+	}
+
+	verifyCh := make(chan *pipelineJob, p.inFlight)
+	var verifiers sync.WaitGroup
+	sem := make(chan struct{}, p.inFlight)
+	for i := 0; i < p.verifiers; i++ {
+		verifiers.Add(1)
+		go func() {
+			defer verifiers.Done()
+			for job := range verifyCh {
+				results <- p.confirm(job)
+				<-sem
+			}
+		}()
+	}
+
+	// a single sender sends the transactions in nonce order, so that no transaction
+	// is sent after the failure of a previous nonce.
+	go func() {
+		defer func() {
+			verifiers.Wait()
+			close(results)
+		}()
+		defer close(verifyCh)
+
+		for _, job := range jobs {
+			err := <-job.signed
+			if err != nil && err != errPipelineSkipped {
+				err = errors.Wrap(err, "sign_transaction_failed")
+				abort(err)
+				results <- p.result(job, err)
+				continue
+			}
+			acquired := false
+			if err == nil {
+				select {
+				case sem <- struct{}{}:
+					acquired = true
+				case <-ctx.Done():
+				}
+			}
+			if err = abortedBy(); err != nil {
+				if acquired {
+					<-sem
+				}
+				results <- p.result(job, errors.Throw(ErrPipelineAborted, err.Error()))
+				continue
+			}
+
+			if err = p.send(job.txn, p.miners); err != nil {
+				abort(err)
+				<-sem
+				results <- p.result(job, err)
+				continue
+			}
+			verifyCh <- job
+		}
+	}()
+
+	return results
+}
+
+func (p *Pipeline) confirm(job *pipelineJob) PipelineResult {
+	txn, err := p.verify(job.txn.Hash)
+	if err != nil {
+		return p.result(job, errors.Throw(ErrPipelineNotConfirmed, err.Error()))
+	}
+	r := p.result(job, nil)
+	r.Txn = txn
+	if txn.Status != 1 {
+		r.Err = errors.New("transaction_failed", txn.TransactionOutput)
+	}
+	return r
+}
+
+func (p *Pipeline) result(job *pipelineJob, err error) PipelineResult {
+	return PipelineResult{
+		Index: job.index,
+		Nonce: job.txn.TransactionNonce,
+		Hash:  job.txn.Hash,
+		Txn:   job.txn,
+		Err:   err,
+	}
+}
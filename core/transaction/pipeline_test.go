@@ -0,0 +1,117 @@
+package transaction
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakePipelineMiner struct {
+	mu     sync.Mutex
+	nonces []int64
+	reject int64
+}
+
+func (m *fakePipelineMiner) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	txn := &Transaction{}
+	if err := json.NewDecoder(r.Body).Decode(txn); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if txn.TransactionNonce == m.reject {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("invalid nonce")) //nolint: errcheck
+		return
+	}
+	m.nonces = append(m.nonces, txn.TransactionNonce)
+	w.Write([]byte("{}")) //nolint: errcheck
+}
+
+func newPipelineTxns(n int) []*Transaction {
+	txns := make([]*Transaction, n)
+	for i := range txns {
+		txns[i] = NewTransactionEntity("client", "chain", "key", 0)
+	}
+	return txns
+}
+
+func collectPipelineResults(results <-chan PipelineResult) map[int64]PipelineResult {
+	byNonce := make(map[int64]PipelineResult)
+	for r := range results {
+		byNonce[r.Nonce] = r
+	}
+	return byNonce
+}
+
+func TestPipelineSubmit(t *testing.T) {
+	miner := &fakePipelineMiner{}
+	server := httptest.NewServer(miner)
+	defer server.Close()
+
+	var verified sync.Map
+	p := NewPipeline([]string{server.URL}, nil, func(msg string) (string, error) {
+		return "signature:" + msg, nil
+	}, WithPipelineInFlight(3), WithPipelineSigners(2))
+	p.verify = func(txnHash string) (*Transaction, error) {
+		verified.Store(txnHash, true)
+		return &Transaction{Hash: txnHash, Status: 1}, nil
+	}
+
+	results := collectPipelineResults(p.Submit(context.Background(), newPipelineTxns(20), 5))
+	require.Len(t, results, 20)
+	for nonce := int64(5); nonce < 25; nonce++ {
+		r, ok := results[nonce]
+		require.True(t, ok)
+		require.NoError(t, r.Err)
+		require.Equal(t, int(nonce-5), r.Index)
+		require.Equal(t, 1, r.Txn.Status)
+		_, ok = verified.Load(r.Hash)
+		require.True(t, ok)
+	}
+	require.Len(t, miner.nonces, 20)
+}
+
+func TestPipelineAbortsAfterFailure(t *testing.T) {
+	miner := &fakePipelineMiner{reject: 3}
+	server := httptest.NewServer(miner)
+	defer server.Close()
+
+	// the transactions are sent in nonce order whatever the number of transactions in flight
+	p := NewPipeline([]string{server.URL}, nil, func(msg string) (string, error) {
+		return "signature", nil
+	})
+	p.verify = func(txnHash string) (*Transaction, error) {
+		return &Transaction{Hash: txnHash, Status: 2, TransactionOutput: "insufficient balance"}, nil
+	}
+
+	results := collectPipelineResults(p.Submit(context.Background(), newPipelineTxns(5), 1))
+	require.Len(t, results, 5)
+	require.EqualError(t, results[1].Err, "transaction_failed: insufficient balance")
+	require.Error(t, results[3].Err)
+	require.False(t, errors.Is(results[3].Err, ErrPipelineAborted))
+	require.True(t, errors.Is(results[4].Err, ErrPipelineAborted))
+	require.True(t, errors.Is(results[5].Err, ErrPipelineAborted))
+	require.Equal(t, []int64{1, 2}, miner.nonces)
+}
+
+func TestPipelineCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p := NewPipeline(nil, nil, func(msg string) (string, error) {
+		return "signature", nil
+	})
+
+	results := collectPipelineResults(p.Submit(ctx, newPipelineTxns(3), 1))
+	require.Len(t, results, 3)
+	for _, r := range results {
+		require.True(t, errors.Is(r.Err, ErrPipelineAborted))
+	}
+}
@@ -22,3 +22,8 @@ type Transaction struct {
 	OutputHash        string `json:"txn_output_hash"`
 	Status            int    `json:"transaction_status"`
 }
+
+func (t *Transaction) setValueAndFee(value, fee uint64) {
+	t.Value = value
+	t.TransactionFee = fee
+}
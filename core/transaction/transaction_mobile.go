@@ -80,3 +80,8 @@ func (t *Transaction) MarshalJSON() ([]byte, error) {
 
 	return json.Marshal(wrapper)
 }
+
+func (t *Transaction) setValueAndFee(value, fee uint64) {
+	t.Value = strconv.FormatUint(value, 10)
+	t.TransactionFee = strconv.FormatUint(fee, 10)
+}
@@ -16,4 +16,10 @@ var (
 
 	// ErrTooLessConfirmation too less sharder to confirm transaction
 	ErrTooLessConfirmation = errors.New("[txn] too less sharders to confirm it")
+
+	// ErrPipelineAborted the transaction wasn't sent because a previous nonce failed or the batch was canceled
+	ErrPipelineAborted = errors.New("[txn] pipeline aborted before sending the transaction")
+
+	// ErrPipelineNotConfirmed the transaction was sent but couldn't be confirmed
+	ErrPipelineNotConfirmed = errors.New("[txn] transaction sent but not confirmed")
 )
@@ -129,7 +129,13 @@ func NewHTTPGetRequestContext(ctx context.Context, url string) (*GetRequest, err
 	return gr, nil
 }
 
+// NewHTTPPostRequest create a PostRequest instance with 60s timeout
 func NewHTTPPostRequest(url string, data interface{}) (*PostRequest, error) {
+	return NewHTTPPostRequestContext(context.Background(), url, data)
+}
+
+// NewHTTPPostRequestContext create a PostRequest with context, url and the json of data, with 60s timeout
+func NewHTTPPostRequestContext(ctx context.Context, url string, data interface{}) (*PostRequest, error) {
 	pr := &PostRequest{}
 	jsonByte, err := json.Marshal(data)
 	if err != nil {
@@ -143,7 +149,7 @@ func NewHTTPPostRequest(url string, data interface{}) (*PostRequest, error) {
 	req.Header.Set("Access-Control-Allow-Origin", "*")
 	pr.url = url
 	pr.req = req
-	pr.ctx, pr.cncl = context.WithTimeout(context.Background(), time.Second*60)
+	pr.ctx, pr.cncl = context.WithTimeout(ctx, time.Second*60)
 	return pr, nil
 }
 
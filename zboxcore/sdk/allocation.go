@@ -98,6 +98,8 @@ type BlobberAllocationStats struct {
 		ReadPrice    int    `json:"ReadPrice"`
 		WritePrice   int    `json:"WritePrice"`
 	} `json:"Terms"`
+	// Health is the health of the blobber measured by the downloads of the sdk, nil if it was never measured.
+	Health *BlobberHealth `json:"Health,omitempty"`
 }
 
 // ConsolidatedFileMeta represents the file meta data.
@@ -292,11 +294,16 @@ type Allocation struct {
 	mutex                   *sync.Mutex
 	commitMutex             *sync.Mutex
 	downloadProgressMap     map[string]*DownloadRequest
+	pausedDownloads         map[string]*DownloadRequest
 	downloadRequests        []*DownloadRequest
 	repairRequestInProgress *RepairRequest
 	initialized             bool
 	checkStatus             bool
 	readFree                bool
+	versioning              *VersioningOptions
+	softDelete              bool
+	uploadBandwidth         *BandwidthLimiter
+	downloadBandwidth       *BandwidthLimiter
 	// conseususes
 	consensusThreshold int
 	fullconsensus      int
@@ -385,7 +392,8 @@ func (a *Allocation) GetStats() *AllocationStats {
 	return a.Stats
 }
 
-// GetBlobberStats returns the statistics of the blobbers in the allocation.
+// GetBlobberStats returns the statistics of the blobbers in the allocation,
+// along with their health measured by the downloads of the sdk.
 func (a *Allocation) GetBlobberStats() map[string]*BlobberAllocationStats {
 	numList := len(a.Blobbers)
 	wg := &sync.WaitGroup{}
@@ -398,6 +406,7 @@ func (a *Allocation) GetBlobberStats() map[string]*BlobberAllocationStats {
 	result := make(map[string]*BlobberAllocationStats, len(a.Blobbers))
 	for i := 0; i < numList; i++ {
 		resp := <-rspCh
+		resp.Health = blobberHealth.Health(resp.BlobberID)
 		result[resp.BlobberURL] = resp
 	}
 	return result
@@ -415,9 +424,19 @@ func (a *Allocation) InitAllocation() {
 	a.repairChan = make(chan *RepairRequest, 1)
 	a.ctx, a.ctxCancelF = context.WithCancel(context.Background())
 	a.downloadProgressMap = make(map[string]*DownloadRequest)
+	a.pausedDownloads = make(map[string]*DownloadRequest)
 	a.downloadRequests = make([]*DownloadRequest, 0, 100)
 	a.mutex = &sync.Mutex{}
 	a.commitMutex = &sync.Mutex{}
+	for _, b := range a.Blobbers {
+		zboxutil.RegisterBlobber(b.Baseurl, b.ID)
+	}
+	if a.uploadBandwidth == nil {
+		a.uploadBandwidth = NewBandwidthLimiter(0)
+	}
+	if a.downloadBandwidth == nil {
+		a.downloadBandwidth = NewBandwidthLimiter(0)
+	}
 	a.fullconsensus, a.consensusThreshold = a.getConsensuses()
 	a.readFree = true
 	if a.ReadPriceRange.Max > 0 {
@@ -817,6 +836,12 @@ func (a *Allocation) StartChunkedUpload(workdir, localPath string,
 		options = append(options, WithThumbnail(buf))
 	}
 
+	if isUpdate && !isRepair {
+		if err := a.keepVersions([]string{remotePath}); err != nil {
+			return err
+		}
+	}
+
 	connectionId := zboxutil.NewConnectionId()
 	now := time.Now()
 	ChunkedUpload, err := CreateChunkedUpload(a.ctx, workdir,
@@ -830,6 +855,10 @@ func (a *Allocation) StartChunkedUpload(workdir, localPath string,
 	logger.Logger.Info("[StartChunkedUpload]", zap.String("allocation_id", a.ID),
 		zap.Duration("CreateChunkedUpload", elapsedCreateChunkedUpload))
 
+	if srcPath, ok := ChunkedUpload.findDuplicate(); ok {
+		return ChunkedUpload.copyDuplicate(srcPath)
+	}
+
 	return ChunkedUpload.Start()
 }
 
@@ -981,13 +1010,25 @@ func (a *Allocation) RepairRequired(remotepath string) (zboxutil.Uint128, zboxut
 // The operations are performed in parallel.
 //   - operations: the operations to perform.
 //   - opts: the options of the multi operation as operation functions that customize the multi operation.
-func (a *Allocation) DoMultiOperation(operations []OperationRequest, opts ...MultiOperationOption) error {
+func (a *Allocation) DoMultiOperation(operations []OperationRequest, opts ...MultiOperationOption) (err error) {
 	if len(operations) == 0 {
 		return nil
 	}
 	if !a.isInitialized() {
 		return notInitialized
 	}
+	ctx, span := zboxutil.StartSpan(a.ctx, "DoMultiOperation",
+		zboxutil.Attribute("allocation.id", a.ID), zboxutil.Attribute("operations", len(operations)))
+	defer func() { zboxutil.EndSpan(span, err) }()
+	if a.softDelete {
+		var err error
+		if operations, err = a.trashOperations(operations); err != nil {
+			return err
+		}
+	}
+	if err := a.keepVersions(versionedPaths(operations)); err != nil {
+		return err
+	}
 	connectionID := zboxutil.NewConnectionId()
 	var mo MultiOperation
 	mo.allocationObj = a
@@ -997,7 +1038,7 @@ func (a *Allocation) DoMultiOperation(operations []OperationRequest, opts ...Mul
 		mo.operationMask = zboxutil.NewUint128(0)
 		mo.maskMU = &sync.Mutex{}
 		mo.connectionID = connectionID
-		mo.ctx, mo.ctxCncl = context.WithCancelCause(a.ctx)
+		mo.ctx, mo.ctxCncl = context.WithCancelCause(ctx)
 		mo.Consensus = Consensus{
 			RWMutex:         &sync.RWMutex{},
 			consensusThresh: a.consensusThreshold,
@@ -1081,7 +1122,11 @@ func (a *Allocation) DoMultiOperation(operations []OperationRequest, opts ...Mul
 				cancelLock.Lock()
 				CancelOpCtx[op.FileMeta.RemotePath] = mo.ctxCncl
 				cancelLock.Unlock()
-				operation, newConnectionID, err = NewUploadOperation(mo.ctx, op.Workdir, mo.allocationObj, mo.connectionID, op.FileMeta, op.FileReader, false, op.IsWebstreaming, op.IsRepair, op.DownloadFile, op.StreamUpload, op.Opts...)
+				var uo *UploadOperation
+				uo, newConnectionID, err = NewUploadOperation(mo.ctx, op.Workdir, mo.allocationObj, mo.connectionID, op.FileMeta, op.FileReader, false, op.IsWebstreaming, op.IsRepair, op.DownloadFile, op.StreamUpload, op.Opts...)
+				if err == nil {
+					operation = mo.deduplicateUpload(uo)
+				}
 
 			case constants.FileOperationDelete:
 				if op.Mask != nil {
@@ -1332,6 +1377,8 @@ func (a *Allocation) generateDownloadRequest(
 	downloadReq.sig = a.sig
 	downloadReq.allocOwnerPubKey = a.OwnerPublicKey
 	downloadReq.ctx, downloadReq.ctxCncl = context.WithCancel(a.ctx)
+	downloadReq.pauseDone = make(chan struct{})
+	downloadReq.bandwidthLimiters = a.downloadBandwidthLimiters()
 	downloadReq.fileHandler = fileHandler
 	downloadReq.localFilePath = localFilePath
 	downloadReq.remotefilepath = remotePath
@@ -1394,8 +1441,11 @@ func (a *Allocation) addAndGenerateDownloadRequest(
 	for _, opt := range downloadReqOpts {
 		opt(downloadReq)
 	}
+	downloadReq.verifyDownload = verifyDownload
+	downloadReq.downloadOpts = downloadReqOpts
 	downloadReq.workdir = filepath.Join(downloadReq.workdir, ".zcn")
 	a.downloadProgressMap[remotePath] = downloadReq
+	delete(a.pausedDownloads, remotePath)
 	a.downloadRequests = append(a.downloadRequests, downloadReq)
 	if isFinal {
 		downloadOps := a.downloadRequests
@@ -1413,6 +1463,9 @@ func (a *Allocation) processReadMarker(drs []*DownloadRequest) {
 	wg := sync.WaitGroup{}
 	now := time.Now()
 
+	for _, dr := range drs {
+		dr.statusCallback = newEventStatusCallback(defaultEventBus, dr.statusCallback, dr.connectionID)
+	}
 	for _, dr := range drs {
 		wg.Add(1)
 		go func(dr *DownloadRequest) {
@@ -1777,7 +1830,33 @@ func (a *Allocation) GetRefs(path, offsetPath, updatedDate, offsetDate, fileType
 	return a.getRefs(path, "", "", offsetPath, updatedDate, offsetDate, fileType, refType, level, pageLimit)
 }
 
-func (a *Allocation) ListObjects(ctx context.Context, path, offsetPath, updatedDate, offsetDate, fileType, refType string, level, pageLimit int) <-chan ORef {
+// ListObjectsOption customizes ListObjects.
+type ListObjectsOption func(o *listObjectsOptions)
+
+type listObjectsOptions struct {
+	ignore *pathutil.IgnoreRules
+}
+
+// WithListObjectsIgnoreRules skips the refs matched by the given rules.
+// Paths are matched relative to the listed path.
+//   - rules: the gitignore-style ignore rules
+func WithListObjectsIgnoreRules(rules *pathutil.IgnoreRules) ListObjectsOption {
+	return func(o *listObjectsOptions) {
+		o.ignore = rules
+	}
+}
+
+// ListObjects lists the refs under the given path page by page and sends them over the returned channel.
+// The channel is closed once all the refs are listed, the context is canceled, or an error occurred (sent as the Err of the last ref).
+// The trash and the versions are skipped, unless the listed path is within them.
+//   - ctx: the context of the listing.
+//   - path, offsetPath, updatedDate, offsetDate, fileType, refType, level, pageLimit: see GetRefs.
+//   - opts: the options of the listing.
+func (a *Allocation) ListObjects(ctx context.Context, path, offsetPath, updatedDate, offsetDate, fileType, refType string, level, pageLimit int, opts ...ListObjectsOption) <-chan ORef {
+	var listOpts listObjectsOptions
+	for _, opt := range opts {
+		opt(&listOpts)
+	}
 	oRefChan := make(chan ORef, 1)
 	sendObjectRef := func(ref ORef) {
 		select {
@@ -1804,6 +1883,9 @@ func (a *Allocation) ListObjects(ctx context.Context, path, offsetPath, updatedD
 				return
 			}
 			for _, ref := range oRefs.Refs {
+				if skipHiddenManagedPath(path, ref.Path) || listOpts.ignore.Match(strings.TrimPrefix(ref.Path, path), ref.Type == fileref.DIRECTORY) {
+					continue
+				}
 				select {
 				// Send object content.
 				case oRefChan <- ref:
@@ -1875,8 +1957,15 @@ func (a *Allocation) GetRecentlyAddedRefs(page int, fromDate int64, pageLimit in
 // The file meta data includes the file type, name, hash, lookup hash, mime type, path, size, number of blocks, encrypted key, collaborators, actual file size, actual thumbnail hash, and actual thumbnail size.
 //   - path: the path of the file to get the meta data.
 func (a *Allocation) GetFileMeta(path string) (*ConsolidatedFileMeta, error) {
+	result, _, err := a.getFileMetaWithMask(path)
+	return result, err
+}
+
+// getFileMetaWithMask retrieves the file meta data like GetFileMeta, along with the
+// mask of the blobbers holding any version of the file.
+func (a *Allocation) getFileMetaWithMask(path string) (*ConsolidatedFileMeta, zboxutil.Uint128, error) {
 	if !a.isInitialized() {
-		return nil, notInitialized
+		return nil, zboxutil.NewUint128(0), notInitialized
 	}
 
 	result := &ConsolidatedFileMeta{}
@@ -1889,7 +1978,8 @@ func (a *Allocation) GetFileMeta(path string) (*ConsolidatedFileMeta, error) {
 	listReq.consensusThresh = a.consensusThreshold
 	listReq.ctx = a.ctx
 	listReq.remotefilepath = path
-	_, _, ref, _ := listReq.getFileConsensusFromBlobbers()
+	foundMask, deleteMask, ref, _ := listReq.getFileConsensusFromBlobbers()
+	mask := foundMask.Or(deleteMask)
 	if ref != nil {
 		result.Type = ref.Type
 		result.Name = ref.Name
@@ -1907,9 +1997,9 @@ func (a *Allocation) GetFileMeta(path string) (*ConsolidatedFileMeta, error) {
 		if result.ActualFileSize > 0 {
 			result.ActualNumBlocks = (ref.ActualFileSize + CHUNK_SIZE - 1) / CHUNK_SIZE
 		}
-		return result, nil
+		return result, mask, nil
 	}
-	return nil, errors.New("file_meta_error", "Error getting the file meta data from blobbers")
+	return nil, mask, errors.New("file_meta_error", "Error getting the file meta data from blobbers")
 }
 
 // GetFileMetaByName retrieve consolidated file metadata given its name (its full path starting from root "/").
@@ -2065,6 +2155,12 @@ func (a *Allocation) GetFileStats(path string) (map[string]*FileStats, error) {
 // The file is deleted from the allocation and the blobbers.
 //   - path: the path of the file to delete.
 func (a *Allocation) DeleteFile(path string) error {
+	if a.softDelete {
+		return a.MoveToTrash(path)
+	}
+	if err := a.keepVersions([]string{path}); err != nil {
+		return err
+	}
 	return a.deleteFile(path, a.consensusThreshold, a.fullconsensus, zboxutil.NewUint128(1).Lsh(uint64(len(a.Blobbers))).Sub64(1))
 }
 
@@ -2402,6 +2498,71 @@ func (a *Allocation) CancelDownload(remotepath string) error {
 	return errors.New("remote_path_not_found", "Invalid path. No download in progress for the path "+remotepath)
 }
 
+// PauseDownload pauses the download of a file started with a download progress storer,
+// see WithDownloadProgressStorer. The block workers are stopped, the progress of the
+// blocks written so far is saved, and the status callback receives ErrPauseDownload.
+//   - remotepath: the remote path of the file being downloaded.
+func (a *Allocation) PauseDownload(remotepath string) error {
+	a.mutex.Lock()
+	downloadReq, ok := a.downloadProgressMap[remotepath]
+	if !ok {
+		a.mutex.Unlock()
+		return errors.New("remote_path_not_found", "Invalid path. No download in progress for the path "+remotepath)
+	}
+	if downloadReq.downloadStorer == nil || downloadReq.localFilePath == "" || downloadReq.contentMode != DOWNLOAD_CONTENT_FULL {
+		a.mutex.Unlock()
+		return errors.New("download_not_resumable", "Download of "+remotepath+" has no progress storer")
+	}
+	downloadReq.isDownloadPaused = true
+	downloadReq.ctxCncl()
+	delete(a.downloadProgressMap, remotepath)
+	a.pausedDownloads[remotepath] = downloadReq
+
+	// a download still queued for the final one has no workers to stop
+	queued := false
+	for i, dr := range a.downloadRequests {
+		if dr == downloadReq {
+			a.downloadRequests = append(a.downloadRequests[:i], a.downloadRequests[i+1:]...)
+			queued = true
+			break
+		}
+	}
+	a.mutex.Unlock()
+	if queued {
+		downloadReq.errorCB(ErrPauseDownload, remotepath)
+	}
+	return nil
+}
+
+// ResumeDownload resumes a download paused by PauseDownload from its last written block,
+// with the same local file, status callback and options. The completed blocks are
+// neither downloaded nor paid again.
+//   - remotepath: the remote path of the paused file.
+func (a *Allocation) ResumeDownload(remotepath string) error {
+	a.mutex.Lock()
+	downloadReq, ok := a.pausedDownloads[remotepath]
+	a.mutex.Unlock()
+	if !ok {
+		return errors.New("remote_path_not_found", "Invalid path. No paused download for the path "+remotepath)
+	}
+
+	// wait for the workers of the paused download to stop
+	<-downloadReq.pauseDone
+	a.mutex.Lock()
+	if a.pausedDownloads[remotepath] != downloadReq {
+		a.mutex.Unlock()
+		return errors.New("remote_path_not_found", "Invalid path. No paused download for the path "+remotepath)
+	}
+	delete(a.pausedDownloads, remotepath)
+	a.mutex.Unlock()
+	if !downloadReq.skip {
+		// the download completed before the pause
+		return nil
+	}
+	return a.DownloadFile(downloadReq.localFilePath, remotepath, downloadReq.verifyDownload,
+		downloadReq.statusCallback, true, downloadReq.downloadOpts...)
+}
+
 // DownloadFromReader downloads a file from the allocation to the specified local path using the provided reader.
 // [DEPRECATED] Use DownloadFile or DownloadFromAuthTicket instead.
 func (a *Allocation) DownloadFromReader(
@@ -2751,6 +2912,7 @@ func (a *Allocation) downloadFromAuthTicket(fileHandler sys.File, authTicket str
 	downloadReq.allocOwnerID = a.Owner
 	downloadReq.allocOwnerPubKey = a.OwnerPublicKey
 	downloadReq.ctx, downloadReq.ctxCncl = context.WithCancel(a.ctx)
+	downloadReq.bandwidthLimiters = a.downloadBandwidthLimiters()
 	downloadReq.fileHandler = fileHandler
 	downloadReq.localFilePath = localFilePath
 	downloadReq.remotefilepathhash = remoteLookupHash
@@ -3176,128 +3338,6 @@ repair:
 	return alloc, hash, isRepairRequired, nil
 }
 
-func (a *Allocation) DownloadDirectory(ctx context.Context, remotePath, localPath, authTicket string, sb StatusCallback) error {
-	if len(a.Blobbers) == 0 {
-		return noBLOBBERS
-	}
-	localPath = filepath.Clean(localPath)
-	dirID := zboxutil.NewConnectionId()
-	err := sys.Files.CreateDirectory(dirID)
-	if err != nil {
-		if sb != nil {
-			sb.Error(a.ID, remotePath, OpDownload, err)
-		}
-		return err
-	}
-	defer sys.Files.RemoveAllDirectories()
-
-	oRefChan := a.ListObjects(ctx, remotePath, "", "", "", fileref.FILE, fileref.REGULAR, 0, getRefPageLimit)
-	refSlice := make([]ORef, BatchSize)
-	refIndex := 0
-	wg := &sync.WaitGroup{}
-	dirPath := path.Dir(remotePath)
-	var totalSize int
-	for oRef := range oRefChan {
-		if contextCanceled(ctx) {
-			if sb != nil {
-				sb.Error(a.ID, remotePath, OpDownload, ctx.Err())
-			}
-			return ctx.Err()
-		}
-		if oRef.Err != nil {
-			if sb != nil {
-				sb.Error(a.ID, remotePath, OpDownload, oRef.Err)
-			}
-			return oRef.Err
-		}
-		refSlice[refIndex] = oRef
-		refIndex++
-		if refIndex == BatchSize {
-			wg.Add(refIndex)
-			downloadStatusBar := &StatusBar{
-				wg: wg,
-				sb: sb,
-			}
-			for ind, ref := range refSlice {
-				fPath := ref.Path
-				if dirPath != "/" {
-					fPath = strings.TrimPrefix(ref.Path, dirPath)
-				}
-				if localPath != "" {
-					fPath = filepath.Join(localPath, fPath)
-				}
-				fh, err := sys.Files.GetFileHandler(dirID, fPath)
-				if err != nil {
-					if sb != nil {
-						sb.Error(a.ID, remotePath, OpDownload, err)
-					}
-					return err
-				}
-				if authTicket == "" {
-					_ = a.DownloadFileToFileHandler(fh, ref.Path, false, downloadStatusBar, ind == BatchSize-1, WithFileCallback(func() {
-						fh.Close() //nolint: errcheck
-					})) //nolint: errcheck
-				} else {
-					_ = a.DownloadFileToFileHandlerFromAuthTicket(fh, authTicket, ref.LookupHash, ref.Path, false, downloadStatusBar, ind == BatchSize-1, WithFileCallback(func() {
-						fh.Close() //nolint: errcheck
-					})) //nolint: errcheck
-				}
-				totalSize += int(ref.ActualFileSize)
-			}
-			wg.Wait()
-			if downloadStatusBar.err != nil {
-				return downloadStatusBar.err
-			}
-			refIndex = 0
-		}
-	}
-	if refIndex > 0 {
-		wg.Add(refIndex)
-		downloadStatusBar := &StatusBar{
-			wg: wg,
-			sb: sb,
-		}
-		for ind, ref := range refSlice[:refIndex] {
-			fPath := ref.Path
-			if dirPath != "/" {
-				fPath = strings.TrimPrefix(ref.Path, dirPath)
-			}
-			if localPath != "" {
-				fPath = filepath.Join(localPath, fPath)
-			}
-			fh, err := sys.Files.GetFileHandler(dirID, fPath)
-			if err != nil {
-				if sb != nil {
-					sb.Error(a.ID, remotePath, OpDownload, err)
-				}
-				return err
-			}
-			if authTicket == "" {
-				_ = a.DownloadFileToFileHandler(fh, ref.Path, false, downloadStatusBar, ind == refIndex-1, WithFileCallback(func() {
-					fh.Close() //nolint: errcheck
-				})) //nolint: errcheck
-			} else {
-				_ = a.DownloadFileToFileHandlerFromAuthTicket(fh, authTicket, ref.LookupHash, ref.Path, false, downloadStatusBar, ind == refIndex-1, WithFileCallback(func() {
-					fh.Close() //nolint: errcheck
-				})) //nolint: errcheck
-			}
-			totalSize += int(ref.ActualFileSize)
-		}
-		wg.Wait()
-		if downloadStatusBar.err != nil {
-			if sb != nil {
-				sb.Error(a.ID, remotePath, OpDownload, downloadStatusBar.err)
-			}
-			return downloadStatusBar.err
-		}
-	}
-	refSlice = nil
-	if sb != nil {
-		sb.Completed(a.ID, remotePath, filepath.Base(remotePath), "", totalSize, OpDownload)
-	}
-	return nil
-}
-
 // contextCanceled returns whether a context is canceled.
 func contextCanceled(ctx context.Context) bool {
 	select {
@@ -0,0 +1,231 @@
+package sdk
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/0chain/errors"
+)
+
+// defaultAdviceMaxDataShards is the maximum number of data shards evaluated by AdviseAllocation by default.
+const defaultAdviceMaxDataShards = 10
+
+// AllocationAdviceOptions describes the needs of a new allocation for AdviseAllocation.
+type AllocationAdviceOptions struct {
+	// Size of the allocation in bytes.
+	Size int64
+
+	// Durability is the number of blobbers the allocation must survive losing,
+	// i.e. the number of parity shards. It must be at least 1.
+	Durability int
+
+	// Budget is the maximum cost of the allocation in SAS, 0 for no limit.
+	Budget uint64
+
+	// ExpectedReadSize is the number of bytes expected to be read from the allocation.
+	ExpectedReadSize int64
+
+	// ExpectedWriteSize is the number of bytes expected to be written to the allocation.
+	// The allocation is sized to hold it when it is larger than Size.
+	ExpectedWriteSize int64
+
+	// MaxDataShards is the maximum number of data shards to evaluate, 10 by default.
+	MaxDataShards int
+
+	// ReadPrice is the read price range of the candidate blobbers, any price if zero.
+	ReadPrice PriceRange
+
+	// WritePrice is the write price range of the candidate blobbers, any price if zero.
+	WritePrice PriceRange
+}
+
+// AllocationAdvice is the erasure coding parameters and the blobbers recommended for a new allocation.
+type AllocationAdvice struct {
+	DataShards   int
+	ParityShards int
+	Size         int64
+
+	// BlobberIds are the recommended blobbers, the cheapest first.
+	BlobberIds []string
+
+	// ReadPrice and WritePrice are the price ranges of the recommended blobbers.
+	ReadPrice  PriceRange
+	WritePrice PriceRange
+
+	// MinLock is the minimum lock demand of the allocation in SAS, as returned by GetAllocationMinLock.
+	MinLock int64
+
+	// ReadCost is the estimated cost in SAS of reading AllocationAdviceOptions.ExpectedReadSize.
+	ReadCost int64
+
+	// Cost is the estimated total cost in SAS, MinLock + ReadCost.
+	Cost int64
+}
+
+// CreateAllocationOptions returns the options to create the advised allocation with CreateAllocationWith,
+// locking MinLock tokens in the write pool.
+func (a *AllocationAdvice) CreateAllocationOptions() CreateAllocationOptions {
+	return CreateAllocationOptions{
+		DataShards:   a.DataShards,
+		ParityShards: a.ParityShards,
+		Size:         a.Size,
+		ReadPrice:    a.ReadPrice,
+		WritePrice:   a.WritePrice,
+		Lock:         uint64(a.MinLock),
+		BlobberIds:   append([]string(nil), a.BlobberIds...),
+	}
+}
+
+// AdviseAllocation recommends the data and parity shards and the blobbers of a new allocation.
+// It evaluates the active blobbers returned by GetBlobbers, filtered by their prices, free capacity and
+// free stake, and for every number of data shards picks the cheapest blobbers. The cheapest combination
+// within the budget is returned, the one with the fewest blobbers on a tie.
+//   - options: the needs of the allocation.
+//
+// returns the advice, the cost of which is estimated with GetAllocationMinLock, and an error if no
+// combination of blobbers fits.
+func AdviseAllocation(options AllocationAdviceOptions) (*AllocationAdvice, error) {
+	blobbers, err := GetBlobbers(true, false)
+	if err != nil {
+		return nil, err
+	}
+	return adviseAllocation(blobbers, options)
+}
+
+func adviseAllocation(blobbers []*Blobber, options AllocationAdviceOptions) (*AllocationAdvice, error) {
+	if options.Durability < 1 {
+		return nil, errors.New("invalid_advice_options", "durability must be at least 1")
+	}
+	size := options.Size
+	if options.ExpectedWriteSize > size {
+		size = options.ExpectedWriteSize
+	}
+	if size <= 0 {
+		return nil, errors.New("invalid_advice_options", "size must be positive")
+	}
+	maxDataShards := options.MaxDataShards
+	if maxDataShards <= 0 {
+		maxDataShards = defaultAdviceMaxDataShards
+	}
+
+	candidates := make([]*Blobber, 0, len(blobbers))
+	for _, b := range blobbers {
+		if b.IsKilled || b.IsShutdown || b.NotAvailable || b.IsRestricted {
+			continue
+		}
+		if !inPriceRange(uint64(b.Terms.ReadPrice), options.ReadPrice) ||
+			!inPriceRange(uint64(b.Terms.WritePrice), options.WritePrice) {
+			continue
+		}
+		candidates = append(candidates, b)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.Terms.WritePrice != b.Terms.WritePrice {
+			return a.Terms.WritePrice < b.Terms.WritePrice
+		}
+		if a.Terms.ReadPrice != b.Terms.ReadPrice {
+			return a.Terms.ReadPrice < b.Terms.ReadPrice
+		}
+		return a.TotalStake > b.TotalStake
+	})
+
+	var (
+		best    *AllocationAdvice
+		lastErr error
+	)
+	for dataShards := 1; dataShards <= maxDataShards; dataShards++ {
+		advice, err := adviseShards(candidates, dataShards, options.Durability, size, options.ExpectedReadSize)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if options.Budget > 0 && uint64(advice.Cost) > options.Budget {
+			lastErr = errors.New("allocation_over_budget",
+				fmt.Sprintf("the cheapest allocation with %d data shards costs %d", dataShards, advice.Cost))
+			continue
+		}
+		if best == nil || advice.Cost < best.Cost {
+			best = advice
+		}
+	}
+	if best == nil {
+		return nil, errors.Wrap(lastErr, "no blobbers fit the allocation")
+	}
+	return best, nil
+}
+
+// adviseShards picks the cheapest blobbers able to store a shard of the allocation.
+func adviseShards(candidates []*Blobber, dataShards, parityShards int, size, readSize int64) (*AllocationAdvice, error) {
+	shardSize := int64(math.Ceil(float64(size) / float64(dataShards)))
+	n := dataShards + parityShards
+	picked := make([]*Blobber, 0, n)
+	for _, b := range candidates {
+		if int64(b.Capacity-b.Allocated) < shardSize {
+			continue
+		}
+		// the stake of the blobber must cover the write price of the shard
+		offer := float64(b.Terms.WritePrice) * float64(shardSize) / GB
+		if float64(b.TotalStake-b.TotalOffers) < offer {
+			continue
+		}
+		picked = append(picked, b)
+		if len(picked) == n {
+			break
+		}
+	}
+	if len(picked) < n {
+		return nil, errors.New("not_enough_blobbers",
+			fmt.Sprintf("%d blobbers can store a shard of %d bytes, %d required", len(picked), shardSize, n))
+	}
+
+	advice := &AllocationAdvice{
+		DataShards:   dataShards,
+		ParityShards: parityShards,
+		Size:         size,
+		BlobberIds:   make([]string, 0, n),
+		ReadPrice:    PriceRange{Min: math.MaxUint64},
+		WritePrice:   PriceRange{Min: math.MaxUint64},
+	}
+	readPrices := make([]uint64, 0, n)
+	for _, b := range picked {
+		advice.BlobberIds = append(advice.BlobberIds, string(b.ID))
+		advice.ReadPrice = extendPriceRange(advice.ReadPrice, uint64(b.Terms.ReadPrice))
+		advice.WritePrice = extendPriceRange(advice.WritePrice, uint64(b.Terms.WritePrice))
+		readPrices = append(readPrices, uint64(b.Terms.ReadPrice))
+	}
+
+	minLock, err := GetAllocationMinLock(dataShards, parityShards, size, advice.WritePrice)
+	if err != nil {
+		return nil, err
+	}
+	advice.MinLock = minLock
+
+	// a read downloads a shard from each of the dataShards cheapest blobbers
+	sort.Slice(readPrices, func(i, j int) bool { return readPrices[i] < readPrices[j] })
+	var readCost float64
+	for _, price := range readPrices[:dataShards] {
+		readCost += float64(price) * float64(readSize) / float64(dataShards) / GB
+	}
+	advice.ReadCost = int64(math.Ceil(readCost))
+	advice.Cost = advice.MinLock + advice.ReadCost
+	return advice, nil
+}
+
+func inPriceRange(price uint64, pr PriceRange) bool {
+	if pr.Min == 0 && pr.Max == 0 {
+		return true
+	}
+	return price >= pr.Min && price <= pr.Max
+}
+
+func extendPriceRange(pr PriceRange, price uint64) PriceRange {
+	if price < pr.Min {
+		pr.Min = price
+	}
+	if price > pr.Max {
+		pr.Max = price
+	}
+	return pr
+}
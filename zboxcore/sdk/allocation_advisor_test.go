@@ -0,0 +1,69 @@
+package sdk
+
+import (
+	"testing"
+
+	"github.com/0chain/gosdk/core/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdviseAllocation(t *testing.T) {
+	blobber := func(id string, writePrice common.Balance) *Blobber {
+		return &Blobber{
+			ID:         common.Key(id),
+			Terms:      Terms{ReadPrice: 10, WritePrice: writePrice},
+			Capacity:   10 * GB,
+			TotalStake: 1e12,
+		}
+	}
+	killed := blobber("killed", 1)
+	killed.IsKilled = true
+	restricted := blobber("restricted", 1)
+	restricted.IsRestricted = true
+	unstaked := blobber("unstaked", 50)
+	unstaked.TotalStake = 0
+	full := blobber("full", 50)
+	full.Allocated = full.Capacity
+	blobbers := []*Blobber{
+		blobber("b6", 200), killed, restricted, unstaked, full,
+		blobber("b1", 100), blobber("b2", 100), blobber("b3", 100), blobber("b4", 100), blobber("b5", 100),
+	}
+
+	options := AllocationAdviceOptions{
+		Size:             4 * GB,
+		Durability:       1,
+		ExpectedReadSize: 8 * GB,
+	}
+	advice, err := adviseAllocation(blobbers, options)
+	require.NoError(t, err)
+	// 4 data shards on the 5 blobbers at 100 store 5GB, more shards need b6
+	require.Equal(t, 4, advice.DataShards)
+	require.Equal(t, 1, advice.ParityShards)
+	require.Equal(t, []string{"b1", "b2", "b3", "b4", "b5"}, advice.BlobberIds)
+	require.Equal(t, PriceRange{Min: 100, Max: 100}, advice.WritePrice)
+	require.Equal(t, int64(500), advice.MinLock)
+	require.Equal(t, int64(80), advice.ReadCost)
+	require.Equal(t, int64(580), advice.Cost)
+
+	opts := advice.CreateAllocationOptions()
+	require.Equal(t, uint64(500), opts.Lock)
+	require.Equal(t, advice.BlobberIds, opts.BlobberIds)
+
+	options.MaxDataShards = 2
+	advice, err = adviseAllocation(blobbers, options)
+	require.NoError(t, err)
+	require.Equal(t, 2, advice.DataShards)
+	require.Equal(t, int64(680), advice.Cost)
+
+	options.Budget = 100
+	_, err = adviseAllocation(blobbers, options)
+	require.Error(t, err)
+
+	options = AllocationAdviceOptions{Size: GB, Durability: 10}
+	_, err = adviseAllocation(blobbers, options)
+	require.Error(t, err)
+
+	options.Durability = 0
+	_, err = adviseAllocation(blobbers, options)
+	require.Error(t, err)
+}
@@ -0,0 +1,201 @@
+package sdk
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/0chain/errors"
+	"github.com/0chain/gosdk/core/common"
+	l "github.com/0chain/gosdk/zboxcore/logger"
+)
+
+// defaultForecastInterval is the interval of the forecast points when the allocation has no time unit.
+const defaultForecastInterval = 24 * time.Hour
+
+// PlannedTransfer is an upload or a download planned on the allocation.
+type PlannedTransfer struct {
+	// At is the time of the transfer.
+	At time.Time
+
+	// Size is the size of the transferred data in bytes.
+	Size int64
+}
+
+// ForecastOptions describes the planned usage of the allocation forecast by Allocation.ForecastPools.
+type ForecastOptions struct {
+	// Uploads are the planned uploads.
+	Uploads []PlannedTransfer
+
+	// Downloads are the expected downloads.
+	Downloads []PlannedTransfer
+
+	// ReadPoolBalance is the balance of the read pool of the client, see GetReadPoolInfo.
+	ReadPoolBalance common.Balance
+
+	// Interval is the interval of the forecast points, the time unit of the allocation by default.
+	Interval time.Duration
+
+	// Now is the start of the forecast, the current time by default.
+	Now time.Time
+}
+
+// PoolForecastPoint is the projected balance of the pools at a time, in SAS.
+// A negative balance is the amount missing in the pool.
+type PoolForecastPoint struct {
+	Time          time.Time
+	WritePool     int64
+	ReadPool      int64
+	ChallengePool int64
+}
+
+// PoolForecast is the projection of the write, read and challenge pools over the lifetime of an allocation.
+type PoolForecast struct {
+	// Points are the balances of the pools from the start of the forecast to the expiration of the allocation.
+	Points []PoolForecastPoint
+
+	// WriteCost and ReadCost are the projected costs of the planned uploads and downloads in SAS.
+	WriteCost int64
+	ReadCost  int64
+
+	// WritePoolDry and ReadPoolDry are the times of the first transfer the pool can't pay, zero if none.
+	WritePoolDry time.Time
+	ReadPoolDry  time.Time
+
+	// WritePoolTopUp and ReadPoolTopUp are the tokens in SAS to lock with WritePoolLock and ReadPoolLock
+	// to pay for all the planned transfers.
+	WritePoolTopUp uint64
+	ReadPoolTopUp  uint64
+
+	// Warnings describe the pools running dry.
+	Warnings []string
+}
+
+type forecastEvent struct {
+	at       time.Time
+	write    int64
+	read     int64
+	isUpload bool
+}
+
+// ForecastPools projects the consumption of the write, read and challenge pools of the allocation from the
+// planned uploads and the expected downloads, until the expiration of the allocation.
+// An upload is paid from the write pool at the write prices of the blobbers for the remaining time units of the
+// allocation, and moved to the challenge pool which pays the blobbers until the expiration. A download is paid
+// from the read pool of the client at the read prices of the blobbers.
+//   - options: the planned usage of the allocation.
+//
+// returns the forecast, with the top-up amounts of the pools running dry, and an error if any.
+func (a *Allocation) ForecastPools(options ForecastOptions) (*PoolForecast, error) {
+	if len(a.BlobberDetails) == 0 {
+		return nil, noBLOBBERS
+	}
+	now := options.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	expiration := time.Unix(a.Expiration, 0)
+	if !expiration.After(now) {
+		return nil, errors.New("allocation_expired", "the allocation expires before the start of the forecast")
+	}
+	interval := options.Interval
+	if interval <= 0 {
+		interval = a.TimeUnit
+	}
+	if interval <= 0 {
+		interval = defaultForecastInterval
+	}
+
+	var writePrice, readPrice float64
+	for _, d := range a.BlobberDetails {
+		writePrice += float64(d.Terms.WritePrice)
+		readPrice += float64(d.Terms.ReadPrice)
+	}
+	writePrice /= float64(len(a.BlobberDetails))
+	readPrice /= float64(len(a.BlobberDetails))
+
+	f := &PoolForecast{}
+	events := make([]forecastEvent, 0, len(options.Uploads)+len(options.Downloads))
+	for _, u := range options.Uploads {
+		if u.At.Before(now) || !u.At.Before(expiration) {
+			continue
+		}
+		// the blobbers are paid for the remaining time units of the allocation
+		cost := float64(a.uploadCostForBlobber(writePrice, u.Size, a.DataShards, a.ParityShards))
+		if a.TimeUnit > 0 {
+			cost *= float64(expiration.Sub(u.At)) / float64(a.TimeUnit)
+		}
+		events = append(events, forecastEvent{at: u.At, write: int64(math.Ceil(cost)), isUpload: true})
+	}
+	for _, d := range options.Downloads {
+		if d.At.Before(now) || !d.At.Before(expiration) {
+			continue
+		}
+		// each data blobber serves its shard of the downloaded data
+		events = append(events, forecastEvent{at: d.At, read: int64(math.Ceil(readPrice * a.sizeInGB(d.Size)))})
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].at.Before(events[j].at) })
+
+	writePool := int64(a.WritePool)
+	readPool := int64(options.ReadPoolBalance)
+	var uploads []forecastEvent
+	challengePool := func(t time.Time) int64 {
+		// the challenge pool pays the blobbers linearly until the expiration
+		var balance float64
+		for _, u := range uploads {
+			balance += float64(u.write) * float64(expiration.Sub(t)) / float64(expiration.Sub(u.at))
+		}
+		return int64(math.Round(balance))
+	}
+
+	next := 0
+	for t := now; ; t = t.Add(interval) {
+		if t.After(expiration) {
+			t = expiration
+		}
+		for ; next < len(events) && !events[next].at.After(t); next++ {
+			e := events[next]
+			if e.isUpload {
+				f.WriteCost += e.write
+				if writePool >= 0 && writePool < e.write && f.WritePoolDry.IsZero() {
+					f.WritePoolDry = e.at
+				}
+				writePool -= e.write
+				uploads = append(uploads, e)
+				continue
+			}
+			f.ReadCost += e.read
+			if readPool >= 0 && readPool < e.read && f.ReadPoolDry.IsZero() {
+				f.ReadPoolDry = e.at
+			}
+			readPool -= e.read
+		}
+		f.Points = append(f.Points, PoolForecastPoint{
+			Time:          t,
+			WritePool:     writePool,
+			ReadPool:      readPool,
+			ChallengePool: challengePool(t),
+		})
+		if !t.Before(expiration) {
+			break
+		}
+	}
+
+	if writePool < 0 {
+		f.WritePoolTopUp = uint64(-writePool)
+		f.Warnings = append(f.Warnings, fmt.Sprintf(
+			"write pool of allocation %s runs dry at %s, lock %d more SAS with WritePoolLock",
+			a.ID, f.WritePoolDry.Format(time.RFC3339), f.WritePoolTopUp))
+	}
+	if readPool < 0 {
+		f.ReadPoolTopUp = uint64(-readPool)
+		f.Warnings = append(f.Warnings, fmt.Sprintf(
+			"read pool runs dry at %s, lock %d more SAS with ReadPoolLock",
+			f.ReadPoolDry.Format(time.RFC3339), f.ReadPoolTopUp))
+	}
+	for _, w := range f.Warnings {
+		l.Logger.Info(w)
+	}
+	return f, nil
+}
@@ -0,0 +1,63 @@
+package sdk
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllocationForecastPools(t *testing.T) {
+	const day = 24 * time.Hour
+	now := time.Unix(1700000000, 0)
+	a := &Allocation{
+		ID:           "alloc",
+		DataShards:   2,
+		ParityShards: 2,
+		Expiration:   now.Add(10 * day).Unix(),
+		TimeUnit:     10 * day,
+		WritePool:    300,
+	}
+	_, err := a.ForecastPools(ForecastOptions{Now: now})
+	require.Error(t, err)
+
+	for i := 0; i < 4; i++ {
+		a.BlobberDetails = append(a.BlobberDetails, &BlobberAllocation{
+			Terms: Terms{ReadPrice: 10, WritePrice: 100},
+		})
+	}
+	f, err := a.ForecastPools(ForecastOptions{
+		Now:      now,
+		Interval: 5 * day,
+		Uploads: []PlannedTransfer{
+			{At: now.Add(5 * day), Size: GB},
+			{At: now, Size: 2 * GB},
+			{At: now.Add(20 * day), Size: GB}, // after the expiration
+		},
+		Downloads:       []PlannedTransfer{{At: now.Add(day), Size: 4 * GB}},
+		ReadPoolBalance: 30,
+	})
+	require.NoError(t, err)
+
+	// 2GB on 4 shards of 1GB for the whole allocation, then 1GB on 4 shards of 512MB for half of it
+	require.Equal(t, int64(500), f.WriteCost)
+	require.Equal(t, int64(40), f.ReadCost)
+	require.Equal(t, []PoolForecastPoint{
+		{Time: now, WritePool: -100, ReadPool: 30, ChallengePool: 400},
+		{Time: now.Add(5 * day), WritePool: -200, ReadPool: -10, ChallengePool: 300},
+		{Time: now.Add(10 * day), WritePool: -200, ReadPool: -10, ChallengePool: 0},
+	}, f.Points)
+	require.Equal(t, now, f.WritePoolDry)
+	require.Equal(t, now.Add(day), f.ReadPoolDry)
+	require.Equal(t, uint64(200), f.WritePoolTopUp)
+	require.Equal(t, uint64(10), f.ReadPoolTopUp)
+	require.Len(t, f.Warnings, 2)
+
+	a.WritePool = 1000
+	f, err = a.ForecastPools(ForecastOptions{Now: now, Uploads: []PlannedTransfer{{At: now, Size: 2 * GB}}})
+	require.NoError(t, err)
+	require.Len(t, f.Points, 2)
+	require.True(t, f.WritePoolDry.IsZero())
+	require.Zero(t, f.WritePoolTopUp)
+	require.Empty(t, f.Warnings)
+}
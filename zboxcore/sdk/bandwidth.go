@@ -0,0 +1,134 @@
+package sdk
+
+import (
+	"context"
+
+	"golang.org/x/time/rate"
+)
+
+// minBandwidthBurst is the minimum burst of a bandwidth limiter, so that a
+// limit lower than a chunk doesn't prevent the chunk from being sent at all.
+const minBandwidthBurst = 64 * 1024
+
+var (
+	uploadBandwidth   = NewBandwidthLimiter(0)
+	downloadBandwidth = NewBandwidthLimiter(0)
+)
+
+// BandwidthLimiter is a token-bucket limiter of the bytes transferred with the blobbers.
+// The same limiter can be shared by several transfers, it's safe for concurrent use
+// and its limit can be changed while transfers are running.
+type BandwidthLimiter struct {
+	limiter *rate.Limiter
+}
+
+// NewBandwidthLimiter creates a bandwidth limiter.
+//   - bytesPerSecond: the maximum throughput, unlimited if not positive.
+func NewBandwidthLimiter(bytesPerSecond int64) *BandwidthLimiter {
+	bl := &BandwidthLimiter{limiter: rate.NewLimiter(rate.Inf, 0)}
+	bl.SetLimit(bytesPerSecond)
+	return bl
+}
+
+// SetLimit changes the maximum throughput of the limiter.
+//   - bytesPerSecond: the maximum throughput, unlimited if not positive.
+func (bl *BandwidthLimiter) SetLimit(bytesPerSecond int64) {
+	if bytesPerSecond <= 0 {
+		bl.limiter.SetLimit(rate.Inf)
+		return
+	}
+	burst := int(bytesPerSecond)
+	if burst < minBandwidthBurst {
+		burst = minBandwidthBurst
+	}
+	bl.limiter.SetBurst(burst)
+	bl.limiter.SetLimit(rate.Limit(bytesPerSecond))
+}
+
+// Limit returns the maximum throughput in bytes per second, 0 if unlimited.
+func (bl *BandwidthLimiter) Limit() int64 {
+	if bl == nil || bl.limiter.Limit() == rate.Inf {
+		return 0
+	}
+	return int64(bl.limiter.Limit())
+}
+
+// WaitN blocks until n bytes can be transferred or the context is done.
+// A nil limiter never blocks.
+//   - ctx: the context of the transfer.
+//   - n: the number of bytes.
+func (bl *BandwidthLimiter) WaitN(ctx context.Context, n int) error {
+	if bl == nil {
+		return nil
+	}
+	for n > 0 {
+		if bl.limiter.Limit() == rate.Inf {
+			return nil
+		}
+		chunk := bl.limiter.Burst()
+		if chunk > n {
+			chunk = n
+		}
+		if err := bl.limiter.WaitN(ctx, chunk); err != nil {
+			if chunk > bl.limiter.Burst() && ctx.Err() == nil {
+				// the limit was lowered in the meantime, retry with the new burst
+				continue
+			}
+			return err
+		}
+		n -= chunk
+	}
+	return nil
+}
+
+// SetUploadBandwidth sets the maximum throughput of all the uploads of the process.
+//   - bytesPerSecond: the maximum throughput, unlimited if not positive.
+func SetUploadBandwidth(bytesPerSecond int64) {
+	uploadBandwidth.SetLimit(bytesPerSecond)
+}
+
+// SetDownloadBandwidth sets the maximum throughput of all the downloads of the process.
+//   - bytesPerSecond: the maximum throughput, unlimited if not positive.
+func SetDownloadBandwidth(bytesPerSecond int64) {
+	downloadBandwidth.SetLimit(bytesPerSecond)
+}
+
+// SetUploadBandwidth sets the maximum throughput of the uploads of the allocation,
+// on top of the process-wide limit.
+//   - bytesPerSecond: the maximum throughput, unlimited if not positive.
+func (a *Allocation) SetUploadBandwidth(bytesPerSecond int64) {
+	if a.uploadBandwidth == nil {
+		a.uploadBandwidth = NewBandwidthLimiter(bytesPerSecond)
+		return
+	}
+	a.uploadBandwidth.SetLimit(bytesPerSecond)
+}
+
+// SetDownloadBandwidth sets the maximum throughput of the downloads of the allocation,
+// on top of the process-wide limit.
+//   - bytesPerSecond: the maximum throughput, unlimited if not positive.
+func (a *Allocation) SetDownloadBandwidth(bytesPerSecond int64) {
+	if a.downloadBandwidth == nil {
+		a.downloadBandwidth = NewBandwidthLimiter(bytesPerSecond)
+		return
+	}
+	a.downloadBandwidth.SetLimit(bytesPerSecond)
+}
+
+func (a *Allocation) uploadBandwidthLimiters() []*BandwidthLimiter {
+	return []*BandwidthLimiter{uploadBandwidth, a.uploadBandwidth}
+}
+
+func (a *Allocation) downloadBandwidthLimiters() []*BandwidthLimiter {
+	return []*BandwidthLimiter{downloadBandwidth, a.downloadBandwidth}
+}
+
+// waitBandwidth blocks until n bytes can be transferred through all the limiters.
+func waitBandwidth(ctx context.Context, n int, limiters []*BandwidthLimiter) error {
+	for _, bl := range limiters {
+		if err := bl.WaitN(ctx, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
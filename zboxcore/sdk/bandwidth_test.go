@@ -0,0 +1,69 @@
+package sdk
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBandwidthLimiter(t *testing.T) {
+	ctx := context.Background()
+
+	var nilLimiter *BandwidthLimiter
+	require.NoError(t, nilLimiter.WaitN(ctx, 1<<30))
+	require.Equal(t, int64(0), nilLimiter.Limit())
+
+	bl := NewBandwidthLimiter(0)
+	require.Equal(t, int64(0), bl.Limit())
+	require.NoError(t, bl.WaitN(ctx, 1<<30))
+
+	bl.SetLimit(1 << 20)
+	require.Equal(t, int64(1<<20), bl.Limit())
+	// the burst is available at once, the rest is throttled
+	start := time.Now()
+	require.NoError(t, bl.WaitN(ctx, 1<<20))
+	require.NoError(t, bl.WaitN(ctx, 1<<18))
+	elapsed := time.Since(start)
+	require.GreaterOrEqual(t, elapsed, 200*time.Millisecond)
+	require.Less(t, elapsed, 2*time.Second)
+
+	// the wait is interrupted by the context
+	cctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	require.Error(t, bl.WaitN(cctx, 4<<20))
+
+	// removing the limit at runtime
+	bl.SetLimit(0)
+	start = time.Now()
+	require.NoError(t, bl.WaitN(ctx, 1<<30))
+	require.Less(t, time.Since(start), 100*time.Millisecond)
+}
+
+func TestWaitBandwidth(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.NoError(t, waitBandwidth(ctx, 1024, []*BandwidthLimiter{nil, NewBandwidthLimiter(0)}))
+	require.Error(t, waitBandwidth(ctx, 1<<20, []*BandwidthLimiter{NewBandwidthLimiter(1024)}))
+}
+
+func TestDownloadBlockThrottled(t *testing.T) {
+	bl := NewBandwidthLimiter(minBandwidthBurst)
+	var tokens float64
+	req := setupHedgedDownload(t, func(w http.ResponseWriter, r *http.Request) {
+		tokens = bl.limiter.Tokens()
+		w.Write([]byte("blobber0")) //nolint: errcheck
+	})
+	req.effectiveBlockSize = minBandwidthBurst
+	req.bandwidthLimiters = []*BandwidthLimiter{bl}
+
+	shards := [][][]byte{make([][]byte, 1)}
+	_, failed, _, err := req.downloadBlockHedged(0, 1, req.downloadMask, 1, shards)
+	require.NoError(t, err)
+	require.Zero(t, failed)
+	require.Equal(t, []byte("blobber0"), shards[0][0])
+	// the block was reserved before it was requested
+	require.Less(t, tokens, float64(1024))
+}
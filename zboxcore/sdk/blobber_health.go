@@ -0,0 +1,168 @@
+package sdk
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// blobberHealthAlpha is the weight of the last request in the moving averages of the blobber health.
+	blobberHealthAlpha = 0.2
+	// blobberHealthTTL is the age after which the health of a blobber is considered unknown,
+	// so that a blobber recovered from its errors gets a chance again.
+	blobberHealthTTL = 10 * time.Minute
+	// maxBlobberErrorRate caps the error rate in the score of a blobber.
+	maxBlobberErrorRate = 0.9
+)
+
+// BlobberHealth is the health of a blobber measured by the block downloads of the sdk.
+type BlobberHealth struct {
+	// LatencyEWMA is the exponentially weighted moving average of the latency of the successful requests.
+	LatencyEWMA time.Duration `json:"latency_ewma"`
+
+	// ErrorRate is the exponentially weighted moving average of the failures, between 0 and 1.
+	ErrorRate float64 `json:"error_rate"`
+
+	// Requests is the number of the measured requests.
+	Requests int64 `json:"requests"`
+
+	// Failures is the number of the failed requests.
+	Failures int64 `json:"failures"`
+
+	// LastSeen is the time of the last successful request, zero if none.
+	LastSeen time.Time `json:"last_seen"`
+
+	// LastFailure is the time of the last failed request, zero if none.
+	LastFailure time.Time `json:"last_failure"`
+}
+
+// updated returns the time of the last measured request.
+func (h *BlobberHealth) updated() time.Time {
+	if h.LastFailure.After(h.LastSeen) {
+		return h.LastFailure
+	}
+	return h.LastSeen
+}
+
+// score is the expected time to get a successful response from the blobber,
+// the latency multiplied by the expected number of attempts.
+func (h *BlobberHealth) score() time.Duration {
+	errorRate := h.ErrorRate
+	if errorRate > maxBlobberErrorRate {
+		errorRate = maxBlobberErrorRate
+	}
+	return time.Duration(float64(h.LatencyEWMA) / (1 - errorRate))
+}
+
+// blobberHealthTracker tracks the health of the blobbers, like node.NodeHolder for the sharders.
+type blobberHealthTracker struct {
+	guard sync.Mutex
+	stats map[string]*BlobberHealth
+	now   func() time.Time
+}
+
+func newBlobberHealthTracker() *blobberHealthTracker {
+	return &blobberHealthTracker{
+		stats: make(map[string]*BlobberHealth),
+		now:   time.Now,
+	}
+}
+
+// blobberHealth is the health of the blobbers shared by the allocations.
+var blobberHealth = newBlobberHealthTracker()
+
+// Success records a successful request to the blobber.
+//   - id: the id of the blobber.
+//   - latency: the latency of the request.
+func (t *blobberHealthTracker) Success(id string, latency time.Duration) {
+	t.guard.Lock()
+	defer t.guard.Unlock()
+	h := t.get(id)
+	if h.LastSeen.IsZero() {
+		h.LatencyEWMA = latency
+	} else {
+		h.LatencyEWMA += time.Duration(blobberHealthAlpha * float64(latency-h.LatencyEWMA))
+	}
+	h.ErrorRate -= blobberHealthAlpha * h.ErrorRate
+	h.Requests++
+	h.LastSeen = t.now()
+}
+
+// Fail records a failed request to the blobber.
+//   - id: the id of the blobber.
+func (t *blobberHealthTracker) Fail(id string) {
+	t.guard.Lock()
+	defer t.guard.Unlock()
+	h := t.get(id)
+	h.ErrorRate += blobberHealthAlpha * (1 - h.ErrorRate)
+	h.Requests++
+	h.Failures++
+	h.LastFailure = t.now()
+}
+
+func (t *blobberHealthTracker) get(id string) *BlobberHealth {
+	h, ok := t.stats[id]
+	if !ok {
+		h = &BlobberHealth{}
+		t.stats[id] = h
+	}
+	return h
+}
+
+// Health returns a copy of the health of the blobber, nil if it was never measured.
+//   - id: the id of the blobber.
+func (t *blobberHealthTracker) Health(id string) *BlobberHealth {
+	t.guard.Lock()
+	defer t.guard.Unlock()
+	h, ok := t.stats[id]
+	if !ok {
+		return nil
+	}
+	cp := *h
+	return &cp
+}
+
+// score returns the score of the blobber, false if its latency is unknown or
+// its health is older than blobberHealthTTL.
+func (t *blobberHealthTracker) score(id string) (time.Duration, bool) {
+	h, ok := t.stats[id]
+	if !ok || h.LastSeen.IsZero() || t.now().Sub(h.updated()) > blobberHealthTTL {
+		return 0, false
+	}
+	return h.score(), true
+}
+
+// Rank sorts the blobbers by their score, the fastest first, and the blobbers
+// with an unknown health last, in their original order.
+//   - ids: the ids of the blobbers.
+//
+// It returns the indexes of ids in the rank order.
+func (t *blobberHealthTracker) Rank(ids []string) []int {
+	t.guard.Lock()
+	defer t.guard.Unlock()
+	order := make([]int, len(ids))
+	scores := make([]time.Duration, len(ids))
+	known := make([]bool, len(ids))
+	for i, id := range ids {
+		order[i] = i
+		scores[i], known[i] = t.score(id)
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		if known[a] != known[b] {
+			return known[a]
+		}
+		return scores[a] < scores[b]
+	})
+	return order
+}
+
+// ExpectedLatency returns the score of the blobber in milliseconds, false if it's unknown.
+//   - id: the id of the blobber.
+func (t *blobberHealthTracker) ExpectedLatency(id string) (int64, bool) {
+	t.guard.Lock()
+	defer t.guard.Unlock()
+	s, ok := t.score(id)
+	return s.Milliseconds(), ok
+}
@@ -0,0 +1,46 @@
+package sdk
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlobberHealthTracker(t *testing.T) {
+	now := time.Now()
+	tracker := newBlobberHealthTracker()
+	tracker.now = func() time.Time { return now }
+
+	require.Nil(t, tracker.Health("fast"))
+	_, known := tracker.ExpectedLatency("fast")
+	require.False(t, known)
+
+	tracker.Success("fast", 100*time.Millisecond)
+	tracker.Success("fast", 200*time.Millisecond)
+	h := tracker.Health("fast")
+	require.Equal(t, 120*time.Millisecond, h.LatencyEWMA)
+	require.Equal(t, int64(2), h.Requests)
+	require.Equal(t, now, h.LastSeen)
+
+	tracker.Success("flaky", 50*time.Millisecond)
+	tracker.Fail("flaky")
+	tracker.Fail("flaky")
+	h = tracker.Health("flaky")
+	require.InDelta(t, 0.36, h.ErrorRate, 1e-9)
+	require.Equal(t, int64(2), h.Failures)
+	latency, known := tracker.ExpectedLatency("flaky")
+	require.True(t, known)
+	require.Equal(t, int64(78), latency) // 50ms / (1 - 0.36)
+
+	tracker.Success("slow", time.Second)
+	// only failures, the latency is unknown
+	tracker.Fail("down")
+
+	require.Equal(t, []int{2, 4, 0, 1, 3}, tracker.Rank([]string{"slow", "unknown", "flaky", "down", "fast"}))
+
+	// the stale health is ignored
+	now = now.Add(blobberHealthTTL + time.Second)
+	tracker.Success("slow", time.Second)
+	require.Equal(t, []int{0, 1, 2}, tracker.Rank([]string{"slow", "fast", "flaky"}))
+}
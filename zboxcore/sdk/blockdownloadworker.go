@@ -48,6 +48,10 @@ type BlockDownloadRequest struct {
 	shouldVerify       bool
 	connectionID       string
 	respBuf            []byte
+	// ownBuf is set when respBuf isn't a download buffer, so that the request
+	// can be abandoned once the ctx is done.
+	ownBuf            bool
+	bandwidthLimiters []*BandwidthLimiter
 }
 
 type downloadResponse struct {
@@ -155,11 +159,38 @@ func (req *BlockDownloadRequest) downloadBlobberBlock(fastClient *fasthttp.Clien
 
 		header.ToFastHeader(httpreq)
 
-		err = func() error {
+		err = func() (err error) {
+			ctx, span := zboxutil.StartSpan(req.ctx, "download_blocks", zboxutil.BlobberSpanAttributes(req.blobber.ID, req.blobber.Baseurl)...)
+			span.SetAttributes(zboxutil.Attribute("download.block", req.blockNum), zboxutil.Attribute("download.blocks", req.numBlocks), zboxutil.Attribute("download.retry", retry))
+			var latency time.Duration
+			defer func() {
+				zboxutil.EndSpan(span, err)
+				req.recordHealth(latency, err)
+			}()
+			zboxutil.InjectTraceContext(ctx, &httpreq.Header)
+
+			// the expected size is reserved before the request, so that the blocks are read at the limited rate
+			expected := len(req.respBuf)
+			if err = waitBandwidth(req.ctx, expected, req.bandwidthLimiters); err != nil {
+				fasthttp.ReleaseRequest(httpreq)
+				return err
+			}
+
 			now := time.Now()
-			statuscode, respBuf, err := fastClient.GetWithRequest(httpreq, req.respBuf)
-			fasthttp.ReleaseRequest(httpreq)
-			timeTaken := time.Since(now).Milliseconds()
+			reqURL := httpreq.URI().String()
+			var (
+				statuscode int
+				respBuf    []byte
+			)
+			if req.ownBuf {
+				statuscode, respBuf, err = getWithRequestContext(req.ctx, fastClient, httpreq, req.respBuf)
+			} else {
+				statuscode, respBuf, err = fastClient.GetWithRequest(httpreq, req.respBuf)
+				fasthttp.ReleaseRequest(httpreq)
+			}
+			zboxutil.ObserveBlobberRequest(reqURL, http.MethodGet, now, statuscode, err, 0, int64(len(respBuf)))
+			latency = time.Since(now)
+			timeTaken := latency.Milliseconds()
 			if err != nil {
 				zlogger.Logger.Error("Error downloading block: ", err)
 				if errors.Is(err, fasthttp.ErrConnectionClosed) || errors.Is(err, syscall.EPIPE) {
@@ -180,6 +211,13 @@ func (req *BlockDownloadRequest) downloadBlobberBlock(fastClient *fasthttp.Clien
 				return errors.New("internal_server_error", "Internal server error")
 			}
 
+			if extra := len(respBuf) - expected; extra > 0 {
+				// e.g. the merkle proofs of a verified download
+				if err = waitBandwidth(req.ctx, extra, req.bandwidthLimiters); err != nil {
+					return err
+				}
+			}
+
 			var rspData downloadBlock
 			if statuscode != http.StatusOK {
 				zlogger.Logger.Error(fmt.Sprintf("downloadBlobberBlock FAIL - blobberID: %v, clientID: %v, blockNum: %d, retry: %d, response: %v", req.blobber.ID, client.GetClientID(), header.BlockNum, retry, string(respBuf)))
@@ -247,8 +285,10 @@ func (req *BlockDownloadRequest) downloadBlobberBlock(fastClient *fasthttp.Clien
 				shouldRetry = false
 				zlogger.Logger.Debug("Retrying for Error occurred: ", err)
 				retry++
+				req.publishBlobberEvent(EventBlobberRetry, retry, err)
 				continue
 			} else {
+				req.publishBlobberEvent(EventBlobberFailed, retry, err)
 				req.result <- &downloadBlock{Success: false, idx: req.blobberIdx, err: err, maskIdx: req.maskIdx}
 			}
 		}
@@ -259,6 +299,62 @@ func (req *BlockDownloadRequest) downloadBlobberBlock(fastClient *fasthttp.Clien
 
 }
 
+// getWithRequestContext sends the request with the client until the ctx is done, and releases it.
+// Like the timeouts of fasthttp, an abandoned request keeps running in the background until the
+// read timeout of the client, so dst must not be reused by the caller.
+func getWithRequestContext(ctx context.Context, fastClient *fasthttp.Client, httpreq *fasthttp.Request, dst []byte) (int, []byte, error) {
+	type response struct {
+		statuscode int
+		body       []byte
+		err        error
+	}
+	rspCh := make(chan response, 1)
+	go func() {
+		statuscode, body, err := fastClient.GetWithRequest(httpreq, dst)
+		fasthttp.ReleaseRequest(httpreq)
+		rspCh <- response{statuscode, body, err}
+	}()
+	select {
+	case rsp := <-rspCh:
+		return rsp.statuscode, rsp.body, rsp.err
+	case <-ctx.Done():
+		return 0, nil, ctx.Err()
+	}
+}
+
+// recordHealth records the result of a block request in the health of the blobber,
+// the requests stopped by the download aren't counted as failures.
+func (req *BlockDownloadRequest) recordHealth(latency time.Duration, err error) {
+	if err == nil {
+		blobberHealth.Success(req.blobber.ID, latency)
+	} else if req.ctx.Err() == nil && !errors.Is(err, context.Canceled) {
+		blobberHealth.Fail(req.blobber.ID)
+	}
+}
+
+func (req *BlockDownloadRequest) publishBlobberEvent(t EventType, retries int, err error) {
+	publishEvent(func() Event {
+		op := OperationDownload
+		if req.contentMode == DOWNLOAD_CONTENT_THUMB {
+			op = OperationThumbnailDownload
+		}
+		path := req.remotefilepath
+		if path == "" {
+			path = req.remotefilepathhash
+		}
+		return Event{
+			Type:         t,
+			OperationID:  req.connectionID,
+			AllocationID: req.allocationID,
+			Op:           op,
+			Path:         path,
+			BlobberID:    req.blobber.ID,
+			Retries:      retries,
+			Err:          err,
+		}
+	})
+}
+
 func AddBlockDownloadReq(ctx context.Context, req *BlockDownloadRequest, rb zboxutil.DownloadBuffer, effectiveBlockSize int) {
 	if rb != nil {
 		reqCtx, cncl := context.WithTimeout(ctx, (time.Second * 45))
@@ -266,9 +362,11 @@ func AddBlockDownloadReq(ctx context.Context, req *BlockDownloadRequest, rb zbox
 		req.respBuf = rb.RequestChunk(reqCtx, int(req.blockNum))
 		if len(req.respBuf) == 0 {
 			req.respBuf = make([]byte, int(req.numBlocks)*effectiveBlockSize)
+			req.ownBuf = true
 		}
 	} else {
 		req.respBuf = make([]byte, int(req.numBlocks)*effectiveBlockSize)
+		req.ownBuf = true
 	}
 	downloadBlockChan[req.blobber.ID] <- req
 }
@@ -177,6 +177,8 @@ func CreateChunkedUpload(
 		commitTimeOut: DefaultUploadTimeOut,
 		maskMu:        &sync.Mutex{},
 		opCode:        opCode,
+
+		bandwidthLimiters: allocationObj.uploadBandwidthLimiters(),
 	}
 
 	// su.ctx, su.ctxCncl = context.WithCancel(allocationObj.ctx)
@@ -235,6 +237,7 @@ func CreateChunkedUpload(
 	}
 
 	su.createUploadProgress(connectionId)
+	su.statusCallback = newEventStatusCallback(defaultEventBus, su.statusCallback, su.progress.ConnectionID)
 
 	su.fileErasureEncoder, err = reedsolomon.New(
 		su.allocationObj.DataShards,
@@ -472,15 +475,27 @@ func (su *ChunkedUpload) process() error {
 	return nil
 }
 
+// startSpan starts the parent span of the upload, the spans of its requests to the blobbers are its children.
+func (su *ChunkedUpload) startSpan() (context.Context, zboxutil.Span) {
+	ctx, span := zboxutil.StartSpan(su.ctx, "ChunkedUpload",
+		zboxutil.Attribute("allocation.id", su.allocationObj.ID),
+		zboxutil.Attribute("file.path", su.fileMeta.RemotePath),
+		zboxutil.Attribute("file.size", su.fileMeta.ActualSize))
+	su.ctx = ctx
+	return ctx, span
+}
+
 // Start start/resume upload
-func (su *ChunkedUpload) Start() error {
+func (su *ChunkedUpload) Start() (err error) {
 	now := time.Now()
+	spanCtx, span := su.startSpan()
+	defer func() { zboxutil.EndSpan(span, err) }()
 
-	err := su.process()
+	err = su.process()
 	if err != nil {
 		return err
 	}
-	su.ctx, su.ctxCncl = context.WithCancelCause(su.allocationObj.ctx)
+	su.ctx, su.ctxCncl = context.WithCancelCause(zboxutil.WithTraceContext(su.allocationObj.ctx, spanCtx))
 	defer su.ctxCncl(nil)
 	elapsedProcess := time.Since(now)
 
@@ -598,7 +613,7 @@ func (su *ChunkedUpload) processCommit() error {
 		wg.Add(1)
 		go func(b *ChunkedUploadBlobber, pos uint64) {
 			defer wg.Done()
-			err := b.processCommit(context.TODO(), su, pos, int64(timestamp))
+			err := b.processCommit(context.WithoutCancel(su.ctx), su, pos, int64(timestamp))
 			if err != nil {
 				b.commitResult = ErrorCommitResult(err.Error())
 			}
@@ -701,6 +716,7 @@ func (su *ChunkedUpload) uploadToBlobbers(uploadData UploadData) error {
 					return
 				}
 				logger.Logger.Error("error during sendUploadRequest", err, " connectionID: ", su.progress.ConnectionID)
+				su.publishBlobberEvent(EventBlobberFailed, su.blobbers[pos].blobber.ID, 0, err)
 				errC := atomic.AddInt32(&errCount, 1)
 				if errC > int32(su.allocationObj.ParityShards-1) { // If atleast data shards + 1 number of blobbers can process the upload, it can be repaired later
 					wgErrors <- err
@@ -45,6 +45,10 @@ func (sb *ChunkedUploadBlobber) sendUploadRequest(
 	formData ChunkedUploadFormMetadata, contentSlice []string,
 	pos uint64, consensus *Consensus) (err error) {
 
+	ctx, span := zboxutil.StartSpan(ctx, "upload_chunks", zboxutil.BlobberSpanAttributes(sb.blobber.ID, sb.blobber.Baseurl)...)
+	span.SetAttributes(zboxutil.Attribute("upload.final", isFinal), zboxutil.Attribute("upload.requests", len(dataBuffers)))
+	defer func() { zboxutil.EndSpan(span, err) }()
+
 	defer func() {
 
 		if err != nil {
@@ -77,6 +81,9 @@ func (sb *ChunkedUploadBlobber) sendUploadRequest(
 			var (
 				shouldContinue bool
 			)
+			if err := waitBandwidth(ctx, dataBuffers[ind].Len(), su.bandwidthLimiters); err != nil {
+				return err
+			}
 			var req *fasthttp.Request
 			for i := 0; i < 3; i++ {
 				req, err = zboxutil.NewFastUploadRequest(
@@ -86,11 +93,14 @@ func (sb *ChunkedUploadBlobber) sendUploadRequest(
 				}
 
 				req.Header.Add("Content-Type", contentSlice[ind])
+				zboxutil.InjectTraceContext(ctx, &req.Header)
 				err, shouldContinue = func() (err error, shouldContinue bool) {
 					resp := fasthttp.AcquireResponse()
 					defer fasthttp.ReleaseResponse(resp)
+					reqURL, sent, start := req.URI().String(), int64(len(req.Body())), time.Now()
 					err = zboxutil.FastHttpClient.DoTimeout(req, resp, su.uploadTimeOut)
 					fasthttp.ReleaseRequest(req)
+					zboxutil.ObserveBlobberRequest(reqURL, su.httpMethod, start, resp.StatusCode(), err, sent, int64(len(resp.Body())))
 					if err != nil {
 						logger.Logger.Error("Upload : ", err)
 						if errors.Is(err, fasthttp.ErrConnectionClosed) || errors.Is(err, syscall.EPIPE) {
@@ -126,6 +136,7 @@ func (sb *ChunkedUploadBlobber) sendUploadRequest(
 				}()
 
 				if shouldContinue {
+					su.publishBlobberEvent(EventBlobberRetry, sb.blobber.ID, i+1, err)
 					continue
 				}
 				buff := &bytebufferpool.ByteBuffer{
@@ -176,6 +187,8 @@ func (sb *ChunkedUploadBlobber) sendUploadRequest(
 }
 
 func (sb *ChunkedUploadBlobber) processCommit(ctx context.Context, su *ChunkedUpload, pos uint64, timestamp int64) (err error) {
+	ctx, span := zboxutil.StartSpan(ctx, "commit", zboxutil.BlobberSpanAttributes(sb.blobber.ID, sb.blobber.Baseurl)...)
+	defer func() { zboxutil.EndSpan(span, err) }()
 	defer func() {
 		if err != nil {
 
@@ -313,6 +326,7 @@ func (sb *ChunkedUploadBlobber) processCommit(ctx context.Context, su *ChunkedUp
 			return
 		}()
 		if shouldContinue {
+			su.publishBlobberEvent(EventBlobberRetry, sb.blobber.ID, retries+1, err)
 			continue
 		}
 		return
@@ -54,6 +54,10 @@ type ChunkedUpload struct {
 	encryptOnUpload bool
 	// webStreaming whether data has to be encoded.
 	webStreaming bool
+	// deduplicate copies an identical file of the allocation instead of uploading the content.
+	deduplicate bool
+	// bandwidthLimiters throttle the bytes sent to the blobbers.
+	bandwidthLimiters []*BandwidthLimiter
 	// chunkSize how much bytes a chunk has. 64KB is default value.
 	chunkSize int64
 	// chunkNumber the number of chunks in a http upload request. 100 is default value
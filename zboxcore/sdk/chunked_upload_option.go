@@ -120,3 +120,24 @@ func WithFileHasher(h Hasher) ChunkedUploadOption {
 		su.fileHasher = h
 	}
 }
+
+// WithDeduplication turn on/off upload deduplication. It is turn off as default.
+// When on, the actual hash of a new file is computed with a file hasher before uploading,
+// and if a file of the allocation with the same name, hash, size and encryption exists,
+// it is copied by the blobbers instead of uploading the content again.
+// The file reader must be an io.ReadSeeker, otherwise the option is ignored.
+// 		- on: true to turn on, false to turn off
+func WithDeduplication(on bool) ChunkedUploadOption {
+	return func(su *ChunkedUpload) {
+		su.deduplicate = on
+	}
+}
+
+// WithRateLimit throttles the upload with the given limiter, on top of the process-wide
+// and allocation limits. The limiter can be shared by several uploads.
+// 		- limiter: the bandwidth limiter
+func WithRateLimit(limiter *BandwidthLimiter) ChunkedUploadOption {
+	return func(su *ChunkedUpload) {
+		su.bandwidthLimiters = append(su.bandwidthLimiters, limiter)
+	}
+}
@@ -62,6 +62,8 @@ type CommitRequest struct {
 	result       *CommitResult
 	timestamp    int64
 	blobberInd   uint64
+	// ctx holds the span of the operation, its cancellation is ignored.
+	ctx context.Context
 }
 
 var commitChan map[string]chan *CommitRequest
@@ -99,6 +101,17 @@ func startCommitWorker(blobberChan chan *CommitRequest, blobberID string) {
 
 func (commitreq *CommitRequest) processCommit() {
 	defer commitreq.wg.Done()
+	parentCtx := context.Background()
+	if commitreq.ctx != nil {
+		parentCtx = context.WithoutCancel(commitreq.ctx)
+	}
+	spanCtx, span := zboxutil.StartSpan(parentCtx, "commit", zboxutil.BlobberSpanAttributes(commitreq.blobber.ID, commitreq.blobber.Baseurl)...)
+	defer func() {
+		if commitreq.result != nil && !commitreq.result.Success {
+			span.RecordError(errors.New("commit_failed", commitreq.result.ErrorMessage))
+		}
+		span.End()
+	}()
 	start := time.Now()
 	l.Logger.Debug("received a commit request")
 	paths := make([]string, 0)
@@ -117,7 +130,7 @@ func (commitreq *CommitRequest) processCommit() {
 		l.Logger.Error("Creating ref path req", err)
 		return
 	}
-	ctx, cncl := context.WithTimeout(context.Background(), (time.Second * 30))
+	ctx, cncl := context.WithTimeout(spanCtx, (time.Second * 30))
 	err = zboxutil.HttpDo(ctx, cncl, req, func(resp *http.Response, err error) error {
 		if err != nil {
 			l.Logger.Error("Ref path error:", err)
@@ -209,7 +222,7 @@ func (commitreq *CommitRequest) processCommit() {
 		hasher.Write(decodedHash) //nolint:errcheck
 		chainHash = hex.EncodeToString(hasher.Sum(nil))
 	}
-	err = commitreq.commitBlobber(rootRef, chainHash, lR.LatestWM, size, fileIDMeta)
+	err = commitreq.commitBlobber(spanCtx, rootRef, chainHash, lR.LatestWM, size, fileIDMeta)
 	if err != nil {
 		commitreq.result = ErrorCommitResult(err.Error())
 		return
@@ -219,7 +232,7 @@ func (commitreq *CommitRequest) processCommit() {
 }
 
 func (req *CommitRequest) commitBlobber(
-	rootRef *fileref.Ref, chainHash string, latestWM *marker.WriteMarker, size int64,
+	ctx context.Context, rootRef *fileref.Ref, chainHash string, latestWM *marker.WriteMarker, size int64,
 	fileIDMeta map[string]string) (err error) {
 
 	fileIDMetaData, err := json.Marshal(fileIDMeta)
@@ -278,7 +291,7 @@ func (req *CommitRequest) commitBlobber(
 				return
 			}
 			httpreq.Header.Add("Content-Type", formWriter.FormDataContentType())
-			reqCtx, ctxCncl := context.WithTimeout(context.Background(), time.Second*60)
+			reqCtx, ctxCncl := context.WithTimeout(ctx, time.Second*60)
 			resp, err = zboxutil.Client.Do(httpreq.WithContext(reqCtx))
 			defer ctxCncl()
 
@@ -0,0 +1,177 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/0chain/errors"
+	"github.com/0chain/gosdk/constants"
+	"github.com/0chain/gosdk/zboxcore/fileref"
+	"github.com/0chain/gosdk/zboxcore/logger"
+	"github.com/0chain/gosdk/zboxcore/zboxutil"
+	"go.uber.org/zap"
+)
+
+// CopyDirectory copies the whole content of the remote directory srcDir into destDir.
+// The tree is expanded into single file copies committed in multi-operation batches of
+// MultiOpBatchSize. Every batch is verified against the hashes of the source files, a
+// batch failing the verification is rolled back and the copy stops.
+//   - ctx: the context of the copy, used to stop it between batches.
+//   - srcDir: the remote directory to copy.
+//   - destDir: the remote directory receiving the content of srcDir. It's created if missing.
+func (a *Allocation) CopyDirectory(ctx context.Context, srcDir, destDir string) error {
+	if !a.CanCopy() {
+		return constants.ErrFileOptionNotPermitted
+	}
+	return a.transferDirectory(ctx, constants.FileOperationCopy, srcDir, destDir)
+}
+
+// MoveDirectory moves the whole content of the remote directory srcDir into destDir,
+// then deletes srcDir. It works as CopyDirectory, a batch failing the verification is
+// rolled back, so the files of the batch are kept in srcDir.
+//   - ctx: the context of the move, used to stop it between batches.
+//   - srcDir: the remote directory to move.
+//   - destDir: the remote directory receiving the content of srcDir. It's created if missing.
+func (a *Allocation) MoveDirectory(ctx context.Context, srcDir, destDir string) error {
+	if !a.CanMove() {
+		return constants.ErrFileOptionNotPermitted
+	}
+	return a.transferDirectory(ctx, constants.FileOperationMove, srcDir, destDir)
+}
+
+// directoryMultiOperation commits the operations of a directory transfer.
+var directoryMultiOperation = (*Allocation).DoMultiOperation
+
+type directoryTransferFile struct {
+	srcPath  string
+	destPath string
+	hash     string
+}
+
+func (a *Allocation) transferDirectory(ctx context.Context, operationType, srcDir, destDir string) error {
+	if !a.isInitialized() {
+		return notInitialized
+	}
+	srcDir = zboxutil.RemoteClean(srcDir)
+	destDir = zboxutil.RemoteClean(destDir)
+	if !zboxutil.IsRemoteAbs(srcDir) || !zboxutil.IsRemoteAbs(destDir) {
+		return errors.New("invalid_path", "Path should be valid and absolute")
+	}
+	if isRemoteSubPath(destDir, srcDir) {
+		return errors.New("invalid_path", "destination "+destDir+" is inside the source "+srcDir)
+	}
+
+	dirs, files, err := a.expandDirectoryTransfer(ctx, srcDir, destDir)
+	if err != nil {
+		return err
+	}
+
+	var dirOps []OperationRequest
+	for _, d := range dirs {
+		dirOps = append(dirOps, OperationRequest{
+			OperationType: constants.FileOperationCreateDir,
+			RemotePath:    d,
+		})
+	}
+	if err := directoryMultiOperation(a, dirOps); err != nil {
+		return err
+	}
+
+	for start := 0; start < len(files); start += MultiOpBatchSize {
+		if contextCanceled(ctx) {
+			return ctx.Err()
+		}
+		end := start + MultiOpBatchSize
+		if end > len(files) {
+			end = len(files)
+		}
+		if err := a.transferDirectoryBatch(operationType, files[start:end]); err != nil {
+			return err
+		}
+	}
+
+	if operationType == constants.FileOperationMove {
+		return directoryMultiOperation(a, []OperationRequest{{
+			OperationType: constants.FileOperationDelete,
+			RemotePath:    srcDir,
+		}})
+	}
+	return nil
+}
+
+// expandDirectoryTransfer lists the directories to create and the files to copy
+// or move from srcDir to destDir.
+func (a *Allocation) expandDirectoryTransfer(ctx context.Context, srcDir, destDir string) ([]string, []directoryTransferFile, error) {
+	srcRoot := strings.TrimRight(srcDir, "/")
+	dirs := []string{destDir}
+	var files []directoryTransferFile
+	for ref := range a.ListObjects(ctx, srcDir, "", "", "", "", fileref.REGULAR, 0, getRefPageLimit) {
+		if ref.Err != nil {
+			return nil, nil, ref.Err
+		}
+		if ref.Path == srcDir {
+			continue
+		}
+		destPath := path.Join(destDir, strings.TrimPrefix(ref.Path, srcRoot))
+		if ref.Type == fileref.DIRECTORY {
+			dirs = append(dirs, destPath)
+			continue
+		}
+		files = append(files, directoryTransferFile{
+			srcPath:  ref.Path,
+			destPath: destPath,
+			hash:     ref.ActualFileHash,
+		})
+	}
+	return dirs, files, nil
+}
+
+// transferDirectoryBatch commits the batch, then checks that the destination files
+// reached consensus with the source hashes. Otherwise it rolls back the blobbers
+// which applied the batch.
+func (a *Allocation) transferDirectoryBatch(operationType string, batch []directoryTransferFile) error {
+	ops := make([]OperationRequest, 0, len(batch))
+	for _, f := range batch {
+		ops = append(ops, OperationRequest{
+			OperationType: operationType,
+			RemotePath:    f.srcPath,
+			DestPath:      path.Dir(f.destPath),
+		})
+	}
+	if err := directoryMultiOperation(a, ops); err != nil {
+		return err
+	}
+
+	applied := zboxutil.NewUint128(0)
+	var failed []string
+	for _, f := range batch {
+		meta, mask, err := a.getFileMetaWithMask(f.destPath)
+		applied = applied.Or(mask)
+		if err != nil || meta.Hash != f.hash {
+			failed = append(failed, f.destPath)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+
+	logger.Logger.Error("directory transfer: batch verification failed, rolling back",
+		zap.String("operation", operationType),
+		zap.Strings("paths", failed),
+		zap.Int("blobbers", applied.CountOnes()))
+	if !applied.Equals64(0) {
+		a.RollbackWithMask(applied)
+	}
+	return errors.New("consistency_check_failed",
+		fmt.Sprintf("%d of %d files of the batch did not reach consensus, first: %s", len(failed), len(batch), failed[0]))
+}
+
+// isRemoteSubPath reports whether p is dir or is inside dir.
+func isRemoteSubPath(p, dir string) bool {
+	if dir == "/" {
+		return true
+	}
+	return p == dir || strings.HasPrefix(p, dir+"/")
+}
@@ -0,0 +1,218 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/0chain/gosdk/constants"
+	"github.com/0chain/gosdk/zboxcore/blockchain"
+	"github.com/0chain/gosdk/zboxcore/fileref"
+	"github.com/0chain/gosdk/zboxcore/mocks"
+	"github.com/0chain/gosdk/zboxcore/zboxutil"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsRemoteSubPath(t *testing.T) {
+	require.True(t, isRemoteSubPath("/a", "/a"))
+	require.True(t, isRemoteSubPath("/a/b/c", "/a"))
+	require.True(t, isRemoteSubPath("/a", "/"))
+	require.False(t, isRemoteSubPath("/ab", "/a"))
+	require.False(t, isRemoteSubPath("/b", "/a/b"))
+}
+
+// directoryTransferBlobbers mocks the blobbers of a directory transfer.
+type directoryTransferBlobbers struct {
+	mu sync.Mutex
+	// refs are listed by every blobber as refs, as directory children and by name.
+	refs []ORef
+	// meta returns the hash of the file stored by the blobber, or "" if it doesn't have it.
+	meta func(blobberIdx int, lookupHash string) string
+	// rolledBack are the blobbers asked for their latest write marker by a rollback.
+	rolledBack []int
+}
+
+func setupDirectoryTransferAllocation(t *testing.T, blobbers *directoryTransferBlobbers) *Allocation {
+	var mockClient = mocks.HttpClient{}
+	zboxutil.Client = &mockClient
+
+	a := &Allocation{ID: mockAllocationId, Tx: mockAllocationTxId, DataShards: 2, ParityShards: 2}
+	for i := 0; i < numBlobbers; i++ {
+		a.Blobbers = append(a.Blobbers, &blockchain.StorageNode{
+			ID:      mockBlobberId + strconv.Itoa(i),
+			Baseurl: "http://TestDirectoryTransfer" + mockBlobberUrl + strconv.Itoa(i),
+		})
+	}
+	setupMockAllocation(t, a)
+
+	respond := func(status int, body interface{}) *http.Response {
+		buf, _ := json.Marshal(body)
+		return &http.Response{StatusCode: status, Body: io.NopCloser(bytes.NewReader(buf))}
+	}
+	mockClient.On("Do", mock.Anything).Return(func(req *http.Request) (*http.Response, error) {
+		blobberIdx, err := strconv.Atoi(strings.TrimPrefix(req.URL.Host, "TestDirectoryTransfer"+mockBlobberUrl))
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case strings.HasPrefix(req.URL.Path, zboxutil.REFS_ENDPOINT):
+			return respond(http.StatusOK, ObjectTreeResult{TotalPages: 1, Refs: blobbers.refs}), nil
+		case strings.HasPrefix(req.URL.Path, zboxutil.LIST_ENDPOINT):
+			dir := req.URL.Query().Get("path")
+			list := fileref.ListResult{Meta: map[string]interface{}{
+				"type": fileref.DIRECTORY, "path": dir, "file_meta_hash": "meta " + dir,
+			}}
+			for _, ref := range blobbers.refs {
+				if path.Dir(ref.Path) == dir && ref.Path != dir {
+					list.Entities = append(list.Entities, map[string]interface{}{
+						"type": ref.Type, "path": ref.Path, "name": path.Base(ref.Path),
+						"actual_file_hash": ref.ActualFileHash, "file_meta_hash": ref.FileMetaHash,
+						"lookup_hash": fileref.GetReferenceLookup(mockAllocationId, ref.Path),
+					})
+				}
+			}
+			return respond(http.StatusOK, list), nil
+		case strings.HasPrefix(req.URL.Path, zboxutil.FILE_META_ENDPOINT):
+			if err := req.ParseMultipartForm(1 << 20); err != nil {
+				return nil, err
+			}
+			if name := req.FormValue("name"); name != "" {
+				var refs []ORef
+				for _, ref := range blobbers.refs {
+					if path.Base(ref.Path) == name {
+						ref.PathHash = fileref.GetReferenceLookup(mockAllocationId, ref.Path)
+						refs = append(refs, ref)
+					}
+				}
+				return respond(http.StatusOK, refs), nil
+			}
+			hash := blobbers.meta(blobberIdx, req.FormValue("path_hash"))
+			if hash == "" {
+				return respond(http.StatusBadRequest, nil), nil
+			}
+			ref := fileref.FileRef{ActualFileHash: hash}
+			ref.Type = fileref.FILE
+			ref.FileMetaHash = "meta " + hash
+			return respond(http.StatusOK, ref), nil
+		case strings.HasPrefix(req.URL.Path, zboxutil.LATEST_WRITE_MARKER_ENDPOINT):
+			blobbers.mu.Lock()
+			blobbers.rolledBack = append(blobbers.rolledBack, blobberIdx)
+			blobbers.mu.Unlock()
+			return respond(http.StatusOK, LatestPrevWriteMarker{}), nil
+		}
+		return nil, errors.New("unexpected request " + req.URL.String())
+	})
+	return a
+}
+
+func fakeDirectoryMultiOperation(t *testing.T, err error) *[]OperationRequest {
+	var ops []OperationRequest
+	prev := directoryMultiOperation
+	t.Cleanup(func() { directoryMultiOperation = prev })
+	directoryMultiOperation = func(a *Allocation, operations []OperationRequest, _ ...MultiOperationOption) error {
+		ops = append(ops, operations...)
+		return err
+	}
+	return &ops
+}
+
+func TestAllocation_expandDirectoryTransfer(t *testing.T) {
+	ref := func(p, refType, hash string) ORef {
+		var r ORef
+		r.Path, r.Type, r.ActualFileHash = p, refType, hash
+		r.FileMetaHash = "meta " + p
+		return r
+	}
+	blobbers := &directoryTransferBlobbers{refs: []ORef{
+		ref("/src", fileref.DIRECTORY, ""),
+		ref("/src/a.txt", fileref.FILE, "hash a"),
+		ref("/src/sub", fileref.DIRECTORY, ""),
+		ref("/src/sub/b.txt", fileref.FILE, "hash b"),
+	}}
+	a := setupDirectoryTransferAllocation(t, blobbers)
+
+	dirs, files, err := a.expandDirectoryTransfer(context.Background(), "/src", "/backup/src")
+	require.NoError(t, err)
+	require.Equal(t, []string{"/backup/src", "/backup/src/sub"}, dirs)
+	require.Equal(t, []directoryTransferFile{
+		{srcPath: "/src/a.txt", destPath: "/backup/src/a.txt", hash: "hash a"},
+		{srcPath: "/src/sub/b.txt", destPath: "/backup/src/sub/b.txt", hash: "hash b"},
+	}, files)
+}
+
+func TestAllocation_transferDirectoryBatch(t *testing.T) {
+	batch := []directoryTransferFile{
+		{srcPath: "/src/a.txt", destPath: "/dst/a.txt", hash: "hash a"},
+		{srcPath: "/src/sub/b.txt", destPath: "/dst/sub/b.txt", hash: "hash b"},
+	}
+	lookup := func(p string) string {
+		return fileref.GetReferenceLookup(mockAllocationId, p)
+	}
+	stored := map[string]string{lookup("/dst/a.txt"): "hash a", lookup("/dst/sub/b.txt"): "hash b"}
+
+	t.Run("verified", func(t *testing.T) {
+		blobbers := &directoryTransferBlobbers{meta: func(_ int, lookupHash string) string {
+			return stored[lookupHash]
+		}}
+		a := setupDirectoryTransferAllocation(t, blobbers)
+		ops := fakeDirectoryMultiOperation(t, nil)
+
+		require.NoError(t, a.transferDirectoryBatch(constants.FileOperationCopy, batch))
+		require.Equal(t, []OperationRequest{
+			{OperationType: constants.FileOperationCopy, RemotePath: "/src/a.txt", DestPath: "/dst"},
+			{OperationType: constants.FileOperationCopy, RemotePath: "/src/sub/b.txt", DestPath: "/dst/sub"},
+		}, *ops)
+		require.Empty(t, blobbers.rolledBack)
+	})
+
+	t.Run("rolled back", func(t *testing.T) {
+		// the last blobber didn't apply the batch, the others have a wrong content for b.txt
+		blobbers := &directoryTransferBlobbers{meta: func(blobberIdx int, lookupHash string) string {
+			if blobberIdx == numBlobbers-1 {
+				return ""
+			}
+			if lookupHash == lookup("/dst/sub/b.txt") {
+				return "hash c"
+			}
+			return stored[lookupHash]
+		}}
+		a := setupDirectoryTransferAllocation(t, blobbers)
+		fakeDirectoryMultiOperation(t, nil)
+
+		err := a.transferDirectoryBatch(constants.FileOperationMove, batch)
+		require.EqualError(t, err, "consistency_check_failed: 1 of 2 files of the batch did not reach consensus, first: /dst/sub/b.txt")
+		sort.Ints(blobbers.rolledBack)
+		require.Equal(t, []int{0, 1, 2}, blobbers.rolledBack)
+	})
+
+	t.Run("commit failed", func(t *testing.T) {
+		var (
+			guard    sync.Mutex
+			verified bool
+		)
+		blobbers := &directoryTransferBlobbers{meta: func(int, string) string {
+			guard.Lock()
+			defer guard.Unlock()
+			verified = true
+			return ""
+		}}
+		a := setupDirectoryTransferAllocation(t, blobbers)
+		fakeDirectoryMultiOperation(t, errors.New("commit failed"))
+
+		require.EqualError(t, a.transferDirectoryBatch(constants.FileOperationCopy, batch), "commit failed")
+		guard.Lock()
+		defer guard.Unlock()
+		require.False(t, verified, "a failed batch isn't verified")
+		require.Empty(t, blobbers.rolledBack)
+	})
+}
@@ -0,0 +1,81 @@
+package sdk
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"path/filepath"
+	"strconv"
+
+	"github.com/0chain/gosdk/core/sys"
+	"github.com/0chain/gosdk/zboxcore/logger"
+	"go.uber.org/zap"
+)
+
+// directoryProgress is the manifest of the files of a directory transfer
+// already completed, used to resume an interrupted upload or download.
+type directoryProgress struct {
+	ID        string                     `json:"id"`
+	Completed map[string]LocalIndexEntry `json:"completed"`
+	// resumed is set when the manifest was saved by a previous run.
+	resumed bool
+}
+
+// directoryProgressID builds the manifest id with
+// [workdir]/[kind]/d[allocationid]_[Hash(src+"_"+dst)] format.
+func (a *Allocation) directoryProgressID(kind, src, dst, workdir string) string {
+	hash := fnv.New64a()
+	hash.Write([]byte(src + "_" + dst))
+	allocID := a.ID
+	if len(allocID) > 8 {
+		allocID = allocID[:8]
+	}
+	return filepath.Join(workdir, kind, "d"+allocID+"_"+strconv.FormatUint(hash.Sum64(), 36))
+}
+
+// loadDirectoryProgress loads the manifest with the given id, or returns an
+// empty one if progress saving is disabled or no valid manifest is found.
+func loadDirectoryProgress(id string) *directoryProgress {
+	progress := &directoryProgress{
+		ID:        id,
+		Completed: make(map[string]LocalIndexEntry),
+	}
+	if !shouldSaveProgress {
+		return progress
+	}
+	buf, err := sys.Files.LoadProgress(id)
+	if err != nil {
+		return progress
+	}
+	saved := &directoryProgress{}
+	if err := json.Unmarshal(buf, saved); err != nil || saved.Completed == nil {
+		logger.Logger.Error("directory progress: invalid progress, starting over", zap.String("id", id))
+		return progress
+	}
+	saved.ID = id
+	saved.resumed = true
+	return saved
+}
+
+func saveDirectoryProgress(progress *directoryProgress) {
+	if !shouldSaveProgress {
+		return
+	}
+	buf, err := json.Marshal(progress)
+	if err != nil {
+		logger.Logger.Error("directory progress: failed to encode progress", zap.Error(err))
+		return
+	}
+	if err := sys.Files.MkdirAll(filepath.Dir(progress.ID), 0744); err != nil {
+		logger.Logger.Error("directory progress: failed to create progress dir", zap.Error(err))
+		return
+	}
+	if err := sys.Files.SaveProgress(progress.ID, buf, 0644); err != nil {
+		logger.Logger.Error("directory progress: failed to save progress", zap.String("id", progress.ID), zap.Error(err))
+	}
+}
+
+func removeDirectoryProgress(progress *directoryProgress) {
+	if shouldSaveProgress {
+		sys.Files.RemoveProgress(progress.ID) //nolint: errcheck
+	}
+}
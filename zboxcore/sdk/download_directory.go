@@ -0,0 +1,250 @@
+package sdk
+
+import (
+	"context"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/0chain/gosdk/core/pathutil"
+	"github.com/0chain/gosdk/core/sys"
+	"github.com/0chain/gosdk/zboxcore/fileref"
+	"github.com/0chain/gosdk/zboxcore/zboxutil"
+)
+
+// DownloadDirectoryOption customizes DownloadDirectory.
+type DownloadDirectoryOption func(o *downloadDirectoryOptions)
+
+type downloadDirectoryOptions struct {
+	ignore      *pathutil.IgnoreRules
+	workdir     string
+	parallelism int
+}
+
+// WithDownloadDirectoryIgnoreRules skips the files matched by the given rules.
+// Paths are matched relative to the downloaded remote directory.
+//   - rules: the gitignore-style ignore rules
+func WithDownloadDirectoryIgnoreRules(rules *pathutil.IgnoreRules) DownloadDirectoryOption {
+	return func(o *downloadDirectoryOptions) {
+		o.ignore = rules
+	}
+}
+
+// WithDownloadDirectoryWorkdir sets the working directory where the manifest
+// of the downloaded files is stored.
+//   - workdir: the working directory
+func WithDownloadDirectoryWorkdir(workdir string) DownloadDirectoryOption {
+	return func(o *downloadDirectoryOptions) {
+		o.workdir = workdir
+	}
+}
+
+// WithDownloadDirectoryParallelism sets the maximum number of files downloaded
+// at the same time, BatchSize by default.
+//   - n: the number of files, ignored if not positive
+func WithDownloadDirectoryParallelism(n int) DownloadDirectoryOption {
+	return func(o *downloadDirectoryOptions) {
+		if n > 0 {
+			o.parallelism = n
+		}
+	}
+}
+
+type downloadDirectoryFile struct {
+	ref       ORef
+	localPath string
+}
+
+// downloadDirectoryStatusBar tracks the files of a batch downloaded successfully.
+type downloadDirectoryStatusBar struct {
+	*StatusBar
+	mu        sync.Mutex
+	completed map[string]bool
+}
+
+func (s *downloadDirectoryStatusBar) Completed(allocationId, filePath string, filename string, mimetype string, size int, op int) {
+	s.mu.Lock()
+	s.completed[filePath] = true
+	s.mu.Unlock()
+	s.StatusBar.Completed(allocationId, filePath, filename, mimetype, size, op)
+}
+
+// DownloadDirectory downloads all the files of the remote directory into the local path.
+// Files are downloaded in parallel batches of BatchSize files by default. When
+// progress saving is enabled, the completed files are recorded in a manifest under the
+// working directory, so that an interrupted download skips them when restarted with the
+// same arguments. Local files whose content already matches the remote file are skipped
+// as well.
+//   - ctx: the context of the download.
+//   - remotePath: the remote directory to download.
+//   - localPath: the local directory to download into.
+//   - authTicket: the auth ticket of a shared directory, empty for the owner.
+//   - sb: the status callback of the download.
+//   - opts: the options of the download.
+func (a *Allocation) DownloadDirectory(ctx context.Context, remotePath, localPath, authTicket string, sb StatusCallback, opts ...DownloadDirectoryOption) error {
+	if len(a.Blobbers) == 0 {
+		return noBLOBBERS
+	}
+	dirOpts := downloadDirectoryOptions{parallelism: BatchSize}
+	for _, opt := range opts {
+		opt(&dirOpts)
+	}
+	fail := func(err error) error {
+		if sb != nil {
+			sb.Error(a.ID, remotePath, OpDownload, err)
+		}
+		return err
+	}
+
+	localPath = filepath.Clean(localPath)
+	dirID := zboxutil.NewConnectionId()
+	err := sys.Files.CreateDirectory(dirID)
+	if err != nil {
+		return fail(err)
+	}
+	defer sys.Files.RemoveAllDirectories()
+
+	progress := loadDirectoryProgress(a.directoryProgressID("download", remotePath, localPath, dirOpts.workdir))
+
+	oRefChan := a.ListObjects(ctx, remotePath, "", "", "", fileref.FILE, fileref.REGULAR, 0, getRefPageLimit,
+		WithListObjectsIgnoreRules(dirOpts.ignore))
+	batch := make([]downloadDirectoryFile, 0, dirOpts.parallelism)
+	dirPath := path.Dir(remotePath)
+	var totalSize int
+	for oRef := range oRefChan {
+		if contextCanceled(ctx) {
+			return fail(ctx.Err())
+		}
+		if oRef.Err != nil {
+			return fail(oRef.Err)
+		}
+		totalSize += int(oRef.ActualFileSize)
+
+		fPath := oRef.Path
+		if dirPath != "/" {
+			fPath = strings.TrimPrefix(oRef.Path, dirPath)
+		}
+		if localPath != "" {
+			fPath = filepath.Join(localPath, fPath)
+		}
+		if isDownloadedLocally(progress, oRef, fPath) {
+			continue
+		}
+
+		batch = append(batch, downloadDirectoryFile{ref: oRef, localPath: fPath})
+		if len(batch) == dirOpts.parallelism {
+			if err := a.downloadDirectoryBatch(dirID, authTicket, batch, progress, sb); err != nil {
+				return fail(err)
+			}
+			batch = batch[:0]
+		}
+	}
+	if len(batch) > 0 {
+		if err := a.downloadDirectoryBatch(dirID, authTicket, batch, progress, sb); err != nil {
+			return fail(err)
+		}
+	}
+
+	removeDirectoryProgress(progress)
+	if sb != nil {
+		sb.Completed(a.ID, remotePath, filepath.Base(remotePath), "", totalSize, OpDownload)
+	}
+	return nil
+}
+
+// downloadDirectoryBatch downloads the files of the batch in parallel and records
+// the completed ones in the manifest, even when some of them failed.
+func (a *Allocation) downloadDirectoryBatch(dirID, authTicket string, batch []downloadDirectoryFile, progress *directoryProgress, sb StatusCallback) error {
+	wg := &sync.WaitGroup{}
+	statusBar := &downloadDirectoryStatusBar{
+		StatusBar: &StatusBar{
+			wg: wg,
+			sb: sb,
+		},
+		completed: make(map[string]bool, len(batch)),
+	}
+
+	// open all the files first, the queued downloads only start with the final one
+	fhs := make([]sys.File, 0, len(batch))
+	for _, f := range batch {
+		fh, err := sys.Files.GetFileHandler(dirID, f.localPath)
+		if err == nil {
+			// a stale local file is rewritten from scratch
+			if t, ok := fh.(interface{ Truncate(size int64) error }); ok {
+				if err = t.Truncate(0); err != nil {
+					fh.Close() //nolint: errcheck
+				}
+			}
+		}
+		if err != nil {
+			for _, fh := range fhs {
+				fh.Close() //nolint: errcheck
+			}
+			return err
+		}
+		fhs = append(fhs, fh)
+	}
+
+	wg.Add(len(batch))
+	for ind, f := range batch {
+		fh := fhs[ind]
+		isFinal := ind == len(batch)-1
+		if authTicket == "" {
+			_ = a.DownloadFileToFileHandler(fh, f.ref.Path, false, statusBar, isFinal, WithFileCallback(func() {
+				fh.Close() //nolint: errcheck
+			})) //nolint: errcheck
+		} else {
+			_ = a.DownloadFileToFileHandlerFromAuthTicket(fh, authTicket, f.ref.LookupHash, f.ref.Path, false, statusBar, isFinal, WithFileCallback(func() {
+				fh.Close() //nolint: errcheck
+			})) //nolint: errcheck
+		}
+	}
+	wg.Wait()
+
+	for _, f := range batch {
+		if !statusBar.completed[f.ref.Path] && !statusBar.completed[f.ref.LookupHash] {
+			continue
+		}
+		info, statErr := sys.Files.Stat(f.localPath)
+		if statErr != nil {
+			continue
+		}
+		progress.Completed[f.ref.Path] = LocalIndexEntry{
+			Size:    info.Size(),
+			ModTime: info.ModTime().UnixNano(),
+			Inode:   fileInode(info),
+			Hash:    f.ref.ActualFileHash,
+		}
+	}
+	saveDirectoryProgress(progress)
+	return statusBar.err
+}
+
+// isDownloadedLocally reports whether the local file already has the content of
+// the remote file, either because it's recorded unchanged in the manifest or
+// because its hash matches. The manifest entry of a stale local file is dropped,
+// the file is kept until its download truncates it.
+func isDownloadedLocally(progress *directoryProgress, ref ORef, localPath string) bool {
+	info, err := sys.Files.Stat(localPath)
+	if err != nil || info.IsDir() || ref.ActualFileHash == "" {
+		return false
+	}
+	state := LocalIndexEntry{
+		Size:    info.Size(),
+		ModTime: info.ModTime().UnixNano(),
+		Inode:   fileInode(info),
+		Hash:    ref.ActualFileHash,
+	}
+	if done, ok := progress.Completed[ref.Path]; ok && done == state {
+		return true
+	}
+	if info.Size() == ref.ActualFileSize {
+		if hash, err := calcFileHash(localPath); err == nil && hash == ref.ActualFileHash {
+			progress.Completed[ref.Path] = state
+			return true
+		}
+	}
+	delete(progress.Completed, ref.Path)
+	return false
+}
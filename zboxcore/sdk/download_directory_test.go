@@ -0,0 +1,61 @@
+package sdk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirectoryProgress(t *testing.T) {
+	workdir := t.TempDir()
+	a := &Allocation{ID: "4f928c7857fabb5737347c42204eea919a4777f893f35724f563b932f64e2367"}
+	id := a.directoryProgressID("download", "/photos", "/home/user/photos", workdir)
+	require.Equal(t, id, a.directoryProgressID("download", "/photos", "/home/user/photos", workdir))
+	require.NotEqual(t, id, a.directoryProgressID("download", "/backup", "/home/user/photos", workdir))
+	require.Equal(t, filepath.Join(workdir, "download"), filepath.Dir(id))
+
+	progress := loadDirectoryProgress(id)
+	require.Empty(t, progress.Completed)
+	progress.Completed["/photos/a.jpg"] = LocalIndexEntry{Size: 5, Hash: "5d41402abc4b2a76b9719d911017c592"}
+	saveDirectoryProgress(progress)
+
+	loaded := loadDirectoryProgress(id)
+	require.Equal(t, progress.Completed, loaded.Completed)
+
+	removeDirectoryProgress(loaded)
+	require.Empty(t, loadDirectoryProgress(id).Completed)
+}
+
+func TestIsDownloadedLocally(t *testing.T) {
+	dir := t.TempDir()
+	progress := &directoryProgress{Completed: make(map[string]LocalIndexEntry)}
+	ref := ORef{}
+	ref.Path = "/docs/a.txt"
+	ref.ActualFileSize = 5
+	ref.ActualFileHash = "5d41402abc4b2a76b9719d911017c592"
+
+	localPath := filepath.Join(dir, "a.txt")
+	require.False(t, isDownloadedLocally(progress, ref, localPath))
+
+	// matching content is skipped and recorded in the manifest
+	require.NoError(t, os.WriteFile(localPath, []byte("hello"), 0644))
+	require.True(t, isDownloadedLocally(progress, ref, localPath))
+	require.Equal(t, ref.ActualFileHash, progress.Completed[ref.Path].Hash)
+
+	// an unchanged file recorded in the manifest isn't rehashed
+	entry := progress.Completed[ref.Path]
+	ref.ActualFileHash = "changed"
+	entry.Hash = ref.ActualFileHash
+	progress.Completed[ref.Path] = entry
+	require.True(t, isDownloadedLocally(progress, ref, localPath))
+
+	// a stale file is kept until it's downloaded again, its manifest entry is dropped
+	ref.ActualFileHash = "7d793037a0760186574b0282f2f435e7"
+	require.False(t, isDownloadedLocally(progress, ref, localPath))
+	require.NotContains(t, progress.Completed, ref.Path)
+	content, err := os.ReadFile(localPath)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(content))
+}
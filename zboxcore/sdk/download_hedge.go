@@ -0,0 +1,183 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/0chain/errors"
+	"github.com/0chain/gosdk/zboxcore/logger"
+	"github.com/0chain/gosdk/zboxcore/zboxutil"
+)
+
+const (
+	// hedgeSamples is the number of the latest block latencies a hedged download keeps.
+	hedgeSamples = 128
+	// minHedgeSamples is the number of block latencies a hedged download needs before hedging.
+	minHedgeSamples = 5
+	// minHedgeDelay is the minimum time to wait for a blobber before hedging.
+	minHedgeDelay = 10 * time.Millisecond
+)
+
+// WithHedgedDownload enables the hedged reads of the download: when a block request
+// takes longer than the given percentile of the latencies of the previous block requests,
+// the same blocks are requested from the next blobber, a parity one, and the first shards
+// to arrive reconstruct the data. The other requests are abandoned: their responses are
+// discarded and their download workers are released right away.
+// The hedged requests don't use the download buffers, so they allocate their responses.
+//   - percentile: the latency percentile, between 0 and 100, e.g. 95. 0 disables the hedging.
+func WithHedgedDownload(percentile float64) DownloadRequestOption {
+	return func(dr *DownloadRequest) {
+		if percentile <= 0 {
+			dr.hedge = nil
+			return
+		}
+		dr.hedge = newDownloadHedge(percentile)
+	}
+}
+
+// downloadHedge keeps the latencies of the block requests of a hedged download.
+type downloadHedge struct {
+	percentile float64
+	mu         sync.Mutex
+	samples    []int64
+	next       int
+}
+
+func newDownloadHedge(percentile float64) *downloadHedge {
+	if percentile > 100 {
+		percentile = 100
+	}
+	return &downloadHedge{percentile: percentile}
+}
+
+// observe records the latency of a successful block request in milliseconds.
+func (h *downloadHedge) observe(ms int64) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.samples) < hedgeSamples {
+		h.samples = append(h.samples, ms)
+		return
+	}
+	h.samples[h.next] = ms
+	h.next = (h.next + 1) % hedgeSamples
+}
+
+// delay returns the time to wait for the blobbers before hedging, false if
+// there are not enough samples yet.
+func (h *downloadHedge) delay() (time.Duration, bool) {
+	if h == nil {
+		return 0, false
+	}
+	h.mu.Lock()
+	samples := append([]int64(nil), h.samples...)
+	h.mu.Unlock()
+	if len(samples) < minHedgeSamples {
+		return 0, false
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	i := int(math.Ceil(h.percentile/100*float64(len(samples)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	d := time.Duration(samples[i]) * time.Millisecond
+	if d < minHedgeDelay {
+		d = minHedgeDelay
+	}
+	return d, true
+}
+
+// downloadBlockHedged downloads the blocks from the first requiredDownloads blobbers of mask and,
+// each time the hedge delay passes without enough shards, from one more blobber. A failed blobber
+// is replaced right away. It returns when requiredDownloads shards are filled, abandoning the other
+// requests, or when no blobber is left, with the number of the missing shards as failed.
+func (req *DownloadRequest) downloadBlockHedged(
+	startBlock, totalBlock int64,
+	mask zboxutil.Uint128, requiredDownloads int,
+	shards [][][]byte) (zboxutil.Uint128, int, []string, error) {
+
+	activeBlobbers := mask.CountOnes()
+	if activeBlobbers < requiredDownloads {
+		return zboxutil.NewUint128(0), 0, nil, errors.New("insufficient_blobbers",
+			fmt.Sprintf("Required downloads %d, remaining active blobber %d",
+				req.consensusThresh, activeBlobbers))
+	}
+
+	ctx, cancel := context.WithCancel(req.ctx)
+	defer cancel()
+	// every blobber answers at most once, so the late responses never block
+	rspCh := make(chan *downloadBlock, activeBlobbers)
+	remainingMask := mask
+	inFlight := 0
+	send := func() bool {
+		if remainingMask.Equals64(0) {
+			return false
+		}
+		pos := uint64(remainingMask.TrailingZeros())
+		remainingMask = remainingMask.And(zboxutil.NewUint128(1).Lsh(pos).Not())
+		req.sendBlockDownloadRequest(ctx, pos, startBlock, totalBlock, rspCh, false)
+		inFlight++
+		return true
+	}
+	for i := 0; i < requiredDownloads; i++ {
+		send()
+	}
+
+	var (
+		timer *time.Timer
+		hedge <-chan time.Time
+	)
+	delay, ok := req.hedge.delay()
+	if ok {
+		timer = time.NewTimer(delay)
+		defer timer.Stop()
+		hedge = timer.C
+	}
+
+	var (
+		succeeded      int
+		downloadErrors []string
+	)
+	for succeeded < requiredDownloads && inFlight > 0 {
+		select {
+		case <-req.ctx.Done():
+			return remainingMask, requiredDownloads - succeeded, downloadErrors, req.ctx.Err()
+		case <-hedge:
+			if !send() {
+				hedge = nil
+				continue
+			}
+			logger.Logger.Debug(fmt.Sprintf("Hedging the download of block %d after %v", startBlock, delay))
+			timer.Reset(delay)
+		case result := <-rspCh:
+			inFlight--
+			var err error
+			if !result.Success {
+				err = fmt.Errorf("Unsuccessful download. Error: %v", result.err)
+			} else {
+				err = req.fillShards(shards, result)
+			}
+			if err != nil {
+				req.removeFromMask(uint64(result.maskIdx))
+				downloadErrors = append(downloadErrors, fmt.Sprintf("Error %s from %s",
+					err.Error(), req.blobbers[result.idx].Baseurl))
+				logger.Logger.Error(err)
+				send()
+				continue
+			}
+			succeeded++
+			req.hedge.observe(result.timeTaken)
+		}
+	}
+
+	if succeeded < requiredDownloads {
+		return remainingMask, requiredDownloads - succeeded, downloadErrors, nil
+	}
+	return remainingMask, 0, nil, nil
+}
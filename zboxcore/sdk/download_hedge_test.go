@@ -0,0 +1,141 @@
+package sdk
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/0chain/gosdk/zboxcore/blockchain"
+	zclient "github.com/0chain/gosdk/zboxcore/client"
+	"github.com/0chain/gosdk/zboxcore/zboxutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadHedge(t *testing.T) {
+	var disabled *downloadHedge
+	disabled.observe(10)
+	_, ok := disabled.delay()
+	require.False(t, ok)
+
+	h := newDownloadHedge(90)
+	for i := int64(1); i < minHedgeSamples; i++ {
+		h.observe(i * 100)
+	}
+	_, ok = h.delay()
+	require.False(t, ok, "not enough samples")
+
+	for i := int64(minHedgeSamples); i <= 10; i++ {
+		h.observe(i * 100)
+	}
+	d, ok := h.delay()
+	require.True(t, ok)
+	require.Equal(t, 900*time.Millisecond, d)
+
+	// the oldest samples are replaced
+	for i := 0; i < hedgeSamples; i++ {
+		h.observe(1)
+	}
+	require.Len(t, h.samples, hedgeSamples)
+	d, _ = h.delay()
+	require.Equal(t, minHedgeDelay, d)
+}
+
+func TestWithHedgedDownload(t *testing.T) {
+	req := &DownloadRequest{}
+	WithHedgedDownload(150)(req)
+	require.NotNil(t, req.hedge)
+	require.Equal(t, float64(100), req.hedge.percentile)
+
+	WithHedgedDownload(0)(req)
+	require.Nil(t, req.hedge)
+}
+
+// setupHedgedDownload returns a download from blobbers served by the handlers,
+// whose responses are blocks of hedgeTestBlockSize bytes.
+func setupHedgedDownload(t *testing.T, handlers ...http.HandlerFunc) *DownloadRequest {
+	prevSign := zclient.Sign
+	t.Cleanup(func() { zclient.Sign = prevSign })
+	zclient.Sign = func(hash string) (string, error) { return "signature", nil }
+
+	req := &DownloadRequest{
+		ctx:                context.Background(),
+		chunkSize:          hedgeTestBlockSize,
+		effectiveBlockSize: hedgeTestBlockSize,
+		maskMu:             &sync.Mutex{},
+		downloadMask:       zboxutil.NewUint128(1).Lsh(uint64(len(handlers))).Sub64(1),
+	}
+	req.consensusThresh = 2
+	for i, handler := range handlers {
+		server := httptest.NewServer(handler)
+		t.Cleanup(server.Close)
+		// the pending requests are stopped before closing the server
+		t.Cleanup(server.CloseClientConnections)
+		req.blobbers = append(req.blobbers, &blockchain.StorageNode{
+			ID:      t.Name() + strconv.Itoa(i),
+			Baseurl: server.URL,
+		})
+		req.downloadQueue = append(req.downloadQueue, downloadPriority{blobberIdx: i})
+	}
+	InitBlockDownloader(req.blobbers, 4)
+	return req
+}
+
+const hedgeTestBlockSize = 8
+
+func respondBlock(data string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(data)) //nolint: errcheck
+	}
+}
+
+// respondSlowly never answers, until the connection is closed.
+func respondSlowly(w http.ResponseWriter, r *http.Request) {
+	<-r.Context().Done()
+}
+
+func TestDownloadBlockHedged(t *testing.T) {
+	t.Run("slow blobber hedged", func(t *testing.T) {
+		req := setupHedgedDownload(t, respondBlock("blobber0"), respondSlowly, respondBlock("blobber2"))
+		req.hedge = newDownloadHedge(90)
+		for i := 0; i < minHedgeSamples; i++ {
+			req.hedge.observe(1)
+		}
+
+		shards := [][][]byte{make([][]byte, 3)}
+		remainingMask, failed, downloadErrors, err := req.downloadBlockHedged(0, 1, req.downloadMask, 2, shards)
+		require.NoError(t, err)
+		require.Zero(t, failed)
+		require.Empty(t, downloadErrors)
+		require.True(t, remainingMask.Equals64(0), "the parity blobber is requested")
+		require.Equal(t, [][]byte{[]byte("blobber0"), nil, []byte("blobber2")}, shards[0])
+	})
+
+	t.Run("failed blobber replaced", func(t *testing.T) {
+		req := setupHedgedDownload(t, func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "unavailable", http.StatusBadRequest)
+		}, respondBlock("blobber1"), respondBlock("blobber2"))
+		req.hedge = newDownloadHedge(90)
+
+		shards := [][][]byte{make([][]byte, 3)}
+		_, failed, _, err := req.downloadBlockHedged(0, 1, req.downloadMask, 2, shards)
+		require.NoError(t, err)
+		require.Zero(t, failed)
+		require.Equal(t, [][]byte{nil, []byte("blobber1"), []byte("blobber2")}, shards[0])
+		require.True(t, req.downloadMask.Equals64(6), "the failed blobber is removed from the download")
+	})
+}
+
+func TestGetWithRequestContext(t *testing.T) {
+	req := setupHedgedDownload(t, respondSlowly)
+	httpreq, err := zboxutil.NewFastDownloadRequest(req.blobbers[0].Baseurl, "alloc", "tx")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, _, err = getWithRequestContext(ctx, zboxutil.GetFastHTTPClient(), httpreq, nil)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -25,6 +25,12 @@ type DownloadProgressStorer interface {
 	Save(dp *DownloadProgress)
 }
 
+// downloadProgressFlusher is implemented by the download progress storers able to
+// save the progress immediately, when a download is paused.
+type downloadProgressFlusher interface {
+	Flush()
+}
+
 type FsDownloadProgressStorer struct {
 	sync.Mutex
 	isRemoved bool
@@ -57,12 +63,7 @@ func (ds *FsDownloadProgressStorer) Start(ctx context.Context) {
 					ds.Unlock()
 					return
 				}
-				if len(ds.queue) > 0 && ds.queue[0] == ds.next {
-					for len(ds.queue) > 0 && ds.queue[0] == ds.next {
-						ds.dp.LastWrittenBlock = ds.next
-						heap.Pop(&ds.queue)
-						ds.next += ds.dp.numBlocks
-					}
+				if ds.advance() {
 					ds.Unlock()
 					ds.saveToDisk()
 				} else {
@@ -73,6 +74,33 @@ func (ds *FsDownloadProgressStorer) Start(ctx context.Context) {
 	}()
 }
 
+// advance moves the last written block past the contiguous written blocks.
+// It reports whether the last written block changed.
+func (ds *FsDownloadProgressStorer) advance() bool {
+	advanced := false
+	for len(ds.queue) > 0 && ds.queue[0] == ds.next {
+		ds.dp.LastWrittenBlock = ds.next
+		heap.Pop(&ds.queue)
+		ds.next += ds.dp.numBlocks
+		advanced = true
+	}
+	return advanced
+}
+
+// Flush saves the progress of the contiguous blocks written so far. The blocks written
+// after a gap are dropped, they're downloaded again when the download is resumed.
+func (ds *FsDownloadProgressStorer) Flush() {
+	ds.Lock()
+	if ds.isRemoved || ds.dp == nil {
+		ds.Unlock()
+		return
+	}
+	ds.advance()
+	ds.queue = ds.queue[:0]
+	ds.Unlock()
+	ds.saveToDisk()
+}
+
 func (ds *FsDownloadProgressStorer) Load(progressID string, numBlocks int) *DownloadProgress {
 	dp := &DownloadProgress{}
 	buf, err := sys.Files.LoadProgress(progressID)
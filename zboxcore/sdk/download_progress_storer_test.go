@@ -0,0 +1,96 @@
+package sdk
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFsDownloadProgressStorerFlush(t *testing.T) {
+	id := filepath.Join(t.TempDir(), "progress")
+	ds := CreateFsDownloadProgress()
+	ds.Save(&DownloadProgress{ID: id, numBlocks: 10})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ds.Start(ctx)
+
+	ds.Update(10)
+	// the block ending at 20 is missing
+	ds.Update(30)
+	ds.Flush()
+	require.Empty(t, ds.queue)
+
+	dp := CreateFsDownloadProgress().Load(id, 10)
+	require.NotNil(t, dp)
+	require.Equal(t, 10, dp.LastWrittenBlock)
+
+	require.NoError(t, ds.Remove())
+	ds.Flush()
+	require.Nil(t, CreateFsDownloadProgress().Load(id, 10))
+}
+
+func TestPauseDownload(t *testing.T) {
+	a := &Allocation{
+		mutex:               &sync.Mutex{},
+		downloadProgressMap: make(map[string]*DownloadRequest),
+		pausedDownloads:     make(map[string]*DownloadRequest),
+	}
+	newRequest := func(storer DownloadProgressStorer) *DownloadRequest {
+		req := &DownloadRequest{
+			localFilePath:  "/tmp/a.txt",
+			contentMode:    DOWNLOAD_CONTENT_FULL,
+			downloadStorer: storer,
+			pauseDone:      make(chan struct{}),
+		}
+		req.ctx, req.ctxCncl = context.WithCancel(context.Background())
+		return req
+	}
+
+	require.Error(t, a.PauseDownload("/a.txt"))
+	require.Error(t, a.ResumeDownload("/a.txt"))
+
+	a.downloadProgressMap["/a.txt"] = newRequest(nil)
+	err := a.PauseDownload("/a.txt")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "download_not_resumable")
+
+	req := newRequest(CreateFsDownloadProgress())
+	a.downloadProgressMap["/a.txt"] = req
+	require.NoError(t, a.PauseDownload("/a.txt"))
+	require.True(t, req.isDownloadPaused)
+	require.Error(t, req.ctx.Err())
+	require.NotContains(t, a.downloadProgressMap, "/a.txt")
+	require.Contains(t, a.pausedDownloads, "/a.txt")
+
+	// the download completed before the workers noticed the pause
+	req.stopped()
+	require.NoError(t, a.ResumeDownload("/a.txt"))
+	require.NotContains(t, a.pausedDownloads, "/a.txt")
+
+	// a download queued for the final one is stopped right away
+	fh, err := os.Create(filepath.Join(t.TempDir(), "b.txt"))
+	require.NoError(t, err)
+	status := &recordingStatusCallback{}
+	req = newRequest(CreateFsDownloadProgress())
+	req.localFilePath = fh.Name()
+	req.fileHandler = fh
+	req.statusCallback = status
+	other := newRequest(CreateFsDownloadProgress())
+	a.downloadProgressMap["/b.txt"] = req
+	a.downloadRequests = []*DownloadRequest{req, other}
+	require.NoError(t, a.PauseDownload("/b.txt"))
+	require.Equal(t, []*DownloadRequest{other}, a.downloadRequests)
+	require.True(t, req.skip)
+	require.Equal(t, []string{"error /b.txt"}, status.calls)
+	select {
+	case <-req.pauseDone:
+	default:
+		t.Fatal("the queued download isn't stopped")
+	}
+	_, err = os.Stat(req.localFilePath)
+	require.True(t, os.IsNotExist(err))
+}
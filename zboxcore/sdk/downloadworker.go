@@ -42,6 +42,9 @@ const (
 
 var (
 	extraCount = 2
+
+	// ErrPauseDownload is the error reported to the status callback of a download paused by PauseDownload.
+	ErrPauseDownload = errors.New("download_paused", "download paused by user")
 )
 
 type DownloadRequestOption func(dr *DownloadRequest)
@@ -66,6 +69,15 @@ func WithFileCallback(cb func()) DownloadRequestOption {
 	}
 }
 
+// WithDownloadRateLimit throttles the download with the given limiter, on top of the
+// process-wide and allocation limits. The limiter can be shared by several downloads.
+//   - limiter: the bandwidth limiter.
+func WithDownloadRateLimit(limiter *BandwidthLimiter) DownloadRequestOption {
+	return func(dr *DownloadRequest) {
+		dr.bandwidthLimiters = append(dr.bandwidthLimiters, limiter)
+	}
+}
+
 type DownloadRequest struct {
 	allocationID       string
 	allocationTx       string
@@ -91,6 +103,9 @@ type DownloadRequest struct {
 	downloadMask       zboxutil.Uint128
 	encryptedKey       string
 	isDownloadCanceled bool
+	isDownloadPaused   bool
+	pauseDone          chan struct{}
+	pauseOnce          sync.Once
 	completedCallback  func(remotepath string, remotepathhash string)
 	fileCallback       func()
 	contentMode        string
@@ -114,6 +129,15 @@ type DownloadRequest struct {
 	downloadQueue      downloadQueue // Always initialize this queue with max time taken
 	isResume           bool
 	isEnterprise       bool
+	bandwidthLimiters  []*BandwidthLimiter
+	verifyDownload     bool
+	downloadOpts       []DownloadRequestOption
+	span               zboxutil.Span
+	// hedge is set by WithHedgedDownload.
+	hedge *downloadHedge
+	// healthRanked is true when the health of all the download blobbers is known, so
+	// the first blocks are requested from the fastest ones instead of all of them.
+	healthRanked bool
 }
 
 type downloadPriority struct {
@@ -162,8 +186,13 @@ func (req *DownloadRequest) getBlocksDataFromBlobbers(startBlock, totalBlock int
 
 	curReqDownloads := requiredDownloads
 	for {
-		remainingMask, failed, downloadErrors, err = req.downloadBlock(
-			startBlock, totalBlock, mask, curReqDownloads, shards, timeRequest)
+		if req.hedge != nil && !timeRequest {
+			remainingMask, failed, downloadErrors, err = req.downloadBlockHedged(
+				startBlock, totalBlock, mask, curReqDownloads, shards)
+		} else {
+			remainingMask, failed, downloadErrors, err = req.downloadBlock(
+				startBlock, totalBlock, mask, curReqDownloads, shards, timeRequest)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -229,9 +258,8 @@ func (req *DownloadRequest) downloadBlock(
 	rspCh := make(chan *downloadBlock, requiredDownloads)
 
 	var (
-		pos          uint64
-		c            int
-		skipDownload bool
+		pos uint64
+		c   int
 	)
 
 	for i := mask; !i.Equals64(0); i = i.And(zboxutil.NewUint128(1).Lsh(pos).Not()) {
@@ -241,46 +269,7 @@ func (req *DownloadRequest) downloadBlock(
 		}
 
 		pos = uint64(i.TrailingZeros())
-		blobberIdx := req.downloadQueue[pos].blobberIdx
-		blockDownloadReq := &BlockDownloadRequest{
-			allocationID:       req.allocationID,
-			allocationTx:       req.allocationTx,
-			allocOwnerID:       req.allocOwnerID,
-			authTicket:         req.authTicket,
-			blobber:            req.blobbers[blobberIdx],
-			blobberIdx:         blobberIdx,
-			maskIdx:            int(pos),
-			chunkSize:          req.chunkSize,
-			blockNum:           startBlock,
-			contentMode:        req.contentMode,
-			result:             rspCh,
-			ctx:                req.ctx,
-			remotefilepath:     req.remotefilepath,
-			remotefilepathhash: req.remotefilepathhash,
-			numBlocks:          totalBlock,
-			encryptedKey:       req.encryptedKey,
-			shouldVerify:       req.shouldVerify,
-			connectionID:       req.connectionID,
-		}
-
-		if blockDownloadReq.blobber.IsSkip() {
-			rspCh <- &downloadBlock{
-				Success: false,
-				idx:     blockDownloadReq.blobberIdx,
-				err:     errors.New("", "skip blobber by previous errors")}
-			skipDownload = true
-		}
-
-		if !skipDownload {
-			bf := req.validationRootMap[blockDownloadReq.blobber.ID]
-			blockDownloadReq.blobberFile = bf
-			if req.shouldVerify {
-				go AddBlockDownloadReq(req.ctx, blockDownloadReq, nil, req.effectiveBlockSize)
-			} else {
-				go AddBlockDownloadReq(req.ctx, blockDownloadReq, req.bufferMap[blobberIdx], req.effectiveBlockSize)
-			}
-		}
-
+		req.sendBlockDownloadRequest(req.ctx, pos, startBlock, totalBlock, rspCh, true)
 		c++
 	}
 
@@ -307,8 +296,11 @@ func (req *DownloadRequest) downloadBlock(
 					if req.bufferMap != nil && req.bufferMap[result.idx] != nil {
 						req.bufferMap[result.idx].ReleaseChunk(int(req.startBlock))
 					}
-				} else if timeRequest {
-					req.downloadQueue[result.maskIdx].timeTaken = result.timeTaken
+				} else {
+					if timeRequest {
+						req.downloadQueue[result.maskIdx].timeTaken = result.timeTaken
+					}
+					req.hedge.observe(result.timeTaken)
 				}
 				wg.Done()
 			}()
@@ -324,6 +316,53 @@ func (req *DownloadRequest) downloadBlock(
 	return remainingMask, int(failed), downloadErrors, nil
 }
 
+// sendBlockDownloadRequest requests the blocks from the blobber at the position pos of the download queue,
+// the result is sent to rspCh. The response is read in the download buffer of the blobber if useBuffer is true.
+func (req *DownloadRequest) sendBlockDownloadRequest(
+	ctx context.Context, pos uint64,
+	startBlock, totalBlock int64,
+	rspCh chan *downloadBlock, useBuffer bool) {
+
+	blobberIdx := req.downloadQueue[pos].blobberIdx
+	blockDownloadReq := &BlockDownloadRequest{
+		allocationID:       req.allocationID,
+		allocationTx:       req.allocationTx,
+		allocOwnerID:       req.allocOwnerID,
+		authTicket:         req.authTicket,
+		blobber:            req.blobbers[blobberIdx],
+		blobberIdx:         blobberIdx,
+		maskIdx:            int(pos),
+		chunkSize:          req.chunkSize,
+		blockNum:           startBlock,
+		contentMode:        req.contentMode,
+		result:             rspCh,
+		ctx:                ctx,
+		remotefilepath:     req.remotefilepath,
+		remotefilepathhash: req.remotefilepathhash,
+		numBlocks:          totalBlock,
+		encryptedKey:       req.encryptedKey,
+		shouldVerify:       req.shouldVerify,
+		connectionID:       req.connectionID,
+		bandwidthLimiters:  req.bandwidthLimiters,
+	}
+
+	if blockDownloadReq.blobber.IsSkip() {
+		rspCh <- &downloadBlock{
+			Success: false,
+			idx:     blockDownloadReq.blobberIdx,
+			maskIdx: blockDownloadReq.maskIdx,
+			err:     errors.New("", "skip blobber by previous errors")}
+		return
+	}
+
+	blockDownloadReq.blobberFile = req.validationRootMap[blockDownloadReq.blobber.ID]
+	if req.shouldVerify || !useBuffer {
+		go AddBlockDownloadReq(ctx, blockDownloadReq, nil, req.effectiveBlockSize)
+	} else {
+		go AddBlockDownloadReq(ctx, blockDownloadReq, req.bufferMap[blobberIdx], req.effectiveBlockSize)
+	}
+}
+
 // decodeEC will reconstruct shards and verify it
 func (req *DownloadRequest) decodeEC(shards [][]byte) (err error) {
 	err = req.ecEncoder.ReconstructData(shards)
@@ -422,6 +461,12 @@ func (req *DownloadRequest) getDecryptedDataForAuthTicket(result *downloadBlock,
 // start block, end block and number of blocks to download in single request.
 // This will also write data to the file handler and will verify content by calculating content hash.
 func (req *DownloadRequest) processDownload() {
+	req.ctx, req.span = zboxutil.StartSpan(req.ctx, "Download",
+		zboxutil.Attribute("allocation.id", req.allocationID),
+		zboxutil.Attribute("file.path", req.remotefilepath),
+		zboxutil.Attribute("download.start_block", req.startBlock),
+		zboxutil.Attribute("download.end_block", req.endBlock))
+	defer req.span.End()
 	ctx := req.ctx
 	if req.completedCallback != nil {
 		defer req.completedCallback(req.remotefilepath, req.remotefilepathhash)
@@ -434,6 +479,7 @@ func (req *DownloadRequest) processDownload() {
 		}()
 	}
 	defer req.ctxCncl()
+	defer req.stopped()
 	remotePathCB := req.remotefilepath
 	if remotePathCB == "" {
 		remotePathCB = req.remotefilepathhash
@@ -722,7 +768,10 @@ func (req *DownloadRequest) processDownload() {
 			if startBlock+int64(j)*numBlocks+numBlocks > endBlock {
 				blocksToDownload = endBlock - (startBlock + int64(j)*numBlocks)
 			}
-			data, err := req.getBlocksData(startBlock+int64(j)*numBlocks, blocksToDownload, j == 0)
+			data, err := req.getBlocksData(startBlock+int64(j)*numBlocks, blocksToDownload, j == 0 && !req.healthRanked)
+			if req.isDownloadPaused {
+				return ErrPauseDownload
+			}
 			if req.isDownloadCanceled {
 				return errors.New("download_abort", "Download aborted by user")
 			}
@@ -752,7 +801,7 @@ func (req *DownloadRequest) processDownload() {
 					rb.ReleaseChunk(int(startBlock + int64(j)*numBlocks))
 				}
 				if req.downloadStorer != nil {
-					go req.downloadStorer.Update(int(startBlock + int64(j)*numBlocks + blocksToDownload))
+					req.downloadStorer.Update(int(startBlock + int64(j)*numBlocks + blocksToDownload))
 				}
 				if req.statusCallback != nil {
 					progressLock.Lock()
@@ -989,9 +1038,18 @@ func (req *DownloadRequest) errorCB(err error, remotePathCB string) {
 	if req.contentMode == DOWNLOAD_CONTENT_THUMB {
 		op = opThumbnailDownload
 	}
-	if req.downloadStorer != nil && !strings.Contains(err.Error(), "context canceled") {
+	defer req.stopped()
+	if req.isDownloadPaused {
+		err = ErrPauseDownload
+		if flusher, ok := req.downloadStorer.(downloadProgressFlusher); ok {
+			flusher.Flush()
+		}
+	} else if req.downloadStorer != nil && !strings.Contains(err.Error(), "context canceled") {
 		req.downloadStorer.Remove() //nolint: errcheck
 	}
+	if req.span != nil {
+		req.span.RecordError(err)
+	}
 	if req.skip {
 		return
 	}
@@ -1010,6 +1068,16 @@ func (req *DownloadRequest) errorCB(err error, remotePathCB string) {
 	}
 }
 
+// stopped signals a paused download that its workers are stopped.
+func (req *DownloadRequest) stopped() {
+	if req.pauseDone == nil {
+		return
+	}
+	req.pauseOnce.Do(func() {
+		close(req.pauseDone)
+	})
+}
+
 func (req *DownloadRequest) calculateShardsParams(
 	fRef *fileref.FileRef) (chunksPerShard int64, err error) {
 
@@ -1199,7 +1267,14 @@ func (req *DownloadRequest) getFileMetaConsensus(fMetaResp []*fileMetaResponse)
 	if req.freeRead {
 		countThreshold = req.fullconsensus
 	}
-	for i := 0; i < len(fMetaResp); i++ {
+	// prefer the healthiest blobbers
+	ids := make([]string, len(fMetaResp))
+	for i, fmr := range fMetaResp {
+		ids[i] = req.blobbers[fmr.blobberIdx].ID
+	}
+	order := blobberHealth.Rank(ids)
+	req.healthRanked = true
+	for _, i := range order {
 		fmr := fMetaResp[i]
 		if fmr.err != nil || fmr.fileref == nil {
 			continue
@@ -1233,9 +1308,14 @@ func (req *DownloadRequest) getFileMetaConsensus(fMetaResp []*fileMetaResponse)
 		}
 		shift := zboxutil.NewUint128(1).Lsh(uint64(fmr.blobberIdx))
 		foundMask = foundMask.Or(shift)
+		timeTaken, known := blobberHealth.ExpectedLatency(req.blobbers[fmr.blobberIdx].ID)
+		if !known {
+			timeTaken = 60000
+			req.healthRanked = false
+		}
 		req.downloadQueue[fmr.blobberIdx] = downloadPriority{
 			blobberIdx: fmr.blobberIdx,
-			timeTaken:  60000,
+			timeTaken:  timeTaken,
 		}
 		blobberCount++
 		if blobberCount == countThreshold {
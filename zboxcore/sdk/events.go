@@ -0,0 +1,421 @@
+package sdk
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0chain/errors"
+	"github.com/0chain/gosdk/constants"
+	"github.com/0chain/gosdk/zboxcore/zboxutil"
+)
+
+// EventType is the type of an Event.
+type EventType string
+
+const (
+	// EventStarted is emitted when an operation starts transferring data.
+	EventStarted EventType = "started"
+	// EventProgress is emitted when an operation transferred more data.
+	EventProgress EventType = "progress"
+	// EventCompleted is emitted when an operation succeeded.
+	EventCompleted EventType = "completed"
+	// EventFailed is emitted when an operation failed, was paused or canceled.
+	EventFailed EventType = "failed"
+	// EventRepairCompleted is emitted when a repair is done.
+	EventRepairCompleted EventType = "repair_completed"
+	// EventBlobberRetry is emitted when a request to a blobber is retried.
+	EventBlobberRetry EventType = "blobber_retry"
+	// EventBlobberFailed is emitted when a blobber failed its part of an operation.
+	EventBlobberFailed EventType = "blobber_failed"
+	// EventCommitted is emitted when the changes of a multi-operation are committed.
+	EventCommitted EventType = "committed"
+)
+
+// OperationKind is the kind of operation an Event relates to.
+type OperationKind string
+
+const (
+	OperationUpload            OperationKind = "upload"
+	OperationUpdate            OperationKind = "update"
+	OperationDownload          OperationKind = "download"
+	OperationThumbnailDownload OperationKind = "thumbnail_download"
+	OperationRepair            OperationKind = "repair"
+	OperationDelete            OperationKind = "delete"
+	OperationMulti             OperationKind = "multi_operation"
+)
+
+// operationKind maps the op codes of StatusCallback to operation kinds.
+func operationKind(op int) OperationKind {
+	switch op {
+	case OpUpload:
+		return OperationUpload
+	case OpUpdate:
+		return OperationUpdate
+	case OpDownload:
+		return OperationDownload
+	case opThumbnailDownload:
+		return OperationThumbnailDownload
+	case OpRepair:
+		return OperationRepair
+	case OpDelete:
+		return OperationDelete
+	}
+	return OperationKind("unknown")
+}
+
+// ErrorClass is a coarse classification of the error of an Event.
+type ErrorClass string
+
+const (
+	ErrorClassPaused       ErrorClass = "paused"
+	ErrorClassCanceled     ErrorClass = "canceled"
+	ErrorClassTimeout      ErrorClass = "timeout"
+	ErrorClassNetwork      ErrorClass = "network"
+	ErrorClassRateLimit    ErrorClass = "rate_limit"
+	ErrorClassConsensus    ErrorClass = "consensus"
+	ErrorClassPermission   ErrorClass = "permission"
+	ErrorClassInsufficient ErrorClass = "insufficient_funds"
+	ErrorClassVerification ErrorClass = "verification"
+	ErrorClassOther        ErrorClass = "other"
+)
+
+// ClassifyError returns the class of an error of the SDK, empty for a nil error.
+//   - err: the error to classify.
+func ClassifyError(err error) ErrorClass {
+	if err == nil {
+		return ""
+	}
+	var netErr net.Error
+	switch {
+	case errors.Is(err, ErrPauseUpload), errors.Is(err, ErrPauseDownload), errors.Is(err, ErrTransferPaused):
+		return ErrorClassPaused
+	case errors.Is(err, ErrTransferCanceled), errors.Is(err, context.Canceled),
+		IsErrCode(err, "download_abort"), strings.Contains(err.Error(), "canceled by user"):
+		return ErrorClassCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorClassTimeout
+	case IsErrCode(err, RateLimitError):
+		return ErrorClassRateLimit
+	case IsErrCode(err, "consensus_not_met"), IsErrCode(err, "consistency_check_failed"):
+		return ErrorClassConsensus
+	case errors.Is(err, constants.ErrFileOptionNotPermitted), IsErrCode(err, InvalidAuthTicket), IsErrCode(err, InvalidShare):
+		return ErrorClassPermission
+	case IsErrCode(err, NotEnoughTokens):
+		return ErrorClassInsufficient
+	case IsErrCode(err, "merkle_path_verification_error"), strings.Contains(err.Error(), "calculated file hash"):
+		return ErrorClassVerification
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			return ErrorClassTimeout
+		}
+		return ErrorClassNetwork
+	case IsErrCode(err, "connection_closed"):
+		return ErrorClassNetwork
+	}
+	return ErrorClassOther
+}
+
+// Event is a typed notification of the progress of an operation.
+type Event struct {
+	// Type is the type of the event.
+	Type EventType `json:"type"`
+
+	// OperationID identifies the operation, it's the connection id of the operation.
+	OperationID string `json:"operation_id"`
+
+	// AllocationID is the id of the allocation.
+	AllocationID string `json:"allocation_id"`
+
+	// Op is the kind of operation.
+	Op OperationKind `json:"op"`
+
+	// Path is the remote path of the file, or its lookup hash for the downloads with an auth ticket.
+	Path string `json:"path,omitempty"`
+
+	// BlobberID is the id of the blobber for the blobber events.
+	BlobberID string `json:"blobber_id,omitempty"`
+
+	// Bytes is the number of bytes transferred so far.
+	Bytes int64 `json:"bytes"`
+
+	// TotalBytes is the number of bytes to transfer.
+	TotalBytes int64 `json:"total_bytes"`
+
+	// Throughput is the average throughput since the start of the operation, in bytes per second.
+	Throughput float64 `json:"throughput"`
+
+	// Retries is the number of retries of a blobber request.
+	Retries int `json:"retries,omitempty"`
+
+	// Consensus is the number of blobbers which committed the changes.
+	Consensus int `json:"consensus,omitempty"`
+
+	// FilesRepaired is the number of files repaired by a repair.
+	FilesRepaired int `json:"files_repaired,omitempty"`
+
+	// Err is the error of a failed event.
+	Err error `json:"-"`
+
+	// ErrorClass is the class of Err.
+	ErrorClass ErrorClass `json:"error_class,omitempty"`
+
+	// Time is the time of the event.
+	Time time.Time `json:"time"`
+}
+
+// EventBus dispatches events to subscribers. Publishing never blocks: the events
+// are dropped for the subscribers whose buffer is full.
+type EventBus struct {
+	mu     sync.RWMutex
+	subs   map[uint64]*eventSubscription
+	nextID uint64
+}
+
+type eventSubscription struct {
+	ch     chan Event
+	filter func(Event) bool
+}
+
+var defaultEventBus = NewEventBus()
+
+// Events returns the event bus receiving the events of all the uploads, downloads
+// and multi-operations of the process.
+func Events() *EventBus {
+	return defaultEventBus
+}
+
+// NewEventBus creates an event bus without subscribers.
+func NewEventBus() *EventBus {
+	return &EventBus{subs: make(map[uint64]*eventSubscription)}
+}
+
+// Subscribe registers a subscriber and returns the channel of its events, and the
+// function unregistering it and closing the channel.
+//   - buffer: the size of the buffer of the channel.
+//   - filter: selects the events to receive, all events if nil.
+func (b *EventBus) Subscribe(buffer int, filter func(Event) bool) (<-chan Event, func()) {
+	sub := &eventSubscription{ch: make(chan Event, buffer), filter: filter}
+	b.mu.Lock()
+	b.nextID++
+	id := b.nextID
+	b.subs[id] = sub
+	b.mu.Unlock()
+
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, id)
+			close(sub.ch)
+			b.mu.Unlock()
+		})
+	}
+}
+
+// HasSubscribers reports whether the bus has at least one subscriber.
+func (b *EventBus) HasSubscribers() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subs) > 0
+}
+
+// Publish sends an event to the subscribers. The time of the event is set if empty.
+//   - e: the event.
+func (b *EventBus) Publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if e.Err != nil && e.ErrorClass == "" {
+		e.ErrorClass = ClassifyError(e.Err)
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, sub := range b.subs {
+		if sub.filter != nil && !sub.filter(e) {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+		}
+	}
+}
+
+// publishEvent publishes an event on the default bus, skipping the building of the
+// event when there is no subscriber.
+func publishEvent(build func() Event) {
+	if defaultEventBus.HasSubscribers() {
+		defaultEventBus.Publish(build())
+	}
+}
+
+// eventStatusCallback publishes the calls of a status callback as events.
+type eventStatusCallback struct {
+	bus         *EventBus
+	sb          StatusCallback
+	operationID string
+
+	mu      sync.Mutex
+	started map[string]*eventOperation
+}
+
+type eventOperation struct {
+	id         string
+	start      time.Time
+	totalBytes int64
+}
+
+// NewEventStatusCallback returns a status callback publishing the calls as events on the
+// bus, then forwarding them to the wrapped status callback. The SDK wraps the status
+// callbacks of the uploads and downloads with the bus of Events, so existing status
+// callbacks keep working while events are emitted.
+//   - bus: the event bus.
+//   - sb: the wrapped status callback, can be nil.
+func NewEventStatusCallback(bus *EventBus, sb StatusCallback) StatusCallback {
+	return newEventStatusCallback(bus, sb, "")
+}
+
+// newEventStatusCallback wraps the status callback of an operation, the events of an
+// empty operationID get a new id per path.
+func newEventStatusCallback(bus *EventBus, sb StatusCallback, operationID string) StatusCallback {
+	if ec, ok := sb.(*eventStatusCallback); ok && ec.bus == bus {
+		sb = ec.sb
+	}
+	return &eventStatusCallback{
+		bus:         bus,
+		sb:          sb,
+		operationID: operationID,
+		started:     make(map[string]*eventOperation),
+	}
+}
+
+func (ec *eventStatusCallback) operation(filePath string, op int, remove bool) *eventOperation {
+	key := filePath + ":" + string(operationKind(op))
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+	o, ok := ec.started[key]
+	if !ok {
+		id := ec.operationID
+		if id == "" {
+			id = zboxutil.NewConnectionId()
+		}
+		o = &eventOperation{id: id, start: time.Now()}
+		ec.started[key] = o
+	}
+	if remove {
+		delete(ec.started, key)
+	}
+	return o
+}
+
+func (ec *eventStatusCallback) event(t EventType, allocationID, filePath string, op int, bytes int, o *eventOperation) Event {
+	e := Event{
+		Type:         t,
+		OperationID:  o.id,
+		AllocationID: allocationID,
+		Op:           operationKind(op),
+		Path:         filePath,
+		Bytes:        int64(bytes),
+		TotalBytes:   o.totalBytes,
+		Time:         time.Now(),
+	}
+	if elapsed := e.Time.Sub(o.start).Seconds(); elapsed > 0 {
+		e.Throughput = float64(bytes) / elapsed
+	}
+	return e
+}
+
+func (ec *eventStatusCallback) Started(allocationId, filePath string, op int, totalBytes int) {
+	if ec.bus.HasSubscribers() {
+		o := ec.operation(filePath, op, false)
+		ec.mu.Lock()
+		o.start = time.Now()
+		o.totalBytes = int64(totalBytes)
+		ec.mu.Unlock()
+		ec.bus.Publish(ec.event(EventStarted, allocationId, filePath, op, 0, o))
+	}
+	if ec.sb != nil {
+		ec.sb.Started(allocationId, filePath, op, totalBytes)
+	}
+}
+
+func (ec *eventStatusCallback) InProgress(allocationId, filePath string, op int, completedBytes int, data []byte) {
+	if ec.bus.HasSubscribers() {
+		o := ec.operation(filePath, op, false)
+		ec.bus.Publish(ec.event(EventProgress, allocationId, filePath, op, completedBytes, o))
+	}
+	if ec.sb != nil {
+		ec.sb.InProgress(allocationId, filePath, op, completedBytes, data)
+	}
+}
+
+func (ec *eventStatusCallback) Error(allocationID string, filePath string, op int, err error) {
+	o := ec.operation(filePath, op, true)
+	if ec.bus.HasSubscribers() {
+		e := ec.event(EventFailed, allocationID, filePath, op, 0, o)
+		e.Err = err
+		ec.bus.Publish(e)
+	}
+	if ec.sb != nil {
+		ec.sb.Error(allocationID, filePath, op, err)
+	}
+}
+
+func (ec *eventStatusCallback) Completed(allocationId, filePath string, filename string, mimetype string, size int, op int) {
+	o := ec.operation(filePath, op, true)
+	if ec.bus.HasSubscribers() {
+		ec.bus.Publish(ec.event(EventCompleted, allocationId, filePath, op, size, o))
+	}
+	if ec.sb != nil {
+		ec.sb.Completed(allocationId, filePath, filename, mimetype, size, op)
+	}
+}
+
+func (ec *eventStatusCallback) RepairCompleted(filesRepaired int) {
+	ec.bus.Publish(Event{
+		Type:          EventRepairCompleted,
+		OperationID:   ec.operationID,
+		Op:            OperationRepair,
+		FilesRepaired: filesRepaired,
+	})
+	if ec.sb != nil {
+		ec.sb.RepairCompleted(filesRepaired)
+	}
+}
+
+func (su *ChunkedUpload) publishBlobberEvent(t EventType, blobberID string, retries int, err error) {
+	publishEvent(func() Event {
+		op := OperationUpload
+		if su.httpMethod == http.MethodPut {
+			op = OperationUpdate
+		}
+		return Event{
+			Type:         t,
+			OperationID:  su.progress.ConnectionID,
+			AllocationID: su.allocationObj.ID,
+			Op:           op,
+			Path:         su.fileMeta.RemotePath,
+			BlobberID:    blobberID,
+			Retries:      retries,
+			Err:          err,
+		}
+	})
+}
+
+func (mo *MultiOperation) publishEvent(t EventType, blobberID string, err error) {
+	publishEvent(func() Event {
+		return Event{
+			Type:         t,
+			OperationID:  mo.connectionID,
+			AllocationID: mo.allocationObj.ID,
+			Op:           OperationMulti,
+			BlobberID:    blobberID,
+			Consensus:    mo.getConsensus(),
+			Err:          err,
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/0chain/gosdk/constants"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingStatusCallback records the calls of a status callback.
+type recordingStatusCallback struct {
+	calls []string
+}
+
+func (r *recordingStatusCallback) Started(allocationId, filePath string, op int, totalBytes int) {
+	r.calls = append(r.calls, fmt.Sprintf("started %s %d", filePath, totalBytes))
+}
+
+func (r *recordingStatusCallback) InProgress(allocationId, filePath string, op int, completedBytes int, data []byte) {
+	r.calls = append(r.calls, fmt.Sprintf("progress %s %d", filePath, completedBytes))
+}
+
+func (r *recordingStatusCallback) Error(allocationID string, filePath string, op int, err error) {
+	r.calls = append(r.calls, fmt.Sprintf("error %s", filePath))
+}
+
+func (r *recordingStatusCallback) Completed(allocationId, filePath string, filename string, mimetype string, size int, op int) {
+	r.calls = append(r.calls, fmt.Sprintf("completed %s %d", filePath, size))
+}
+
+func (r *recordingStatusCallback) RepairCompleted(filesRepaired int) {
+	r.calls = append(r.calls, fmt.Sprintf("repaired %d", filesRepaired))
+}
+
+func TestEventBus(t *testing.T) {
+	bus := NewEventBus()
+	require.False(t, bus.HasSubscribers())
+
+	all, unsubscribeAll := bus.Subscribe(1, nil)
+	failures, unsubscribeFailures := bus.Subscribe(10, func(e Event) bool {
+		return e.Type == EventFailed
+	})
+	require.True(t, bus.HasSubscribers())
+
+	bus.Publish(Event{Type: EventStarted, Path: "/a"})
+	// the buffer of all is full, the event is dropped without blocking
+	bus.Publish(Event{Type: EventFailed, Path: "/a", Err: ErrPauseUpload})
+
+	e := <-all
+	require.Equal(t, EventStarted, e.Type)
+	require.False(t, e.Time.IsZero())
+	e = <-failures
+	require.Equal(t, ErrorClassPaused, e.ErrorClass)
+	require.Len(t, all, 0)
+
+	unsubscribeAll()
+	unsubscribeAll()
+	_, ok := <-all
+	require.False(t, ok)
+	unsubscribeFailures()
+	require.False(t, bus.HasSubscribers())
+}
+
+func TestEventStatusCallback(t *testing.T) {
+	bus := NewEventBus()
+	events, unsubscribe := bus.Subscribe(10, nil)
+	defer unsubscribe()
+
+	rec := &recordingStatusCallback{}
+	sb := newEventStatusCallback(bus, rec, "conn")
+	// wrapping again doesn't publish the events twice
+	sb = newEventStatusCallback(bus, sb, "conn")
+
+	sb.Started("alloc", "/a.txt", OpUpload, 100)
+	sb.InProgress("alloc", "/a.txt", OpUpload, 40, nil)
+	sb.Completed("alloc", "/a.txt", "a.txt", "text/plain", 100, OpUpload)
+	require.Equal(t, []string{"started /a.txt 100", "progress /a.txt 40", "completed /a.txt 100"}, rec.calls)
+
+	require.Len(t, events, 3)
+	started, progress, completed := <-events, <-events, <-events
+	require.Equal(t, EventStarted, started.Type)
+	require.Equal(t, "conn", started.OperationID)
+	require.Equal(t, OperationUpload, started.Op)
+	require.Equal(t, int64(100), started.TotalBytes)
+	require.Equal(t, EventProgress, progress.Type)
+	require.Equal(t, int64(40), progress.Bytes)
+	require.Equal(t, int64(100), progress.TotalBytes)
+	require.Equal(t, EventCompleted, completed.Type)
+	require.Equal(t, "alloc", completed.AllocationID)
+
+	// without an operation id, every path gets its own
+	sb = NewEventStatusCallback(bus, nil)
+	sb.Started("alloc", "/b.txt", OpDownload, 10)
+	sb.Started("alloc", "/c.txt", OpDownload, 10)
+	sb.Error("alloc", "/b.txt", OpDownload, ErrPauseDownload)
+	b, c, failed := <-events, <-events, <-events
+	require.NotEmpty(t, b.OperationID)
+	require.NotEqual(t, b.OperationID, c.OperationID)
+	require.Equal(t, b.OperationID, failed.OperationID)
+	require.Equal(t, OperationDownload, failed.Op)
+	require.Equal(t, ErrorClassPaused, failed.ErrorClass)
+}
+
+func TestClassifyError(t *testing.T) {
+	require.Equal(t, ErrorClass(""), ClassifyError(nil))
+	require.Equal(t, ErrorClassPaused, ClassifyError(ErrPauseUpload))
+	require.Equal(t, ErrorClassCanceled, ClassifyError(fmt.Errorf("upload canceled by user")))
+	require.Equal(t, ErrorClassCanceled, ClassifyError(context.Canceled))
+	require.Equal(t, ErrorClassTimeout, ClassifyError(context.DeadlineExceeded))
+	require.Equal(t, ErrorClassConsensus, ClassifyError(fmt.Errorf("consensus_not_met: Required consensus 4 got 3")))
+	require.Equal(t, ErrorClassPermission, ClassifyError(constants.ErrFileOptionNotPermitted))
+	require.Equal(t, ErrorClassInsufficient, ClassifyError(fmt.Errorf("not_enough_tokens: read pool is empty")))
+	require.Equal(t, ErrorClassOther, ClassifyError(fmt.Errorf("unexpected")))
+}
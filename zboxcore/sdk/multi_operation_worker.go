@@ -79,6 +79,8 @@ func (mo *MultiOperation) createConnectionObj(blobberIdx int) (err error) {
 		latestStatusCode int
 	)
 	blobber := mo.allocationObj.Blobbers[blobberIdx]
+	spanCtx, span := zboxutil.StartSpan(mo.ctx, "create_connection", zboxutil.BlobberSpanAttributes(blobber.ID, blobber.Baseurl)...)
+	defer func() { zboxutil.EndSpan(span, err) }()
 
 	for i := 0; i < 3; i++ {
 		err, shouldContinue = func() (err error, shouldContinue bool) {
@@ -99,7 +101,7 @@ func (mo *MultiOperation) createConnectionObj(blobberIdx int) (err error) {
 			}
 
 			httpreq.Header.Add("Content-Type", formWriter.FormDataContentType())
-			ctx, cncl := context.WithTimeout(mo.ctx, DefaultCreateConnectionTimeOut)
+			ctx, cncl := context.WithTimeout(spanCtx, DefaultCreateConnectionTimeOut)
 			defer cncl()
 			err = zboxutil.HttpDo(ctx, cncl, httpreq, func(r *http.Response, err error) error {
 				resp = r
@@ -293,6 +295,7 @@ func (mo *MultiOperation) Process() error {
 			wg:           wg,
 			timestamp:    timestamp,
 			blobberInd:   pos,
+			ctx:          mo.ctx,
 		}
 
 		commitReq.changes = append(commitReq.changes, mo.changes[pos]...)
@@ -316,6 +319,7 @@ func (mo *MultiOperation) Process() error {
 			} else {
 				errSlice[idx] = errors.New("commit_failed", commitReq.result.ErrorMessage)
 				l.Logger.Error("Commit failed", commitReq.blobber.Baseurl, commitReq.result.ErrorMessage)
+				mo.publishEvent(EventBlobberFailed, commitReq.blobber.ID, errSlice[idx])
 			}
 		} else {
 			l.Logger.Debug("Commit result not set", commitReq.blobber.Baseurl)
@@ -324,15 +328,17 @@ func (mo *MultiOperation) Process() error {
 
 	if !mo.isConsensusOk() {
 		err = zboxutil.MajorError(errSlice)
+		mo.publishEvent(EventFailed, "", err)
 		if mo.getConsensus() != 0 {
 			l.Logger.Info("Rolling back changes on minority blobbers")
-			mo.allocationObj.RollbackWithMask(rollbackMask)
+			mo.allocationObj.rollbackWithMask(context.WithoutCancel(mo.ctx), rollbackMask)
 		}
 		for _, op := range mo.operations {
 			op.Error(mo.allocationObj, mo.getConsensus(), err)
 		}
 		return err
 	} else {
+		mo.publishEvent(EventCommitted, "", nil)
 		for _, op := range mo.operations {
 			op.Completed(mo.allocationObj)
 		}
@@ -178,6 +178,7 @@ func GetDStorageFileReader(alloc *Allocation, ref *ORef, sdo *StreamDownloadOpti
 			numBlocks:         int64(sdo.BlocksPerMarker),
 			validationRootMap: make(map[string]*blobberFile),
 			shouldVerify:      sdo.VerifyDownload,
+			bandwidthLimiters: alloc.downloadBandwidthLimiters(),
 			Consensus: Consensus{
 				RWMutex:         &sync.RWMutex{},
 				fullconsensus:   alloc.fullconsensus,
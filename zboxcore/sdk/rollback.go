@@ -118,7 +118,9 @@ func GetWritemarker(allocID, allocTx, sig, id, baseUrl string) (*LatestPrevWrite
 	return nil, fmt.Errorf("writemarker error response %d", http.StatusTooManyRequests)
 }
 
-func (rb *RollbackBlobber) processRollback(ctx context.Context, tx string) error {
+func (rb *RollbackBlobber) processRollback(ctx context.Context, tx string) (err error) {
+	ctx, span := zboxutil.StartSpan(ctx, "rollback", zboxutil.BlobberSpanAttributes(rb.blobber.ID, rb.blobber.Baseurl)...)
+	defer func() { zboxutil.EndSpan(span, err) }()
 
 	wm := &marker.WriteMarker{}
 	wm.AllocationID = rb.lpm.LatestWM.AllocationID
@@ -147,7 +149,7 @@ func (rb *RollbackBlobber) processRollback(ctx context.Context, tx string) error
 		wm.Size = 0
 	}
 
-	err := wm.Sign()
+	err = wm.Sign()
 	if err != nil {
 		l.Logger.Error("Signing writemarker failed: ", err)
 		return err
@@ -386,7 +388,10 @@ func (a *Allocation) CheckAllocStatus() (AllocStatus, []BlobberStatus, error) {
 // The mask is used to specify which blobbers to rollback.
 //   - mask: 128-bitmask to specify which blobbers to rollback
 func (a *Allocation) RollbackWithMask(mask zboxutil.Uint128) {
+	a.rollbackWithMask(context.TODO(), mask)
+}
 
+func (a *Allocation) rollbackWithMask(ctx context.Context, mask zboxutil.Uint128) {
 	wg := &sync.WaitGroup{}
 	markerChan := make(chan *RollbackBlobber, mask.CountOnes())
 	var pos uint64
@@ -423,7 +428,7 @@ func (a *Allocation) RollbackWithMask(mask zboxutil.Uint128) {
 		wg.Add(1)
 		go func(rb *RollbackBlobber) {
 			defer wg.Done()
-			err := rb.processRollback(context.TODO(), a.Tx)
+			err := rb.processRollback(ctx, a.Tx)
 			if err != nil {
 				rb.commitResult = ErrorCommitResult(err.Error())
 				l.Logger.Error("error during rollback", zap.Error(err))
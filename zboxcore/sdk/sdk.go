@@ -42,6 +42,7 @@ const (
 	OpRepair            int = 2
 	OpUpdate            int = 3
 	opThumbnailDownload int = 4
+	OpDelete            int = 5
 )
 
 type StatusCallback interface {
@@ -1,12 +1,9 @@
 package sdk
 
 import (
-	"crypto/md5"
-	"encoding/hex"
+	"bytes"
 	"encoding/json"
-	"io"
 	"io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
 	"sort"
@@ -14,6 +11,7 @@ import (
 
 	"github.com/0chain/errors"
 	"github.com/0chain/gosdk/core/common"
+	"github.com/0chain/gosdk/core/pathutil"
 	"github.com/0chain/gosdk/core/sys"
 	"github.com/0chain/gosdk/zboxcore/fileref"
 	l "github.com/0chain/gosdk/zboxcore/logger"
@@ -40,6 +38,23 @@ const (
 	LocalDelete = "LocalDelete"
 )
 
+// IgnoreFileName is the name of the file holding the ignore rules of a local sync root.
+const IgnoreFileName = ".zboxignore"
+
+// LoadIgnoreFile loads gitignore-style ignore rules from the given file.
+// A missing file yields empty rules.
+//   - filePath is the path of the ignore file, usually the .zboxignore file of the local root
+func LoadIgnoreFile(filePath string) (*pathutil.IgnoreRules, error) {
+	buf, err := sys.Files.ReadFile(filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return pathutil.NewIgnoreRules()
+		}
+		return nil, errors.Wrap(err, "can't read ignore file.")
+	}
+	return pathutil.ParseIgnoreRules(bytes.NewReader(buf))
+}
+
 // FileInfo file information representation for sync
 type FileInfo struct {
 	Size         int64            `json:"size"`
@@ -60,7 +75,7 @@ type FileDiff struct {
 	Type string `json:"type"`
 }
 
-func (a *Allocation) getRemoteFilesAndDirs(dirList []string, fMap map[string]FileInfo, exclMap map[string]int, remotePath string) ([]string, error) {
+func (a *Allocation) getRemoteFilesAndDirs(dirList []string, fMap map[string]FileInfo, exclMap map[string]int, remotePath string, ignore *pathutil.IgnoreRules) ([]string, error) {
 	childDirList := make([]string, 0)
 	remotePath = strings.TrimRight(remotePath, "/")
 	for _, dir := range dirList {
@@ -69,10 +84,15 @@ func (a *Allocation) getRemoteFilesAndDirs(dirList []string, fMap map[string]Fil
 			return []string{}, err
 		}
 		for _, child := range ref.Children {
-			if _, ok := exclMap[child.Path]; ok {
+			if _, ok := exclMap[child.Path]; ok || skipHiddenManagedPath(remotePath, child.Path) {
 				continue
 			}
 			relativePathFromRemotePath := strings.TrimPrefix(child.Path, remotePath)
+			if ignore.Match(relativePathFromRemotePath, child.Type == fileref.DIRECTORY) {
+				continue
+			}
+			// the Hash of a listed file is its ActualFileHash, the md5 of the
+			// content, which is compared with the local md5 of LocalFileIndex.
 			fMap[relativePathFromRemotePath] = FileInfo{
 				Size:         child.Size,
 				ActualSize:   child.ActualSize,
@@ -92,16 +112,20 @@ func (a *Allocation) getRemoteFilesAndDirs(dirList []string, fMap map[string]Fil
 	return childDirList, nil
 }
 
-// GetRemoteFileMap retrieve the remote file map
+// GetRemoteFileMap retrieve the remote file map, without the trash and the versions
 //   - exclMap is the exclude map, a map of paths to exclude
 //   - remotepath is the remote path to get the file map
 func (a *Allocation) GetRemoteFileMap(exclMap map[string]int, remotepath string) (map[string]FileInfo, error) {
+	return a.getRemoteFileMap(exclMap, remotepath, nil)
+}
+
+func (a *Allocation) getRemoteFileMap(exclMap map[string]int, remotepath string, ignore *pathutil.IgnoreRules) (map[string]FileInfo, error) {
 	// 1. Iteratively get dir and files separately till no more dirs left
 	remoteList := make(map[string]FileInfo)
 	dirs := []string{remotepath}
 	var err error
 	for {
-		dirs, err = a.getRemoteFilesAndDirs(dirs, remoteList, exclMap, remotepath)
+		dirs, err = a.getRemoteFilesAndDirs(dirs, remoteList, exclMap, remotepath, ignore)
 		if err != nil {
 			l.Logger.Error(err.Error())
 			break
@@ -114,20 +138,6 @@ func (a *Allocation) GetRemoteFileMap(exclMap map[string]int, remotepath string)
 	return remoteList, err
 }
 
-func calcFileHash(filePath string) string {
-	fp, err := os.Open(filePath)
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer fp.Close()
-
-	h := md5.New()
-	if _, err := io.Copy(h, fp); err != nil {
-		log.Fatal(err)
-	}
-	return hex.EncodeToString(h.Sum(nil))
-}
-
 func getRemoteExcludeMap(exclPath []string) map[string]int {
 	exclMap := make(map[string]int)
 	for idx, path := range exclPath {
@@ -136,7 +146,7 @@ func getRemoteExcludeMap(exclPath []string) map[string]int {
 	return exclMap
 }
 
-func addLocalFileList(root string, fMap map[string]FileInfo, dirList *[]string, filter map[string]bool, exclMap map[string]int) filepath.WalkFunc {
+func addLocalFileList(root string, fMap map[string]FileInfo, dirList *[]string, filter map[string]bool, exclMap map[string]int, ignore *pathutil.IgnoreRules, idx *LocalFileIndex) filepath.WalkFunc {
 	return func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			l.Logger.Error("Local file list error for path", path, err.Error())
@@ -154,7 +164,7 @@ func addLocalFileList(root string, fMap map[string]FileInfo, dirList *[]string,
 		// to forward slashes. File path in windows contain backslashes.
 		lPath = "/" + strings.ReplaceAll(lPath, "\\", "/")
 		// Exclude
-		if _, ok := exclMap[lPath]; ok {
+		if _, ok := exclMap[lPath]; ok || ignore.Match(lPath, info.IsDir()) {
 			if info.IsDir() {
 				return filepath.SkipDir
 			} else {
@@ -165,20 +175,28 @@ func addLocalFileList(root string, fMap map[string]FileInfo, dirList *[]string,
 		if info.IsDir() {
 			*dirList = append(*dirList, lPath)
 		} else {
-			fMap[lPath] = FileInfo{Size: info.Size(), Hash: calcFileHash(path), Type: fileref.FILE}
+			hash, err := idx.fileHash(lPath, path, info)
+			if err != nil {
+				return errors.Wrap(err, "error hashing local file "+path)
+			}
+			fMap[lPath] = FileInfo{Size: info.Size(), Hash: hash, Type: fileref.FILE}
 		}
 		return nil
 	}
 }
 
-func getLocalFileMap(rootPath string, filters []string, exclMap map[string]int) (map[string]FileInfo, error) {
+func getLocalFileMap(rootPath string, filters []string, exclMap map[string]int, ignore *pathutil.IgnoreRules, idx *LocalFileIndex) (map[string]FileInfo, error) {
+	if idx == nil {
+		idx, _ = LoadLocalFileIndex("")
+	}
+	idx.beginScan()
 	localMap := make(map[string]FileInfo)
 	var dirList []string
 	filterMap := make(map[string]bool)
 	for _, f := range filters {
 		filterMap[f] = true
 	}
-	err := filepath.Walk(rootPath, addLocalFileList(rootPath, localMap, &dirList, filterMap, exclMap))
+	err := filepath.Walk(rootPath, addLocalFileList(rootPath, localMap, &dirList, filterMap, exclMap, ignore, idx))
 	// Add the dirs at the end of the list for dir deletiion after all file deletion
 	for _, d := range dirList {
 		localMap[d] = FileInfo{Type: fileref.DIRECTORY}
@@ -306,13 +324,44 @@ func findDelta(rMap map[string]FileInfo, lMap map[string]FileInfo, prevMap map[s
 	return lFDiff
 }
 
+// AllocationDiffOption customizes GetAllocationDiff.
+type AllocationDiffOption func(o *allocationDiffOptions)
+
+type allocationDiffOptions struct {
+	localIndex *LocalFileIndex
+	ignore     *pathutil.IgnoreRules
+}
+
+// WithLocalFileIndex makes GetAllocationDiff reuse the content hashes cached in the
+// given index for unchanged local files, and saves the updated index afterwards.
+//   - idx: the local file index of the local root path
+func WithLocalFileIndex(idx *LocalFileIndex) AllocationDiffOption {
+	return func(o *allocationDiffOptions) {
+		o.localIndex = idx
+	}
+}
+
+// WithIgnoreRules excludes the local and remote paths matched by the given rules
+// from the diff. Paths are matched relative to the local root and remote path.
+//   - rules: the ignore rules, usually loaded from the .zboxignore file of the local root
+func WithIgnoreRules(rules *pathutil.IgnoreRules) AllocationDiffOption {
+	return func(o *allocationDiffOptions) {
+		o.ignore = rules
+	}
+}
+
 // GetAllocationDiff retrieves the difference between the remote and local filesystem representation of the allocation
 //   - lastSyncCachePath is the path to the last sync cache file, which carries exact state of the remote filesystem
 //   - localRootPath is the local root path of the allocation
 //   - localFileFilters is the list of local file filters
 //   - remoteExcludePath is the list of remote exclude paths
 //   - remotePath is the remote path of the allocation
-func (a *Allocation) GetAllocationDiff(lastSyncCachePath string, localRootPath string, localFileFilters []string, remoteExcludePath []string, remotePath string) ([]FileDiff, error) {
+//   - opts is the list of options to customize the diff
+func (a *Allocation) GetAllocationDiff(lastSyncCachePath string, localRootPath string, localFileFilters []string, remoteExcludePath []string, remotePath string, opts ...AllocationDiffOption) ([]FileDiff, error) {
+	var diffOpts allocationDiffOptions
+	for _, opt := range opts {
+		opt(&diffOpts)
+	}
 	var lFdiff []FileDiff
 	prevRemoteFileMap := make(map[string]FileInfo)
 	// 1. Validate localSycnCachePath
@@ -338,17 +387,28 @@ func (a *Allocation) GetAllocationDiff(lastSyncCachePath string, localRootPath s
 	exclMap := getRemoteExcludeMap(remoteExcludePath)
 
 	// 3. Get flat file list from remote
-	remoteFileMap, err := a.GetRemoteFileMap(exclMap, remotePath)
+	remoteFileMap, err := a.getRemoteFileMap(exclMap, remotePath, diffOpts.ignore)
 	if err != nil {
 		return lFdiff, errors.Wrap(err, "error getting list dir from remote.")
 	}
 
 	// 4. Get flat file list on the local filesystem
 	localRootPath = strings.TrimRight(localRootPath, "/")
-	localFileList, err := getLocalFileMap(localRootPath, localFileFilters, exclMap)
+	localFileList, err := getLocalFileMap(localRootPath, localFileFilters, exclMap, diffOpts.ignore, diffOpts.localIndex)
 	if err != nil {
 		return lFdiff, errors.Wrap(err, "error getting list dir from local.")
 	}
+	// A local copy of the trash or the versions must not be uploaded back in them.
+	for lPath := range localFileList {
+		if skipHiddenManagedPath(remotePath, strings.TrimRight(remotePath, "/")+lPath) {
+			delete(localFileList, lPath)
+		}
+	}
+	if diffOpts.localIndex != nil {
+		if err := diffOpts.localIndex.Save(); err != nil {
+			return lFdiff, err
+		}
+	}
 
 	// 5. Get the file diff with operation
 	lFdiff = findDelta(remoteFileMap, localFileList, prevRemoteFileMap, localRootPath)
@@ -361,6 +421,10 @@ func (a *Allocation) GetAllocationDiff(lastSyncCachePath string, localRootPath s
 //   - pathToSave is the path to save the remote snapshot
 //   - remoteExcludePath is the list of paths to exclude
 func (a *Allocation) SaveRemoteSnapshot(pathToSave string, remoteExcludePath []string) error {
+	return a.saveRemoteSnapshot(pathToSave, remoteExcludePath, "/", nil)
+}
+
+func (a *Allocation) saveRemoteSnapshot(pathToSave string, remoteExcludePath []string, remotePath string, ignore *pathutil.IgnoreRules) error {
 	bIsFileExists := false
 	// Validate path
 	fileInfo, err := sys.Files.Stat(pathToSave)
@@ -373,7 +437,7 @@ func (a *Allocation) SaveRemoteSnapshot(pathToSave string, remoteExcludePath []s
 
 	// Get flat file list from remote
 	exclMap := getRemoteExcludeMap(remoteExcludePath)
-	remoteFileList, err := a.GetRemoteFileMap(exclMap, "/")
+	remoteFileList, err := a.getRemoteFileMap(exclMap, remotePath, ignore)
 	if err != nil {
 		return errors.Wrap(err, "error getting list dir from remote.")
 	}
@@ -0,0 +1,495 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/0chain/errors"
+	"github.com/0chain/gosdk/constants"
+	"github.com/0chain/gosdk/core/pathutil"
+	l "github.com/0chain/gosdk/zboxcore/logger"
+	"go.uber.org/zap"
+)
+
+// ConflictPolicy decides how Sync resolves a file modified both locally and remotely.
+type ConflictPolicy int
+
+const (
+	// ConflictLocalWins overwrites the remote file with the local one.
+	ConflictLocalWins ConflictPolicy = iota
+
+	// ConflictRemoteWins overwrites the local file with the remote one.
+	ConflictRemoteWins
+
+	// ConflictKeepBoth keeps the remote file at its path and uploads the local
+	// file next to it, renamed with SyncOptions.ConflictSuffix.
+	ConflictKeepBoth
+)
+
+const defaultConflictSuffix = ".conflict"
+
+// SyncOptions holds the parameters of an Allocation.Sync run.
+type SyncOptions struct {
+	// LocalRootPath is the local directory mirrored with the allocation.
+	LocalRootPath string
+
+	// RemotePath is the remote directory mirrored with the local root. Defaults to "/".
+	RemotePath string
+
+	// LastSyncCachePath is the snapshot file of the previous sync. It is read to
+	// compute the diff and rewritten once every operation succeeded.
+	LastSyncCachePath string
+
+	// LocalFileFilters is the list of local file names to ignore.
+	LocalFileFilters []string
+
+	// RemoteExcludePath is the list of remote paths to ignore.
+	RemoteExcludePath []string
+
+	// IgnoreRules excludes matching paths from the sync. When nil, the rules are
+	// loaded from the .zboxignore file of the local root, if any.
+	IgnoreRules *pathutil.IgnoreRules
+
+	// LocalIndexPath is the file persisting the local file index, so that only
+	// changed local files are rehashed. The index is kept in memory if empty.
+	LocalIndexPath string
+
+	// ConflictPolicy is the policy used to resolve Conflict operations.
+	ConflictPolicy ConflictPolicy
+
+	// ConflictSuffix is appended to the file name, before the extension, of the
+	// local copy kept by ConflictKeepBoth. Defaults to ".conflict".
+	ConflictSuffix string
+
+	// Workdir is the working directory used by the chunked uploads.
+	Workdir string
+
+	// Encrypt turns on encryption for uploaded files.
+	Encrypt bool
+
+	// StatusCallback receives the per-file results of the sync.
+	StatusCallback StatusCallback
+}
+
+// Sync computes the difference between the local root and the remote path with
+// GetAllocationDiff and applies it: local changes are uploaded, updated or deleted
+// in batched multi-operations, remote changes are downloaded or removed locally,
+// and conflicts are resolved with the configured policy. When every operation
+// succeeds and LastSyncCachePath is set, the post-sync remote snapshot is saved there.
+//   - ctx: the context of the sync, used to stop it between operations.
+//   - opts: the sync options.
+func (a *Allocation) Sync(ctx context.Context, opts SyncOptions) error {
+	if !a.isInitialized() {
+		return notInitialized
+	}
+	if opts.RemotePath == "" {
+		opts.RemotePath = "/"
+	}
+
+	if err := opts.loadIgnoreRules(); err != nil {
+		return err
+	}
+	idx, err := LoadLocalFileIndex(opts.LocalIndexPath)
+	if err != nil {
+		return err
+	}
+	return a.sync(ctx, opts, idx)
+}
+
+func (a *Allocation) sync(ctx context.Context, opts SyncOptions, idx *LocalFileIndex) error {
+	diff, err := a.GetAllocationDiff(opts.LastSyncCachePath, opts.LocalRootPath, opts.LocalFileFilters, opts.RemoteExcludePath, opts.RemotePath,
+		WithLocalFileIndex(idx), WithIgnoreRules(opts.IgnoreRules))
+	if err != nil {
+		return err
+	}
+
+	if err := a.applyAllocationDiff(ctx, diff, opts); err != nil {
+		return err
+	}
+
+	if opts.LastSyncCachePath != "" {
+		return a.saveRemoteSnapshot(opts.LastSyncCachePath, opts.RemoteExcludePath, opts.RemotePath, opts.IgnoreRules)
+	}
+	return nil
+}
+
+// loadIgnoreRules loads the .zboxignore file of the local root when no rules are set.
+func (opts *SyncOptions) loadIgnoreRules() error {
+	if opts.IgnoreRules != nil {
+		return nil
+	}
+	rules, err := LoadIgnoreFile(filepath.Join(opts.LocalRootPath, IgnoreFileName))
+	if err != nil {
+		return err
+	}
+	opts.IgnoreRules = rules
+	return nil
+}
+
+// syncStatusBar forwards every callback of a single file to the sync status
+// callback and tracks the file completion on the wait group.
+type syncStatusBar struct {
+	wg  *sync.WaitGroup
+	sb  StatusCallback
+	mu  sync.Mutex
+	err error
+}
+
+func (s *syncStatusBar) Started(allocationId, filePath string, op int, totalBytes int) {
+	if s.sb != nil {
+		s.sb.Started(allocationId, filePath, op, totalBytes)
+	}
+}
+
+func (s *syncStatusBar) InProgress(allocationId, filePath string, op int, completedBytes int, data []byte) {
+	if s.sb != nil {
+		s.sb.InProgress(allocationId, filePath, op, completedBytes, data)
+	}
+}
+
+func (s *syncStatusBar) Completed(allocationId, filePath string, filename string, mimetype string, size int, op int) {
+	if s.sb != nil {
+		s.sb.Completed(allocationId, filePath, filename, mimetype, size, op)
+	}
+	s.wg.Done()
+}
+
+func (s *syncStatusBar) Error(allocationID string, filePath string, op int, err error) {
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+	if s.sb != nil {
+		s.sb.Error(allocationID, filePath, op, err)
+	}
+	s.wg.Done()
+}
+
+func (s *syncStatusBar) RepairCompleted(filesRepaired int) {}
+
+// syncPlan is a diff resolved into the concrete operations to run.
+type syncPlan struct {
+	uploads      []string // relative paths uploaded as new remote files
+	updates      []string // relative paths overwriting remote files
+	deletes      []string // relative paths deleted from remote
+	downloads    []string // relative paths downloaded from remote
+	localDeletes []string // relative paths deleted locally
+	keepBoth     []string // relative paths whose local copy is renamed before download
+}
+
+func newSyncPlan(diff []FileDiff, policy ConflictPolicy) *syncPlan {
+	plan := &syncPlan{}
+	for _, d := range diff {
+		switch d.Op {
+		case Upload:
+			plan.uploads = append(plan.uploads, d.Path)
+		case Update:
+			plan.updates = append(plan.updates, d.Path)
+		case Delete:
+			plan.deletes = append(plan.deletes, d.Path)
+		case Download:
+			plan.downloads = append(plan.downloads, d.Path)
+		case LocalDelete:
+			plan.localDeletes = append(plan.localDeletes, d.Path)
+		case Conflict:
+			switch policy {
+			case ConflictRemoteWins:
+				plan.downloads = append(plan.downloads, d.Path)
+			case ConflictKeepBoth:
+				plan.keepBoth = append(plan.keepBoth, d.Path)
+				plan.downloads = append(plan.downloads, d.Path)
+			default:
+				plan.updates = append(plan.updates, d.Path)
+			}
+		}
+	}
+	return plan
+}
+
+// conflictCopyPath returns the path of the copy kept for a conflicting file,
+// e.g. "/docs/report.conflict.txt" for "/docs/report.txt".
+func conflictCopyPath(p, suffix string) string {
+	ext := path.Ext(p)
+	return strings.TrimSuffix(p, ext) + suffix + ext
+}
+
+// applyAllocationDiff runs the operations of the given diff. Paths of the diff are
+// relative to both opts.LocalRootPath and opts.RemotePath.
+// Failed operations are reported to the status callback and do not stop the others;
+// an error counting the failures is returned at the end.
+func (a *Allocation) applyAllocationDiff(ctx context.Context, diff []FileDiff, opts SyncOptions) error {
+	if len(diff) == 0 {
+		return nil
+	}
+	if opts.ConflictSuffix == "" {
+		opts.ConflictSuffix = defaultConflictSuffix
+	}
+	localRoot := strings.TrimRight(opts.LocalRootPath, "/")
+	remoteRoot := opts.RemotePath
+	if remoteRoot == "" {
+		remoteRoot = "/"
+	}
+	localPath := func(p string) string {
+		return filepath.Join(localRoot, filepath.FromSlash(p))
+	}
+	remotePath := func(p string) string {
+		return path.Join(remoteRoot, p)
+	}
+
+	plan := newSyncPlan(diff, opts.ConflictPolicy)
+	var failed int
+
+	// Keep the local side of conflicts before the remote version overwrites it.
+	for _, p := range plan.keepBoth {
+		copyPath := conflictCopyPath(p, opts.ConflictSuffix)
+		if err := os.Rename(localPath(p), localPath(copyPath)); err != nil {
+			failed++
+			a.reportSyncError(opts.StatusCallback, remotePath(p), OpUpload, err)
+			continue
+		}
+		plan.uploads = append(plan.uploads, copyPath)
+	}
+
+	for _, p := range plan.localDeletes {
+		if err := os.RemoveAll(localPath(p)); err != nil {
+			failed++
+			a.reportSyncError(opts.StatusCallback, remotePath(p), OpDelete, err)
+			continue
+		}
+		if opts.StatusCallback != nil {
+			opts.StatusCallback.Completed(a.ID, remotePath(p), path.Base(p), "", 0, OpDelete)
+		}
+	}
+
+	var ops []OperationRequest
+	for _, p := range plan.uploads {
+		ops = append(ops, OperationRequest{
+			OperationType: constants.FileOperationInsert,
+			LocalPath:     localPath(p),
+			RemotePath:    remotePath(p),
+		})
+	}
+	for _, p := range plan.updates {
+		ops = append(ops, OperationRequest{
+			OperationType: constants.FileOperationUpdate,
+			LocalPath:     localPath(p),
+			RemotePath:    remotePath(p),
+		})
+	}
+	for _, p := range plan.deletes {
+		ops = append(ops, OperationRequest{
+			OperationType: constants.FileOperationDelete,
+			RemotePath:    remotePath(p),
+		})
+	}
+	failed += a.syncRemoteOperations(ctx, ops, opts)
+
+	failed += a.syncDownloads(ctx, plan.downloads, localPath, remotePath, opts.StatusCallback)
+
+	if failed > 0 {
+		return errors.New("sync_failed", fmt.Sprintf("%d of %d sync operations failed", failed, len(diff)))
+	}
+	return nil
+}
+
+func (a *Allocation) reportSyncError(sb StatusCallback, remotePath string, op int, err error) {
+	l.Logger.Error("sync operation failed", zap.String("path", remotePath), zap.Error(err))
+	if sb != nil {
+		sb.Error(a.ID, remotePath, op, err)
+	}
+}
+
+// syncRemoteOperations runs upload, update and delete operations in batches of
+// MultiOpBatchSize and returns the number of failed operations.
+func (a *Allocation) syncRemoteOperations(ctx context.Context, ops []OperationRequest, opts SyncOptions) int {
+	var failed int
+	for start := 0; start < len(ops); start += MultiOpBatchSize {
+		end := start + MultiOpBatchSize
+		if end > len(ops) {
+			end = len(ops)
+		}
+		batch := ops[start:end]
+		if contextCanceled(ctx) {
+			for _, op := range batch {
+				a.reportSyncError(opts.StatusCallback, op.RemotePath, syncOpCode(op.OperationType), ctx.Err())
+			}
+			failed += len(batch)
+			continue
+		}
+
+		var (
+			files     []*os.File
+			prepared  []OperationRequest
+			prepFails int
+		)
+		for _, op := range batch {
+			if op.OperationType == constants.FileOperationDelete {
+				prepared = append(prepared, op)
+				continue
+			}
+			f, req, err := openUploadOperation(op, opts.Workdir,
+				WithEncrypt(opts.Encrypt),
+				WithStatusCallback(opts.StatusCallback),
+			)
+			if err != nil {
+				prepFails++
+				a.reportSyncError(opts.StatusCallback, op.RemotePath, syncOpCode(op.OperationType), err)
+				continue
+			}
+			files = append(files, f)
+			prepared = append(prepared, req)
+		}
+
+		err := syncMultiOperation(a, prepared)
+		for _, f := range files {
+			f.Close() //nolint: errcheck
+		}
+		failed += prepFails
+		if err != nil {
+			failed += len(prepared)
+			for _, op := range prepared {
+				// uploads report their own errors through the chunked upload callback
+				if op.OperationType == constants.FileOperationDelete {
+					a.reportSyncError(opts.StatusCallback, op.RemotePath, OpDelete, err)
+				}
+			}
+			continue
+		}
+		if opts.StatusCallback != nil {
+			for _, op := range prepared {
+				if op.OperationType == constants.FileOperationDelete {
+					opts.StatusCallback.Completed(a.ID, op.RemotePath, path.Base(op.RemotePath), "", 0, OpDelete)
+				}
+			}
+		}
+	}
+	return failed
+}
+
+func syncOpCode(operationType string) int {
+	switch operationType {
+	case constants.FileOperationUpdate:
+		return OpUpdate
+	case constants.FileOperationDelete:
+		return OpDelete
+	default:
+		return OpUpload
+	}
+}
+
+// syncDownloads downloads the given remote files over their local counterparts in
+// batches of BatchSize and returns the number of failed downloads. Every file is
+// downloaded into a temporary file of its directory, which replaces the local file
+// only once the download completed, so a failed download keeps the local content.
+func (a *Allocation) syncDownloads(ctx context.Context, paths []string, localPath, remotePath func(string) string, sb StatusCallback) int {
+	var failed int
+	for start := 0; start < len(paths); start += BatchSize {
+		end := start + BatchSize
+		if end > len(paths) {
+			end = len(paths)
+		}
+		batch := paths[start:end]
+		if contextCanceled(ctx) {
+			for _, p := range batch {
+				a.reportSyncError(sb, remotePath(p), OpDownload, ctx.Err())
+			}
+			failed += len(batch)
+			continue
+		}
+
+		var (
+			wg        sync.WaitGroup
+			lastQueue = -1
+		)
+		downloads := make([]*syncDownload, len(batch))
+		for i, p := range batch {
+			d, err := newSyncDownload(localPath(p), remotePath(p))
+			if err != nil {
+				failed++
+				a.reportSyncError(sb, remotePath(p), OpDownload, err)
+				continue
+			}
+			downloads[i] = d
+			lastQueue = i
+		}
+		var queued []*syncDownload
+		for i, p := range batch {
+			d := downloads[i]
+			if d == nil {
+				continue
+			}
+			d.bar = &syncStatusBar{wg: &wg, sb: sb}
+			wg.Add(1)
+			fh := d.file
+			err := syncDownloadFile(a, fh, remotePath(p), false, d.bar, i == lastQueue, WithFileCallback(func() {
+				fh.Close() //nolint: errcheck
+			}))
+			if err != nil {
+				wg.Done()
+				d.discard()
+				failed++
+				a.reportSyncError(sb, remotePath(p), OpDownload, err)
+				continue
+			}
+			queued = append(queued, d)
+		}
+		wg.Wait()
+		for _, d := range queued {
+			if d.bar.err != nil {
+				d.discard()
+				failed++
+				continue
+			}
+			if err := d.commit(); err != nil {
+				failed++
+				a.reportSyncError(sb, d.remotePath, OpDownload, err)
+			}
+		}
+	}
+	return failed
+}
+
+// syncDownloadFile and syncMultiOperation run the downloads and the remote
+// operations of a sync, they're replaced in tests.
+var (
+	syncDownloadFile   = (*Allocation).DownloadFileToFileHandler
+	syncMultiOperation = (*Allocation).DoMultiOperation
+)
+
+// syncDownload is a download into a temporary file replacing the target on success.
+type syncDownload struct {
+	target     string
+	remotePath string
+	file       *os.File
+	bar        *syncStatusBar
+}
+
+func newSyncDownload(target, remotePath string) (*syncDownload, error) {
+	dir := filepath.Dir(target)
+	if err := os.MkdirAll(dir, 0744); err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp(dir, "."+filepath.Base(target)+".*.zcnsync")
+	if err != nil {
+		return nil, err
+	}
+	return &syncDownload{target: target, remotePath: remotePath, file: f}, nil
+}
+
+func (d *syncDownload) discard() {
+	d.file.Close()           //nolint: errcheck
+	os.Remove(d.file.Name()) //nolint: errcheck
+}
+
+func (d *syncDownload) commit() error {
+	d.file.Close() //nolint: errcheck
+	if err := os.Rename(d.file.Name(), d.target); err != nil {
+		os.Remove(d.file.Name()) //nolint: errcheck
+		return err
+	}
+	return nil
+}
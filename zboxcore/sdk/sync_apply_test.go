@@ -0,0 +1,144 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0chain/gosdk/constants"
+	"github.com/0chain/gosdk/core/sys"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSyncPlan(t *testing.T) {
+	diff := []FileDiff{
+		{Op: Upload, Path: "/new.txt"},
+		{Op: Update, Path: "/changed.txt"},
+		{Op: Delete, Path: "/removed"},
+		{Op: Download, Path: "/remote.txt"},
+		{Op: LocalDelete, Path: "/gone.txt"},
+		{Op: Conflict, Path: "/both.txt"},
+	}
+
+	tests := []struct {
+		name          string
+		policy        ConflictPolicy
+		wantUpdates   []string
+		wantDownloads []string
+		wantKeepBoth  []string
+	}{
+		{
+			name:          "local wins",
+			policy:        ConflictLocalWins,
+			wantUpdates:   []string{"/changed.txt", "/both.txt"},
+			wantDownloads: []string{"/remote.txt"},
+		},
+		{
+			name:          "remote wins",
+			policy:        ConflictRemoteWins,
+			wantUpdates:   []string{"/changed.txt"},
+			wantDownloads: []string{"/remote.txt", "/both.txt"},
+		},
+		{
+			name:          "keep both",
+			policy:        ConflictKeepBoth,
+			wantUpdates:   []string{"/changed.txt"},
+			wantDownloads: []string{"/remote.txt", "/both.txt"},
+			wantKeepBoth:  []string{"/both.txt"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := newSyncPlan(diff, tt.policy)
+			require.Equal(t, []string{"/new.txt"}, plan.uploads)
+			require.Equal(t, []string{"/removed"}, plan.deletes)
+			require.Equal(t, []string{"/gone.txt"}, plan.localDeletes)
+			require.Equal(t, tt.wantUpdates, plan.updates)
+			require.Equal(t, tt.wantDownloads, plan.downloads)
+			require.Equal(t, tt.wantKeepBoth, plan.keepBoth)
+		})
+	}
+}
+
+func TestConflictCopyPath(t *testing.T) {
+	require.Equal(t, "/docs/report.conflict.txt", conflictCopyPath("/docs/report.txt", ".conflict"))
+	require.Equal(t, "/docs/Makefile_mine", conflictCopyPath("/docs/Makefile", "_mine"))
+}
+
+// fakeSyncRemote replaces the downloads and the remote operations of the sync.
+func fakeSyncRemote(t *testing.T, failDownloads map[string]bool) *[]OperationRequest {
+	var ops []OperationRequest
+	prevDownload, prevMultiOp := syncDownloadFile, syncMultiOperation
+	t.Cleanup(func() { syncDownloadFile, syncMultiOperation = prevDownload, prevMultiOp })
+
+	syncDownloadFile = func(a *Allocation, fh sys.File, remotePath string, _ bool, status StatusCallback, _ bool, opts ...DownloadRequestOption) error {
+		req := &DownloadRequest{}
+		for _, opt := range opts {
+			opt(req)
+		}
+		if failDownloads[remotePath] {
+			_, err := fh.Write([]byte("partial"))
+			require.NoError(t, err)
+			status.Error(a.ID, remotePath, OpDownload, errors.New("download failed"))
+		} else {
+			_, err := fh.Write([]byte("remote " + remotePath))
+			require.NoError(t, err)
+			status.Completed(a.ID, remotePath, filepath.Base(remotePath), "", 0, OpDownload)
+		}
+		req.fileCallback()
+		return nil
+	}
+	syncMultiOperation = func(a *Allocation, operations []OperationRequest, _ ...MultiOperationOption) error {
+		ops = append(ops, operations...)
+		return nil
+	}
+	return &ops
+}
+
+func TestApplyAllocationDiff(t *testing.T) {
+	root := t.TempDir()
+	write := func(p, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(root, p)), 0744))
+		require.NoError(t, os.WriteFile(filepath.Join(root, p), []byte(content), 0644))
+	}
+	read := func(p string) string {
+		b, err := os.ReadFile(filepath.Join(root, p))
+		require.NoError(t, err)
+		return string(b)
+	}
+	write("both.txt", "local both")
+	write("gone.txt", "local gone")
+	write("failed.txt", "local failed")
+
+	ops := fakeSyncRemote(t, map[string]bool{"/remote/failed.txt": true})
+	a := &Allocation{ID: "alloc"}
+	err := a.applyAllocationDiff(context.Background(), []FileDiff{
+		{Op: Download, Path: "/new/dir/a.txt"},
+		{Op: Download, Path: "/failed.txt"},
+		{Op: Conflict, Path: "/both.txt"},
+		{Op: LocalDelete, Path: "/gone.txt"},
+	}, SyncOptions{LocalRootPath: root, RemotePath: "/remote", ConflictPolicy: ConflictKeepBoth})
+	require.EqualError(t, err, "sync_failed: 1 of 4 sync operations failed")
+
+	// a remote file in a new directory is downloaded
+	require.Equal(t, "remote /remote/new/dir/a.txt", read("new/dir/a.txt"))
+
+	// a failed download keeps the local content and leaves no temporary file
+	require.Equal(t, "local failed", read("failed.txt"))
+
+	// keep both uploads the local copy and downloads the remote file
+	require.Equal(t, "local both", read("both.conflict.txt"))
+	require.Equal(t, "remote /remote/both.txt", read("both.txt"))
+	require.Len(t, *ops, 1)
+	require.Equal(t, constants.FileOperationInsert, (*ops)[0].OperationType)
+	require.Equal(t, "/remote/both.conflict.txt", (*ops)[0].RemotePath)
+
+	_, err = os.Stat(filepath.Join(root, "gone.txt"))
+	require.True(t, os.IsNotExist(err))
+
+	entries, err := filepath.Glob(filepath.Join(root, "*.zcnsync"))
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
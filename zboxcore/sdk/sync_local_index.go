@@ -0,0 +1,144 @@
+package sdk
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/0chain/errors"
+	"github.com/0chain/gosdk/core/sys"
+)
+
+// LocalIndexEntry is the cached state of a local file used by sync.
+type LocalIndexEntry struct {
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mtime"`
+	Inode   uint64 `json:"inode,omitempty"`
+	// Hash is the md5 of the file content, the scheme of the remote ActualFileHash,
+	// which ListDir returns as the Hash of the files compared by findDelta.
+	Hash string `json:"hash"`
+}
+
+// LocalFileIndex caches the content hash of the local files of a sync root,
+// keyed by their allocation-style relative path. A file is rehashed only when
+// its size, modification time or inode changed since it was indexed.
+type LocalFileIndex struct {
+	mu        sync.Mutex
+	indexPath string
+	entries   map[string]LocalIndexEntry
+	seen      map[string]bool
+}
+
+// LoadLocalFileIndex loads the local file index persisted at indexPath.
+// A missing index file yields an empty index that is created on Save.
+//   - indexPath: path of the index file. An empty path keeps the index in memory only.
+func LoadLocalFileIndex(indexPath string) (*LocalFileIndex, error) {
+	idx := &LocalFileIndex{
+		indexPath: indexPath,
+		entries:   make(map[string]LocalIndexEntry),
+	}
+	if indexPath == "" {
+		return idx, nil
+	}
+	buf, err := sys.Files.ReadFile(indexPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return idx, nil
+		}
+		return nil, errors.Wrap(err, "can't read local index file.")
+	}
+	if err := json.Unmarshal(buf, &idx.entries); err != nil {
+		return nil, errors.Wrap(err, "invalid local index content.")
+	}
+	return idx, nil
+}
+
+// Save persists the index, dropping the entries of files that were not seen
+// by the last scan of the local root.
+func (idx *LocalFileIndex) Save() error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.seen != nil {
+		for p := range idx.entries {
+			if !idx.seen[p] {
+				delete(idx.entries, p)
+			}
+		}
+		idx.seen = nil
+	}
+	if idx.indexPath == "" {
+		return nil
+	}
+	buf, err := json.Marshal(idx.entries)
+	if err != nil {
+		return errors.Wrap(err, "failed to convert JSON.")
+	}
+	if err := sys.Files.WriteFile(idx.indexPath, buf, 0644); err != nil {
+		return errors.Wrap(err, "error saving local index.")
+	}
+	return nil
+}
+
+// Get returns the indexed entry of the given relative path.
+func (idx *LocalFileIndex) Get(relPath string) (LocalIndexEntry, bool) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	e, ok := idx.entries[relPath]
+	return e, ok
+}
+
+// beginScan starts tracking the files seen during a walk of the local root.
+func (idx *LocalFileIndex) beginScan() {
+	idx.mu.Lock()
+	idx.seen = make(map[string]bool)
+	idx.mu.Unlock()
+}
+
+// fileHash returns the content hash of the file, reusing the indexed hash when
+// the file is unchanged.
+func (idx *LocalFileIndex) fileHash(relPath, absPath string, info os.FileInfo) (string, error) {
+	entry := LocalIndexEntry{
+		Size:    info.Size(),
+		ModTime: info.ModTime().UnixNano(),
+		Inode:   fileInode(info),
+	}
+
+	idx.mu.Lock()
+	if idx.seen != nil {
+		idx.seen[relPath] = true
+	}
+	cached, ok := idx.entries[relPath]
+	idx.mu.Unlock()
+
+	if ok && cached.Size == entry.Size && cached.ModTime == entry.ModTime && cached.Inode == entry.Inode {
+		return cached.Hash, nil
+	}
+
+	hash, err := calcFileHash(absPath)
+	if err != nil {
+		return "", err
+	}
+	entry.Hash = hash
+
+	idx.mu.Lock()
+	idx.entries[relPath] = entry
+	idx.mu.Unlock()
+	return hash, nil
+}
+
+func calcFileHash(filePath string) (string, error) {
+	fp, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer fp.Close()
+
+	h := md5.New()
+	if _, err := io.Copy(h, fp); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,12 @@
+//go:build windows
+// +build windows
+
+package sdk
+
+import "os"
+
+// fileInode is not available from os.FileInfo on windows, size and
+// modification time alone decide whether a file is rehashed.
+func fileInode(_ os.FileInfo) uint64 {
+	return 0
+}
@@ -0,0 +1,58 @@
+package sdk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalFileIndex(t *testing.T) {
+	dir := t.TempDir()
+	indexPath := filepath.Join(dir, "index.json")
+	root := filepath.Join(dir, "root")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.txt"), []byte("hello"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "sub", "b.txt"), []byte("world"), 0644))
+
+	idx, err := LoadLocalFileIndex(indexPath)
+	require.NoError(t, err)
+	fMap, err := getLocalFileMap(root, nil, map[string]int{}, nil, idx)
+	require.NoError(t, err)
+	require.NoError(t, idx.Save())
+	require.Equal(t, "5d41402abc4b2a76b9719d911017c592", fMap["/a.txt"].Hash)
+	require.Equal(t, "7d793037a0760186574b0282f2f435e7", fMap["/sub/b.txt"].Hash)
+
+	// a stale hash is reused as long as the file metadata is unchanged
+	idx, err = LoadLocalFileIndex(indexPath)
+	require.NoError(t, err)
+	entry, ok := idx.Get("/a.txt")
+	require.True(t, ok)
+	entry.Hash = "cached"
+	idx.entries["/a.txt"] = entry
+	fMap, err = getLocalFileMap(root, nil, map[string]int{}, nil, idx)
+	require.NoError(t, err)
+	require.Equal(t, "cached", fMap["/a.txt"].Hash)
+
+	// a modified file is rehashed and removed files are dropped on save
+	modTime := time.Now().Add(time.Minute)
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.txt"), []byte("hello"), 0644))
+	require.NoError(t, os.Chtimes(filepath.Join(root, "a.txt"), modTime, modTime))
+	require.NoError(t, os.Remove(filepath.Join(root, "sub", "b.txt")))
+	fMap, err = getLocalFileMap(root, nil, map[string]int{}, nil, idx)
+	require.NoError(t, err)
+	require.NoError(t, idx.Save())
+	require.Equal(t, "5d41402abc4b2a76b9719d911017c592", fMap["/a.txt"].Hash)
+
+	idx, err = LoadLocalFileIndex(indexPath)
+	require.NoError(t, err)
+	_, ok = idx.Get("/sub/b.txt")
+	require.False(t, ok)
+}
+
+func TestCalcFileHash_MissingFile(t *testing.T) {
+	_, err := calcFileHash(filepath.Join(t.TempDir(), "missing"))
+	require.Error(t, err)
+}
@@ -0,0 +1,584 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/0chain/errors"
+	"github.com/0chain/gosdk/core/common"
+	"github.com/0chain/gosdk/core/sys"
+	"github.com/0chain/gosdk/zboxcore/fileref"
+	l "github.com/0chain/gosdk/zboxcore/logger"
+	"github.com/0chain/gosdk/zboxcore/zboxutil"
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultWatchDebounce           = 2 * time.Second
+	defaultWatchLocalPollInterval  = 30 * time.Second
+	defaultWatchRemotePollInterval = time.Minute
+
+	// remotePullSlack is subtracted from the last pull time, in seconds, so that
+	// refs committed while the previous pull was running are not missed.
+	remotePullSlack = 60
+)
+
+// WatchSyncOptions holds the parameters of an Allocation.WatchAndSync run.
+type WatchSyncOptions struct {
+	SyncOptions
+
+	// Debounce is the quiet period after the last local change before the
+	// pending changes are synced. Defaults to 2 seconds.
+	Debounce time.Duration
+
+	// LocalPollInterval is the interval of the local scans used when file system
+	// notifications are not available. Defaults to 30 seconds.
+	LocalPollInterval time.Duration
+
+	// RemotePollInterval is the interval of the remote pulls through
+	// GetRecentlyAddedRefs. Defaults to 1 minute.
+	RemotePollInterval time.Duration
+
+	// ForcePolling disables file system notifications and always scans the local root.
+	ForcePolling bool
+}
+
+// WatchAndSync keeps the local root and the remote path mirrored until the
+// context is canceled. It runs a full Sync first, then watches the local root for
+// changes, debounces them and maps them onto Upload, Update and Delete operations,
+// while remote additions are periodically pulled with GetRecentlyAddedRefs.
+// Local changes are detected with file system notifications, or by polling the
+// local root when notifications are not available on the platform.
+// Returns the error of the initial sync, or the context error once canceled.
+//   - ctx: the context of the watch, cancel it to stop watching.
+//   - opts: the watch options.
+func (a *Allocation) WatchAndSync(ctx context.Context, opts WatchSyncOptions) error {
+	if !a.isInitialized() {
+		return notInitialized
+	}
+	if opts.RemotePath == "" {
+		opts.RemotePath = "/"
+	}
+	if opts.Debounce <= 0 {
+		opts.Debounce = defaultWatchDebounce
+	}
+	if opts.LocalPollInterval <= 0 {
+		opts.LocalPollInterval = defaultWatchLocalPollInterval
+	}
+	if opts.RemotePollInterval <= 0 {
+		opts.RemotePollInterval = defaultWatchRemotePollInterval
+	}
+
+	if err := opts.loadIgnoreRules(); err != nil {
+		return err
+	}
+	idx, err := LoadLocalFileIndex(opts.LocalIndexPath)
+	if err != nil {
+		return err
+	}
+	if err := a.sync(ctx, opts.SyncOptions, idx); err != nil {
+		return err
+	}
+
+	w := &syncWatcher{
+		alloc:     a,
+		opts:      opts,
+		idx:       idx,
+		localRoot: strings.TrimRight(opts.LocalRootPath, "/"),
+		filter:    make(map[string]bool),
+		exclMap:   getRemoteExcludeMap(opts.RemoteExcludePath),
+		pending:   make(map[string]bool),
+	}
+	w.listRemote = func(ctx context.Context) <-chan ORef {
+		return a.ListObjects(ctx, opts.RemotePath, "", "", "", "", fileref.REGULAR, 0, getRefPageLimit,
+			WithListObjectsIgnoreRules(opts.IgnoreRules))
+	}
+	w.recentRefs = func(page int, from int64) (*RecentlyAddedRefResult, error) {
+		return a.GetRecentlyAddedRefs(page, from, getRefPageLimit)
+	}
+	for _, f := range opts.LocalFileFilters {
+		w.filter[f] = true
+	}
+	return w.run(ctx)
+}
+
+// localWatcher delivers the file system notifications of watched directories.
+type localWatcher interface {
+	Add(name string) error
+	EventsChan() <-chan fsnotify.Event
+	ErrorsChan() <-chan error
+	Close() error
+}
+
+// syncWatcher holds the state of a WatchAndSync run. All its fields are owned by
+// the goroutine of run.
+type syncWatcher struct {
+	alloc     *Allocation
+	opts      WatchSyncOptions
+	idx       *LocalFileIndex
+	localRoot string
+	filter    map[string]bool
+	exclMap   map[string]int
+
+	// pending is the set of relative paths changed locally since the last flush.
+	pending map[string]bool
+	// remote is the last known remote state, keyed by relative path. The Hash of
+	// the files is their ActualFileHash, the md5 of the content.
+	remote map[string]FileInfo
+	// scan is the last local scan used by the polling mode.
+	scan map[string]LocalIndexEntry
+	// lastRemotePull is the unix time of the last successful remote pull.
+	lastRemotePull int64
+
+	// listRemote and recentRefs query the refs of the allocation.
+	listRemote func(ctx context.Context) <-chan ORef
+	recentRefs func(page int, from int64) (*RecentlyAddedRefResult, error)
+}
+
+func (w *syncWatcher) run(ctx context.Context) error {
+	if err := w.refreshRemote(ctx); err != nil {
+		return err
+	}
+	w.lastRemotePull = time.Now().Unix()
+
+	var (
+		events  <-chan fsnotify.Event
+		errs    <-chan error
+		pollC   <-chan time.Time
+		watcher localWatcher
+		err     error
+	)
+	if !w.opts.ForcePolling {
+		watcher, err = newLocalWatcher()
+		if err != nil {
+			l.Logger.Info("file system notifications not available, polling local changes", zap.Error(err))
+		}
+	}
+	if watcher != nil {
+		defer watcher.Close()
+		if err := w.watchDir(watcher, w.localRoot); err != nil {
+			return err
+		}
+		events, errs = watcher.EventsChan(), watcher.ErrorsChan()
+	} else {
+		w.scan = w.scanLocal()
+		pollTicker := time.NewTicker(w.opts.LocalPollInterval)
+		defer pollTicker.Stop()
+		pollC = pollTicker.C
+	}
+
+	remoteTicker := time.NewTicker(w.opts.RemotePollInterval)
+	defer remoteTicker.Stop()
+
+	debounce := time.NewTimer(w.opts.Debounce)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case ev, ok := <-events:
+			if !ok {
+				return errors.New("sync_watch_closed", "file system watcher closed")
+			}
+			w.handleEvent(watcher, ev)
+			debounce.Reset(w.opts.Debounce)
+
+		case err, ok := <-errs:
+			if !ok {
+				return errors.New("sync_watch_closed", "file system watcher closed")
+			}
+			// Events may have been dropped, fall back to a full sync.
+			l.Logger.Error("sync watcher error", zap.Error(err))
+			if err := w.resync(ctx); err != nil {
+				l.Logger.Error("sync watcher resync failed", zap.Error(err))
+			}
+
+		case <-pollC:
+			current := w.scanLocal()
+			for p, e := range current {
+				if prev, ok := w.scan[p]; !ok || prev != e {
+					w.pending[p] = true
+				}
+			}
+			for p := range w.scan {
+				if _, ok := current[p]; !ok {
+					w.pending[p] = true
+				}
+			}
+			w.scan = current
+			w.flush(ctx)
+
+		case <-debounce.C:
+			w.flush(ctx)
+
+		case <-remoteTicker.C:
+			w.pullRemote(ctx)
+		}
+	}
+}
+
+// watchDir adds the directory and all its subdirectories to the watcher.
+func (w *syncWatcher) watchDir(watcher localWatcher, root string) error {
+	return filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if rel, ok := w.relPath(p); ok && w.isIgnored(rel, true) {
+			return filepath.SkipDir
+		}
+		return watcher.Add(p)
+	})
+}
+
+func (w *syncWatcher) handleEvent(watcher localWatcher, ev fsnotify.Event) {
+	rel, ok := w.relPath(ev.Name)
+	if !ok || rel == "/" || w.isStateFile(ev.Name) {
+		return
+	}
+	info, err := os.Stat(ev.Name)
+	isDir := err == nil && info.IsDir()
+	if err != nil {
+		// removed or renamed away, rely on the last known remote type
+		isDir = w.remote[rel].Type == fileref.DIRECTORY
+	}
+	if w.isIgnored(rel, isDir) {
+		return
+	}
+	w.pending[rel] = true
+	if !ev.Has(fsnotify.Create) || !isDir {
+		return
+	}
+	// Files may be written to a new directory before it is watched.
+	if err := w.watchDir(watcher, ev.Name); err != nil {
+		l.Logger.Error("sync watcher failed to watch directory", zap.String("path", ev.Name), zap.Error(err))
+	}
+	filepath.Walk(ev.Name, func(p string, info os.FileInfo, err error) error { //nolint: errcheck
+		if err == nil && !info.IsDir() {
+			if rel, ok := w.relPath(p); ok && !w.isIgnored(rel, false) {
+				w.pending[rel] = true
+			}
+		}
+		return nil
+	})
+}
+
+// flush turns the pending local changes into sync operations and applies them.
+func (w *syncWatcher) flush(ctx context.Context) {
+	if len(w.pending) == 0 {
+		return
+	}
+	paths := make([]string, 0, len(w.pending))
+	for p := range w.pending {
+		paths = append(paths, p)
+	}
+	w.pending = make(map[string]bool)
+	sort.Strings(paths)
+
+	var (
+		diff     []FileDiff
+		uploaded = make(map[string]FileInfo)
+	)
+	for _, p := range paths {
+		abs := filepath.Join(w.localRoot, filepath.FromSlash(p))
+		info, err := os.Stat(abs)
+		switch {
+		case err == nil && info.IsDir():
+			continue
+		case err == nil:
+			hash, err := w.idx.fileHash(p, abs, info)
+			if err != nil {
+				l.Logger.Error("sync watcher failed to hash file", zap.String("path", abs), zap.Error(err))
+				continue
+			}
+			r, ok := w.remote[p]
+			if ok && r.Hash == hash {
+				continue
+			}
+			op := Upload
+			if ok {
+				op = Update
+			}
+			uploaded[p] = w.localFileInfo(p, abs, info, hash, r)
+			diff = append(diff, FileDiff{Op: op, Path: p, Type: fileref.FILE})
+		case errors.Is(err, os.ErrNotExist):
+			r, ok := w.remote[p]
+			if !ok || isParentFolderExists(diff, path.Dir(p)) {
+				continue
+			}
+			diff = append(diff, FileDiff{Op: Delete, Path: p, Type: r.Type})
+		}
+	}
+	if len(diff) == 0 {
+		return
+	}
+
+	if err := w.alloc.applyAllocationDiff(ctx, diff, w.opts.SyncOptions); err != nil {
+		l.Logger.Error("sync watcher failed to apply local changes", zap.Error(err))
+		if err := w.refreshRemote(ctx); err != nil {
+			l.Logger.Error("sync watcher failed to refresh remote state", zap.Error(err))
+		}
+		return
+	}
+	for _, d := range diff {
+		if d.Op == Delete {
+			w.forgetRemote(d.Path)
+			continue
+		}
+		w.remote[d.Path] = uploaded[d.Path]
+	}
+	w.saveState()
+}
+
+// pullRemote downloads the files added or updated remotely since the last pull.
+func (w *syncWatcher) pullRemote(ctx context.Context) {
+	from := w.lastRemotePull - remotePullSlack
+	now := time.Now().Unix()
+
+	var diff []FileDiff
+	seen := make(map[string]bool)
+	for page := 1; ; page++ {
+		res, err := w.recentRefs(page, from)
+		if err != nil {
+			l.Logger.Error("sync watcher failed to get recently added refs", zap.Error(err))
+			return
+		}
+		for _, ref := range res.Refs {
+			if ref.Type != fileref.FILE {
+				continue
+			}
+			rel, ok := w.remoteRelPath(ref.Path)
+			if !ok || seen[rel] || w.isIgnored(rel, false) {
+				continue
+			}
+			seen[rel] = true
+			known, isKnown := w.remote[rel]
+			if isKnown && known.Hash == ref.ActualFileHash {
+				continue
+			}
+			info := remoteFileInfo(ref)
+
+			op := Download
+			abs := filepath.Join(w.localRoot, filepath.FromSlash(rel))
+			if fi, err := os.Stat(abs); err == nil && !fi.IsDir() {
+				hash, err := w.idx.fileHash(rel, abs, fi)
+				if err != nil {
+					l.Logger.Error("sync watcher failed to hash file", zap.String("path", abs), zap.Error(err))
+					continue
+				}
+				if hash == ref.ActualFileHash {
+					w.remote[rel] = info
+					continue
+				}
+				// The local file diverged from the last known remote state as well.
+				if !isKnown || known.Hash != hash || w.pending[rel] {
+					op = Conflict
+					delete(w.pending, rel)
+				}
+			}
+			w.remote[rel] = info
+			diff = append(diff, FileDiff{Op: op, Path: rel, Type: fileref.FILE})
+		}
+		if len(res.Refs) < getRefPageLimit {
+			break
+		}
+	}
+	w.lastRemotePull = now
+	if len(diff) == 0 {
+		return
+	}
+
+	if err := w.alloc.applyAllocationDiff(ctx, diff, w.opts.SyncOptions); err != nil {
+		l.Logger.Error("sync watcher failed to apply remote changes", zap.Error(err))
+		if err := w.refreshRemote(ctx); err != nil {
+			l.Logger.Error("sync watcher failed to refresh remote state", zap.Error(err))
+		}
+		return
+	}
+	w.saveState()
+}
+
+// resync runs a full sync and reloads the remote state.
+func (w *syncWatcher) resync(ctx context.Context) error {
+	w.pending = make(map[string]bool)
+	if err := w.alloc.sync(ctx, w.opts.SyncOptions, w.idx); err != nil {
+		return err
+	}
+	if w.scan != nil {
+		w.scan = w.scanLocal()
+	}
+	return w.refreshRemote(ctx)
+}
+
+// refreshRemote reloads the remote state from the refs of the remote path.
+func (w *syncWatcher) refreshRemote(ctx context.Context) error {
+	remote := make(map[string]FileInfo)
+	for ref := range w.listRemote(ctx) {
+		if ref.Err != nil {
+			return ref.Err
+		}
+		rel, ok := w.remoteRelPath(ref.Path)
+		if !ok || rel == "/" || w.isIgnored(rel, ref.Type == fileref.DIRECTORY) {
+			continue
+		}
+		remote[rel] = remoteFileInfo(ref)
+	}
+	w.remote = remote
+	return nil
+}
+
+// remoteFileInfo returns the remote state of a ref, with its ActualFileHash as Hash.
+func remoteFileInfo(ref ORef) FileInfo {
+	return FileInfo{
+		Size:         ref.Size,
+		ActualSize:   ref.ActualFileSize,
+		Hash:         ref.ActualFileHash,
+		MimeType:     ref.MimeType,
+		Type:         ref.Type,
+		EncryptedKey: ref.EncryptedKey,
+		LookupHash:   ref.LookupHash,
+		CreatedAt:    ref.CreatedAt,
+		UpdatedAt:    ref.UpdatedAt,
+	}
+}
+
+// localFileInfo returns the remote state of a local file once uploaded.
+func (w *syncWatcher) localFileInfo(rel, abs string, info os.FileInfo, hash string, prev FileInfo) FileInfo {
+	now := common.Now()
+	remotePath := path.Join(w.opts.RemotePath, rel)
+	fi := FileInfo{
+		ActualSize: info.Size(),
+		Hash:       hash,
+		MimeType:   prev.MimeType,
+		Type:       fileref.FILE,
+		LookupHash: fileref.GetReferenceLookup(w.alloc.ID, remotePath),
+		CreatedAt:  prev.CreatedAt,
+		UpdatedAt:  now,
+	}
+	if fi.CreatedAt == 0 {
+		fi.CreatedAt = now
+	}
+	if f, err := os.Open(abs); err == nil {
+		if mimeType, err := zboxutil.GetFileContentType(path.Ext(rel), f); err == nil {
+			fi.MimeType = mimeType
+		}
+		f.Close() //nolint: errcheck
+	}
+	return fi
+}
+
+// forgetRemote removes the path and everything below it from the remote state.
+func (w *syncWatcher) forgetRemote(p string) {
+	delete(w.remote, p)
+	prefix := strings.TrimRight(p, "/") + "/"
+	for rp := range w.remote {
+		if strings.HasPrefix(rp, prefix) {
+			delete(w.remote, rp)
+		}
+	}
+}
+
+// saveState persists the local index and the remote state as the last sync snapshot.
+func (w *syncWatcher) saveState() {
+	if err := w.idx.Save(); err != nil {
+		l.Logger.Error("sync watcher failed to save local index", zap.Error(err))
+	}
+	if w.opts.LastSyncCachePath == "" {
+		return
+	}
+	buf, err := json.Marshal(w.remote)
+	if err != nil {
+		l.Logger.Error("sync watcher failed to encode snapshot", zap.Error(err))
+		return
+	}
+	if err := sys.Files.WriteFile(w.opts.LastSyncCachePath, buf, 0644); err != nil {
+		l.Logger.Error("sync watcher failed to save snapshot", zap.Error(err))
+	}
+}
+
+// scanLocal stats every file of the local root for the polling mode.
+func (w *syncWatcher) scanLocal() map[string]LocalIndexEntry {
+	scan := make(map[string]LocalIndexEntry)
+	filepath.Walk(w.localRoot, func(p string, info os.FileInfo, err error) error { //nolint: errcheck
+		if err != nil {
+			return nil
+		}
+		rel, ok := w.relPath(p)
+		if !ok || rel == "/" {
+			return nil
+		}
+		if w.isIgnored(rel, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() || w.isStateFile(p) {
+			return nil
+		}
+		scan[rel] = LocalIndexEntry{Size: info.Size(), ModTime: info.ModTime().UnixNano(), Inode: fileInode(info)}
+		return nil
+	})
+	return scan
+}
+
+// relPath converts a local path into an allocation-style path relative to the local root.
+func (w *syncWatcher) relPath(p string) (string, bool) {
+	rel, err := filepath.Rel(w.localRoot, p)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return "", false
+	}
+	if rel == "." {
+		return "/", true
+	}
+	return "/" + filepath.ToSlash(rel), true
+}
+
+// remoteRelPath converts a remote path into a path relative to the remote root.
+func (w *syncWatcher) remoteRelPath(p string) (string, bool) {
+	root := strings.TrimRight(w.opts.RemotePath, "/")
+	if root == "" {
+		return p, true
+	}
+	if !strings.HasPrefix(p, root+"/") {
+		return "", false
+	}
+	return strings.TrimPrefix(p, root), true
+}
+
+// isIgnored applies the local file filters, remote exclude paths and ignore rules
+// of the sync options to a relative path.
+func (w *syncWatcher) isIgnored(rel string, isDir bool) bool {
+	if w.filter[path.Base(rel)] || w.opts.IgnoreRules.Match(rel, isDir) {
+		return true
+	}
+	if skipHiddenManagedPath(w.opts.RemotePath, path.Join(w.opts.RemotePath, rel)) {
+		return true
+	}
+	for p := rel; p != "/" && p != "."; p = path.Dir(p) {
+		if _, ok := w.exclMap[p]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isStateFile reports whether the local path is one of the files written by the
+// sync itself.
+func (w *syncWatcher) isStateFile(p string) bool {
+	p = filepath.Clean(p)
+	return (w.opts.LastSyncCachePath != "" && p == filepath.Clean(w.opts.LastSyncCachePath)) ||
+		(w.opts.LocalIndexPath != "" && p == filepath.Clean(w.opts.LocalIndexPath))
+}
@@ -0,0 +1,26 @@
+//go:build !js && !wasm
+// +build !js,!wasm
+
+package sdk
+
+import "github.com/fsnotify/fsnotify"
+
+type fsLocalWatcher struct {
+	*fsnotify.Watcher
+}
+
+func newLocalWatcher() (localWatcher, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	return &fsLocalWatcher{Watcher: w}, nil
+}
+
+func (w *fsLocalWatcher) EventsChan() <-chan fsnotify.Event {
+	return w.Events
+}
+
+func (w *fsLocalWatcher) ErrorsChan() <-chan error {
+	return w.Errors
+}
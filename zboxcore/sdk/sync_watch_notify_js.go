@@ -0,0 +1,11 @@
+//go:build js && wasm
+// +build js,wasm
+
+package sdk
+
+import "github.com/0chain/errors"
+
+// newLocalWatcher always fails on webassembly, WatchAndSync polls the local root instead.
+func newLocalWatcher() (localWatcher, error) {
+	return nil, errors.New("sync_watch_unsupported", "file system notifications are not supported on webassembly")
+}
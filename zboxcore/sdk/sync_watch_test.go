@@ -0,0 +1,152 @@
+package sdk
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0chain/gosdk/constants"
+	"github.com/0chain/gosdk/core/pathutil"
+	"github.com/0chain/gosdk/zboxcore/fileref"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncWatcher_Paths(t *testing.T) {
+	w := &syncWatcher{
+		opts:      WatchSyncOptions{SyncOptions: SyncOptions{RemotePath: "/backup/"}},
+		localRoot: "/home/user/sync",
+		filter:    map[string]bool{".DS_Store": true},
+		exclMap:   getRemoteExcludeMap([]string{"/build/"}),
+	}
+
+	rel, ok := w.relPath("/home/user/sync/docs/a.txt")
+	require.True(t, ok)
+	require.Equal(t, "/docs/a.txt", rel)
+	_, ok = w.relPath("/home/user/other/a.txt")
+	require.False(t, ok)
+
+	rel, ok = w.remoteRelPath("/backup/docs/a.txt")
+	require.True(t, ok)
+	require.Equal(t, "/docs/a.txt", rel)
+	_, ok = w.remoteRelPath("/backups/a.txt")
+	require.False(t, ok)
+
+	require.True(t, w.isIgnored("/docs/.DS_Store", false))
+	require.True(t, w.isIgnored("/build", true))
+	require.True(t, w.isIgnored("/build/out/app", false))
+	require.False(t, w.isIgnored("/docs/a.txt", false))
+
+	w.opts.IgnoreRules, _ = pathutil.NewIgnoreRules("*.tmp", "cache/")
+	require.True(t, w.isIgnored("/docs/a.tmp", false))
+	require.True(t, w.isIgnored("/docs/cache", true))
+	require.True(t, w.isIgnored("/docs/cache/a.txt", false))
+	require.False(t, w.isIgnored("/docs/a.txt", false))
+}
+
+func TestSyncWatcher_ScanLocal(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "build"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.txt"), []byte("a"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "build", "app"), []byte("b"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "index.json"), []byte("{}"), 0644))
+
+	w := &syncWatcher{
+		opts: WatchSyncOptions{SyncOptions: SyncOptions{
+			RemotePath:     "/",
+			LocalIndexPath: filepath.Join(root, "index.json"),
+		}},
+		localRoot: root,
+		filter:    map[string]bool{},
+		exclMap:   getRemoteExcludeMap([]string{"/build"}),
+	}
+	scan := w.scanLocal()
+	require.Len(t, scan, 1)
+	require.Equal(t, int64(1), scan["/a.txt"].Size)
+}
+
+func TestSyncWatcher_RefreshPullFlush(t *testing.T) {
+	root := t.TempDir()
+	md5sum := func(content string) string {
+		h := md5.Sum([]byte(content))
+		return hex.EncodeToString(h[:])
+	}
+	read := func(p string) string {
+		b, err := os.ReadFile(filepath.Join(root, p))
+		require.NoError(t, err)
+		return string(b)
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.txt"), []byte("v1"), 0644))
+
+	ops := fakeSyncRemote(t, nil)
+	rules, err := pathutil.NewIgnoreRules("*.tmp")
+	require.NoError(t, err)
+	idx, err := LoadLocalFileIndex("")
+	require.NoError(t, err)
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	w := &syncWatcher{
+		alloc: &Allocation{ID: "alloc"},
+		opts: WatchSyncOptions{SyncOptions: SyncOptions{
+			LocalRootPath:     root,
+			RemotePath:        "/remote",
+			LastSyncCachePath: statePath,
+			ConflictPolicy:    ConflictKeepBoth,
+			IgnoreRules:       rules,
+		}},
+		idx:       idx,
+		localRoot: root,
+		filter:    map[string]bool{},
+		exclMap:   map[string]int{},
+		pending:   map[string]bool{},
+	}
+	w.listRemote = func(ctx context.Context) <-chan ORef {
+		refs := make(chan ORef, 3)
+		refs <- ORef{SimilarField: SimilarField{Path: "/remote", Type: fileref.DIRECTORY}}
+		refs <- ORef{SimilarField: SimilarField{Path: "/remote/a.txt", Type: fileref.FILE, ActualFileHash: md5sum("v1")}}
+		refs <- ORef{SimilarField: SimilarField{Path: "/remote/b.tmp", Type: fileref.FILE, ActualFileHash: md5sum("b")}}
+		close(refs)
+		return refs
+	}
+	w.recentRefs = func(page int, from int64) (*RecentlyAddedRefResult, error) {
+		return &RecentlyAddedRefResult{Refs: []ORef{
+			{SimilarField: SimilarField{Path: "/remote/a.txt", Type: fileref.FILE, ActualFileHash: md5sum("remote /remote/a.txt")}},
+		}}, nil
+	}
+
+	// the remote state holds the content hashes, without the ignored files
+	require.NoError(t, w.refreshRemote(context.Background()))
+	require.Len(t, w.remote, 1)
+	require.Equal(t, md5sum("v1"), w.remote["/a.txt"].Hash)
+
+	// a file changed only remotely is downloaded, not reported as a conflict
+	w.pullRemote(context.Background())
+	require.Equal(t, "remote /remote/a.txt", read("a.txt"))
+	_, err = os.Stat(filepath.Join(root, "a.conflict.txt"))
+	require.True(t, os.IsNotExist(err))
+	require.Empty(t, *ops)
+	require.Equal(t, md5sum("remote /remote/a.txt"), w.remote["/a.txt"].Hash)
+
+	// a local change is uploaded and recorded with the full remote state
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.txt"), []byte("local v2"), 0644))
+	w.pending["/a.txt"] = true
+	w.flush(context.Background())
+	require.Len(t, *ops, 1)
+	require.Equal(t, constants.FileOperationUpdate, (*ops)[0].OperationType)
+	require.Equal(t, "/remote/a.txt", (*ops)[0].RemotePath)
+
+	info := w.remote["/a.txt"]
+	require.Equal(t, md5sum("local v2"), info.Hash)
+	require.Equal(t, int64(len("local v2")), info.ActualSize)
+	require.Equal(t, fileref.FILE, info.Type)
+	require.Equal(t, fileref.GetReferenceLookup("alloc", "/remote/a.txt"), info.LookupHash)
+	require.NotZero(t, info.UpdatedAt)
+
+	buf, err := os.ReadFile(statePath)
+	require.NoError(t, err)
+	var state map[string]FileInfo
+	require.NoError(t, json.Unmarshal(buf, &state))
+	require.Equal(t, info.Hash, state["/a.txt"].Hash)
+}
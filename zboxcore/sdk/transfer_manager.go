@@ -0,0 +1,650 @@
+package sdk
+
+import (
+	"container/heap"
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/0chain/errors"
+	"github.com/0chain/gosdk/constants"
+	"github.com/0chain/gosdk/zboxcore/logger"
+	"github.com/0chain/gosdk/zboxcore/zboxutil"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrTransferPaused is the cause of the cancellation of a transfer paused by TransferManager.Pause.
+	ErrTransferPaused = errors.New("transfer_paused", "transfer paused by user")
+
+	// ErrTransferCanceled is the cause of the cancellation of a transfer canceled by TransferManager.Cancel.
+	ErrTransferCanceled = errors.New("transfer_canceled", "transfer canceled by user")
+)
+
+// transferCancelRetry is the interval between the attempts to forward the pause or
+// the cancellation of an upload which isn't registered in CancelOpCtx yet.
+const transferCancelRetry = 100 * time.Millisecond
+
+// TransferKind is the kind of a job of a TransferManager.
+type TransferKind string
+
+const (
+	TransferUpload            TransferKind = "upload"
+	TransferDownload          TransferKind = "download"
+	TransferDownloadDirectory TransferKind = "download_directory"
+)
+
+// TransferState is the state of a job of a TransferManager.
+type TransferState string
+
+const (
+	TransferQueued    TransferState = "queued"
+	TransferRunning   TransferState = "running"
+	TransferPaused    TransferState = "paused"
+	TransferCompleted TransferState = "completed"
+	TransferFailed    TransferState = "failed"
+	TransferCanceled  TransferState = "canceled"
+)
+
+// IsFinal reports whether the job won't change state anymore.
+func (s TransferState) IsFinal() bool {
+	return s == TransferCompleted || s == TransferFailed || s == TransferCanceled
+}
+
+// TransferJobStatus is a snapshot of the state of a job of a TransferManager.
+type TransferJobStatus struct {
+	ID             string        `json:"id"`
+	Kind           TransferKind  `json:"kind"`
+	AllocationID   string        `json:"allocation_id"`
+	LocalPath      string        `json:"local_path"`
+	RemotePath     string        `json:"remote_path"`
+	Priority       int           `json:"priority"`
+	State          TransferState `json:"state"`
+	TotalBytes     int64         `json:"total_bytes"`
+	CompletedBytes int64         `json:"completed_bytes"`
+	Error          string        `json:"error,omitempty"`
+	QueuedAt       time.Time     `json:"queued_at"`
+	StartedAt      time.Time     `json:"started_at,omitempty"`
+	FinishedAt     time.Time     `json:"finished_at,omitempty"`
+}
+
+// UploadJob describes an upload queued in a TransferManager.
+type UploadJob struct {
+	Allocation     *Allocation
+	Workdir        string
+	LocalPath      string
+	RemotePath     string
+	IsUpdate       bool
+	Encrypt        bool
+	StatusCallback StatusCallback
+	Opts           []ChunkedUploadOption
+}
+
+// DownloadJob describes a file download queued in a TransferManager.
+type DownloadJob struct {
+	Allocation     *Allocation
+	LocalPath      string
+	RemotePath     string
+	VerifyDownload bool
+	StatusCallback StatusCallback
+	Opts           []DownloadRequestOption
+}
+
+// DownloadDirectoryJob describes a directory download queued in a TransferManager.
+type DownloadDirectoryJob struct {
+	Allocation     *Allocation
+	LocalPath      string
+	RemotePath     string
+	StatusCallback StatusCallback
+	Opts           []DownloadDirectoryOption
+}
+
+// TransferManagerOption is a function that configures a TransferManager.
+type TransferManagerOption func(tm *TransferManager)
+
+// WithMaxConcurrentTransfers sets the maximum number of jobs running at the same time.
+//   - n: the maximum number of running jobs, unlimited if not positive.
+func WithMaxConcurrentTransfers(n int) TransferManagerOption {
+	return func(tm *TransferManager) {
+		tm.maxConcurrent = n
+	}
+}
+
+// WithMaxTransfersPerBlobber sets the maximum number of running jobs using the same blobber.
+// Every transfer of an allocation connects to all the blobbers of the allocation.
+//   - n: the maximum number of running jobs per blobber, unlimited if not positive.
+func WithMaxTransfersPerBlobber(n int) TransferManagerOption {
+	return func(tm *TransferManager) {
+		tm.maxPerBlobber = n
+	}
+}
+
+// TransferManager schedules the uploads and downloads of one or several allocations.
+// The queued jobs are started by decreasing priority, then in queuing order, within
+// the limits of running jobs in total and per blobber. The jobs can be paused,
+// resumed and canceled by id, whatever their kind.
+type TransferManager struct {
+	mu            sync.Mutex
+	maxConcurrent int
+	maxPerBlobber int
+	jobs          map[string]*transferJob
+	queue         transferQueue
+	running       int
+	blobberLoad   map[string]int
+	seq           uint64
+}
+
+type transferJob struct {
+	status   TransferJobStatus
+	seq      uint64
+	index    int
+	blobbers []string
+	sb       StatusCallback
+	run      func(ctx context.Context, sb StatusCallback) error
+	ctxCncl  context.CancelCauseFunc
+	stop     error
+	err      error
+	done     chan struct{}
+}
+
+// NewTransferManager creates a transfer manager.
+//   - opts: the options of the manager, by default the number of running jobs is unlimited.
+func NewTransferManager(opts ...TransferManagerOption) *TransferManager {
+	tm := &TransferManager{
+		jobs:        make(map[string]*transferJob),
+		blobberLoad: make(map[string]int),
+	}
+	for _, opt := range opts {
+		opt(tm)
+	}
+	return tm
+}
+
+// EnqueueUpload queues the upload of a local file and returns the id of the job.
+// A paused upload is resumed from its last uploaded chunk, as for PauseUpload.
+//   - job: the upload to queue.
+//   - priority: the priority of the job, jobs of higher priority start first.
+func (tm *TransferManager) EnqueueUpload(job UploadJob, priority int) (string, error) {
+	a := job.Allocation
+	if a == nil || !a.isInitialized() {
+		return "", notInitialized
+	}
+	if (!job.IsUpdate && !a.CanUpload()) || (job.IsUpdate && !a.CanUpdate()) {
+		return "", constants.ErrFileOptionNotPermitted
+	}
+	remotePath := zboxutil.RemoteClean(job.RemotePath)
+	if !zboxutil.IsRemoteAbs(remotePath) {
+		return "", errors.New("invalid_path", "Path should be valid and absolute")
+	}
+	job.RemotePath = zboxutil.GetFullRemotePath(job.LocalPath, remotePath)
+	return tm.enqueue(TransferUpload, a, job.LocalPath, job.RemotePath, priority, job.StatusCallback,
+		func(ctx context.Context, sb StatusCallback) error {
+			return a.transferUpload(ctx, job, sb)
+		}), nil
+}
+
+// EnqueueDownload queues the download of a remote file and returns the id of the job.
+// A paused download is resumed from its last written block when it has a download
+// progress storer, as for PauseDownload, otherwise it's restarted.
+//   - job: the download to queue.
+//   - priority: the priority of the job, jobs of higher priority start first.
+func (tm *TransferManager) EnqueueDownload(job DownloadJob, priority int) (string, error) {
+	a := job.Allocation
+	if a == nil || !a.isInitialized() {
+		return "", notInitialized
+	}
+	return tm.enqueue(TransferDownload, a, job.LocalPath, job.RemotePath, priority, job.StatusCallback,
+		func(ctx context.Context, sb StatusCallback) error {
+			return a.transferDownload(ctx, job, sb)
+		}), nil
+}
+
+// EnqueueDownloadDirectory queues the download of a remote directory and returns the id of
+// the job. A paused directory download stops after its current batch of files, and skips
+// the files already downloaded when resumed.
+//   - job: the download to queue.
+//   - priority: the priority of the job, jobs of higher priority start first.
+func (tm *TransferManager) EnqueueDownloadDirectory(job DownloadDirectoryJob, priority int) (string, error) {
+	a := job.Allocation
+	if a == nil || !a.isInitialized() {
+		return "", notInitialized
+	}
+	return tm.enqueue(TransferDownloadDirectory, a, job.LocalPath, job.RemotePath, priority, job.StatusCallback,
+		func(ctx context.Context, sb StatusCallback) error {
+			return a.DownloadDirectory(ctx, job.RemotePath, job.LocalPath, "", sb, job.Opts...)
+		}), nil
+}
+
+func (tm *TransferManager) enqueue(kind TransferKind, a *Allocation, localPath, remotePath string, priority int,
+	sb StatusCallback, run func(ctx context.Context, sb StatusCallback) error) string {
+	blobbers := make([]string, 0, len(a.Blobbers))
+	for _, b := range a.Blobbers {
+		blobbers = append(blobbers, b.ID)
+	}
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.seq++
+	job := &transferJob{
+		status: TransferJobStatus{
+			ID:           zboxutil.NewConnectionId(),
+			Kind:         kind,
+			AllocationID: a.ID,
+			LocalPath:    localPath,
+			RemotePath:   remotePath,
+			Priority:     priority,
+			State:        TransferQueued,
+			QueuedAt:     time.Now(),
+		},
+		seq:      tm.seq,
+		blobbers: blobbers,
+		sb:       sb,
+		run:      run,
+		done:     make(chan struct{}),
+	}
+	tm.jobs[job.status.ID] = job
+	heap.Push(&tm.queue, job)
+	tm.scheduleLocked()
+	return job.status.ID
+}
+
+// Pause pauses a job. A queued job isn't started until it's resumed, a running job is stopped.
+//   - id: the id of the job.
+func (tm *TransferManager) Pause(id string) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	job, err := tm.jobLocked(id)
+	if err != nil {
+		return err
+	}
+	switch job.status.State {
+	case TransferQueued:
+		heap.Remove(&tm.queue, job.index)
+		job.status.State = TransferPaused
+	case TransferRunning:
+		if job.stop == nil {
+			job.stop = ErrTransferPaused
+			job.ctxCncl(ErrTransferPaused)
+		}
+	case TransferPaused:
+	default:
+		return errors.New("transfer_finished", "transfer "+id+" is "+string(job.status.State))
+	}
+	return nil
+}
+
+// Resume queues a paused job again.
+//   - id: the id of the job.
+func (tm *TransferManager) Resume(id string) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	job, err := tm.jobLocked(id)
+	if err != nil {
+		return err
+	}
+	if job.status.State != TransferPaused {
+		return errors.New("transfer_not_paused", "transfer "+id+" is "+string(job.status.State))
+	}
+	job.status.State = TransferQueued
+	job.status.Error = ""
+	heap.Push(&tm.queue, job)
+	tm.scheduleLocked()
+	return nil
+}
+
+// Cancel cancels a job. A running upload is canceled as with CancelUpload, a running
+// download as with CancelDownload.
+//   - id: the id of the job.
+func (tm *TransferManager) Cancel(id string) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	job, err := tm.jobLocked(id)
+	if err != nil {
+		return err
+	}
+	switch job.status.State {
+	case TransferQueued:
+		heap.Remove(&tm.queue, job.index)
+		tm.finishLocked(job, TransferCanceled, ErrTransferCanceled)
+	case TransferPaused:
+		tm.finishLocked(job, TransferCanceled, ErrTransferCanceled)
+	case TransferRunning:
+		job.stop = ErrTransferCanceled
+		job.ctxCncl(ErrTransferCanceled)
+	default:
+		return errors.New("transfer_finished", "transfer "+id+" is "+string(job.status.State))
+	}
+	return nil
+}
+
+// SetPriority changes the priority of a job, it's only relevant until the job is started.
+//   - id: the id of the job.
+//   - priority: the new priority of the job.
+func (tm *TransferManager) SetPriority(id string, priority int) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	job, err := tm.jobLocked(id)
+	if err != nil {
+		return err
+	}
+	job.status.Priority = priority
+	if job.status.State == TransferQueued {
+		heap.Fix(&tm.queue, job.index)
+	}
+	return nil
+}
+
+// Wait blocks until the job is completed, failed or canceled, and returns its error.
+//   - ctx: the context of the wait.
+//   - id: the id of the job.
+func (tm *TransferManager) Wait(ctx context.Context, id string) error {
+	tm.mu.Lock()
+	job, err := tm.jobLocked(id)
+	tm.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	select {
+	case <-job.done:
+		return job.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Snapshot returns the status of all the jobs of the manager, in queuing order.
+func (tm *TransferManager) Snapshot() []TransferJobStatus {
+	tm.mu.Lock()
+	jobs := make([]*transferJob, 0, len(tm.jobs))
+	for _, job := range tm.jobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].seq < jobs[j].seq
+	})
+	statuses := make([]TransferJobStatus, 0, len(jobs))
+	for _, job := range jobs {
+		statuses = append(statuses, job.status)
+	}
+	tm.mu.Unlock()
+	return statuses
+}
+
+// Remove forgets a completed, failed or canceled job.
+//   - id: the id of the job.
+func (tm *TransferManager) Remove(id string) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	job, err := tm.jobLocked(id)
+	if err != nil {
+		return err
+	}
+	if !job.status.State.IsFinal() {
+		return errors.New("transfer_not_finished", "transfer "+id+" is "+string(job.status.State))
+	}
+	delete(tm.jobs, id)
+	return nil
+}
+
+func (tm *TransferManager) jobLocked(id string) (*transferJob, error) {
+	job, ok := tm.jobs[id]
+	if !ok {
+		return nil, errors.New("transfer_not_found", "transfer "+id+" not found")
+	}
+	return job, nil
+}
+
+// scheduleLocked starts the queued jobs allowed by the limits. A job waiting for a busy
+// blobber doesn't hold back the jobs of lower priority using other blobbers.
+func (tm *TransferManager) scheduleLocked() {
+	var waiting []*transferJob
+	for tm.queue.Len() > 0 && (tm.maxConcurrent <= 0 || tm.running < tm.maxConcurrent) {
+		job := heap.Pop(&tm.queue).(*transferJob)
+		if !tm.blobbersAvailableLocked(job) {
+			waiting = append(waiting, job)
+			continue
+		}
+		tm.startLocked(job)
+	}
+	for _, job := range waiting {
+		heap.Push(&tm.queue, job)
+	}
+}
+
+func (tm *TransferManager) blobbersAvailableLocked(job *transferJob) bool {
+	if tm.maxPerBlobber <= 0 {
+		return true
+	}
+	for _, id := range job.blobbers {
+		if tm.blobberLoad[id] >= tm.maxPerBlobber {
+			return false
+		}
+	}
+	return true
+}
+
+func (tm *TransferManager) startLocked(job *transferJob) {
+	ctx, ctxCncl := context.WithCancelCause(context.Background())
+	job.ctxCncl = ctxCncl
+	job.stop = nil
+	job.status.State = TransferRunning
+	job.status.StartedAt = time.Now()
+	tm.running++
+	for _, id := range job.blobbers {
+		tm.blobberLoad[id]++
+	}
+	go tm.runJob(ctx, job)
+}
+
+func (tm *TransferManager) runJob(ctx context.Context, job *transferJob) {
+	err := job.run(ctx, &transferProgress{tm: tm, job: job})
+	job.ctxCncl(nil)
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.running--
+	for _, id := range job.blobbers {
+		tm.blobberLoad[id]--
+	}
+	switch {
+	case err == nil:
+		tm.finishLocked(job, TransferCompleted, nil)
+	case job.stop == ErrTransferPaused:
+		job.status.State = TransferPaused
+	case job.stop == ErrTransferCanceled:
+		tm.finishLocked(job, TransferCanceled, ErrTransferCanceled)
+	default:
+		logger.Logger.Error("transfer failed", zap.String("id", job.status.ID),
+			zap.String("remote_path", job.status.RemotePath), zap.Error(err))
+		tm.finishLocked(job, TransferFailed, err)
+	}
+	tm.scheduleLocked()
+}
+
+func (tm *TransferManager) finishLocked(job *transferJob, state TransferState, err error) {
+	job.status.State = state
+	job.status.FinishedAt = time.Now()
+	job.err = err
+	if err != nil {
+		job.status.Error = err.Error()
+	}
+	close(job.done)
+}
+
+// transferUpload uploads a file with a multi-operation, so that the upload can be
+// paused or canceled through CancelOpCtx.
+func (a *Allocation) transferUpload(ctx context.Context, job UploadJob, sb StatusCallback) error {
+	operationType := constants.FileOperationInsert
+	if job.IsUpdate {
+		operationType = constants.FileOperationUpdate
+	}
+	opts := []ChunkedUploadOption{
+		WithEncrypt(job.Encrypt),
+		WithStatusCallback(sb),
+	}
+	opts = append(opts, job.Opts...)
+	fileReader, op, err := openUploadOperation(OperationRequest{
+		OperationType: operationType,
+		LocalPath:     job.LocalPath,
+		RemotePath:    job.RemotePath,
+	}, job.Workdir, opts...)
+	if err != nil {
+		return err
+	}
+	defer fileReader.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	go forwardUploadCancel(ctx, done, job.RemotePath)
+
+	return a.DoMultiOperation([]OperationRequest{op})
+}
+
+// forwardUploadCancel forwards the cancellation of the job context to the upload
+// of remotePath, waiting for the upload to be registered in CancelOpCtx.
+func forwardUploadCancel(ctx context.Context, done <-chan struct{}, remotePath string) {
+	select {
+	case <-done:
+		return
+	case <-ctx.Done():
+	}
+	cause := context.Cause(ctx)
+	if errors.Is(cause, ErrTransferPaused) {
+		// keep the upload progress
+		cause = ErrPauseUpload
+	}
+	ticker := time.NewTicker(transferCancelRetry)
+	defer ticker.Stop()
+	for {
+		cancelLock.Lock()
+		cancelFunc, ok := CancelOpCtx[remotePath]
+		cancelLock.Unlock()
+		if ok {
+			cancelFunc(cause)
+			return
+		}
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// transferDownload downloads a file and waits for its status callback.
+func (a *Allocation) transferDownload(ctx context.Context, job DownloadJob, sb StatusCallback) error {
+	waiter := &transferWaiter{StatusCallback: sb, result: make(chan error, 1)}
+	err := a.DownloadFile(job.LocalPath, job.RemotePath, job.VerifyDownload, waiter, true, job.Opts...)
+	if err != nil {
+		return err
+	}
+	select {
+	case err = <-waiter.result:
+		return err
+	case <-ctx.Done():
+		if !errors.Is(context.Cause(ctx), ErrTransferPaused) || a.PauseDownload(job.RemotePath) != nil {
+			a.CancelDownload(job.RemotePath) //nolint: errcheck
+		}
+		return <-waiter.result
+	}
+}
+
+// transferProgress records the progress of a job, then forwards it to the status
+// callback of the job.
+type transferProgress struct {
+	tm  *TransferManager
+	job *transferJob
+}
+
+func (tp *transferProgress) Started(allocationId, filePath string, op int, totalBytes int) {
+	tp.tm.mu.Lock()
+	tp.job.status.TotalBytes = int64(totalBytes)
+	tp.job.status.CompletedBytes = 0
+	tp.tm.mu.Unlock()
+	if tp.job.sb != nil {
+		tp.job.sb.Started(allocationId, filePath, op, totalBytes)
+	}
+}
+
+func (tp *transferProgress) InProgress(allocationId, filePath string, op int, completedBytes int, data []byte) {
+	tp.tm.mu.Lock()
+	tp.job.status.CompletedBytes = int64(completedBytes)
+	tp.tm.mu.Unlock()
+	if tp.job.sb != nil {
+		tp.job.sb.InProgress(allocationId, filePath, op, completedBytes, data)
+	}
+}
+
+func (tp *transferProgress) Error(allocationID string, filePath string, op int, err error) {
+	if tp.job.sb != nil {
+		tp.job.sb.Error(allocationID, filePath, op, err)
+	}
+}
+
+func (tp *transferProgress) Completed(allocationId, filePath string, filename string, mimetype string, size int, op int) {
+	tp.tm.mu.Lock()
+	if tp.job.status.Kind != TransferDownloadDirectory {
+		tp.job.status.CompletedBytes = int64(size)
+	}
+	tp.tm.mu.Unlock()
+	if tp.job.sb != nil {
+		tp.job.sb.Completed(allocationId, filePath, filename, mimetype, size, op)
+	}
+}
+
+func (tp *transferProgress) RepairCompleted(filesRepaired int) {
+	if tp.job.sb != nil {
+		tp.job.sb.RepairCompleted(filesRepaired)
+	}
+}
+
+// transferWaiter reports the end of an asynchronous download.
+type transferWaiter struct {
+	StatusCallback
+	once   sync.Once
+	result chan error
+}
+
+func (tw *transferWaiter) Error(allocationID string, filePath string, op int, err error) {
+	tw.StatusCallback.Error(allocationID, filePath, op, err)
+	tw.once.Do(func() { tw.result <- err })
+}
+
+func (tw *transferWaiter) Completed(allocationId, filePath string, filename string, mimetype string, size int, op int) {
+	tw.StatusCallback.Completed(allocationId, filePath, filename, mimetype, size, op)
+	tw.once.Do(func() { tw.result <- nil })
+}
+
+// transferQueue is a priority queue of jobs, by decreasing priority then queuing order.
+type transferQueue []*transferJob
+
+func (q transferQueue) Len() int { return len(q) }
+
+func (q transferQueue) Less(i, j int) bool {
+	if q[i].status.Priority != q[j].status.Priority {
+		return q[i].status.Priority > q[j].status.Priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q transferQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *transferQueue) Push(x interface{}) {
+	job := x.(*transferJob)
+	job.index = len(*q)
+	*q = append(*q, job)
+}
+
+func (q *transferQueue) Pop() interface{} {
+	old := *q
+	job := old[len(old)-1]
+	old[len(old)-1] = nil
+	job.index = -1
+	*q = old[:len(old)-1]
+	return job
+}
@@ -0,0 +1,151 @@
+package sdk
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/0chain/gosdk/zboxcore/blockchain"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTransfer is a job run which blocks until it's released or its context is done.
+type fakeTransfer struct {
+	started chan string
+	release chan error
+}
+
+func newFakeTransfer() *fakeTransfer {
+	return &fakeTransfer{started: make(chan string, 10), release: make(chan error)}
+}
+
+func (ft *fakeTransfer) enqueue(tm *TransferManager, a *Allocation, name string, priority int) string {
+	return tm.enqueue(TransferUpload, a, name, "/"+name, priority, nil, func(ctx context.Context, sb StatusCallback) error {
+		sb.Started(a.ID, "/"+name, OpUpload, 10)
+		ft.started <- name
+		select {
+		case err := <-ft.release:
+			return err
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		}
+	})
+}
+
+func (ft *fakeTransfer) requireStarted(t *testing.T, name string) {
+	select {
+	case started := <-ft.started:
+		require.Equal(t, name, started)
+	case <-time.After(time.Second):
+		t.Fatalf("%s not started", name)
+	}
+}
+
+func (ft *fakeTransfer) requireIdle(t *testing.T) {
+	select {
+	case started := <-ft.started:
+		t.Fatalf("%s started", started)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func transferStates(tm *TransferManager) map[string]TransferState {
+	states := make(map[string]TransferState)
+	for _, s := range tm.Snapshot() {
+		states[s.LocalPath] = s.State
+	}
+	return states
+}
+
+func fakeTransferAllocation(id string, blobbers ...string) *Allocation {
+	a := &Allocation{ID: id}
+	for _, b := range blobbers {
+		a.Blobbers = append(a.Blobbers, &blockchain.StorageNode{ID: b})
+	}
+	return a
+}
+
+func TestTransferManagerPriorities(t *testing.T) {
+	a := fakeTransferAllocation("alloc", "b1", "b2")
+	tm := NewTransferManager(WithMaxConcurrentTransfers(1))
+	ft := newFakeTransfer()
+
+	first := ft.enqueue(tm, a, "first", 0)
+	ft.requireStarted(t, "first")
+	low := ft.enqueue(tm, a, "low", 0)
+	high := ft.enqueue(tm, a, "high", 5)
+	ft.requireIdle(t)
+
+	ft.release <- nil
+	require.NoError(t, tm.Wait(context.Background(), first))
+	ft.requireStarted(t, "high")
+	ft.release <- nil
+	require.NoError(t, tm.Wait(context.Background(), high))
+	ft.requireStarted(t, "low")
+	ft.release <- nil
+	require.NoError(t, tm.Wait(context.Background(), low))
+
+	snapshot := tm.Snapshot()
+	require.Len(t, snapshot, 3)
+	require.Equal(t, first, snapshot[0].ID)
+	require.Equal(t, TransferCompleted, snapshot[0].State)
+	require.Equal(t, int64(10), snapshot[0].TotalBytes)
+	require.Error(t, tm.Pause(first))
+	require.NoError(t, tm.Remove(first))
+	require.Len(t, tm.Snapshot(), 2)
+}
+
+func TestTransferManagerBlobberCaps(t *testing.T) {
+	tm := NewTransferManager(WithMaxTransfersPerBlobber(1))
+	ft := newFakeTransfer()
+
+	ft.enqueue(tm, fakeTransferAllocation("a1", "b1", "b2"), "a1", 0)
+	ft.requireStarted(t, "a1")
+	// b2 is busy, the job waits without holding back the next one
+	waiting := ft.enqueue(tm, fakeTransferAllocation("a2", "b2", "b3"), "a2", 1)
+	ft.enqueue(tm, fakeTransferAllocation("a3", "b3", "b4"), "a3", 0)
+	ft.requireStarted(t, "a3")
+	ft.requireIdle(t)
+	require.Equal(t, TransferQueued, transferStates(tm)["a2"])
+
+	ft.release <- nil
+	ft.release <- nil
+	ft.requireStarted(t, "a2")
+	ft.release <- nil
+	require.NoError(t, tm.Wait(context.Background(), waiting))
+}
+
+func TestTransferManagerPauseResumeCancel(t *testing.T) {
+	a := fakeTransferAllocation("alloc", "b1")
+	tm := NewTransferManager(WithMaxConcurrentTransfers(1))
+	ft := newFakeTransfer()
+
+	running := ft.enqueue(tm, a, "running", 0)
+	ft.requireStarted(t, "running")
+	queued := ft.enqueue(tm, a, "queued", 0)
+
+	// a paused queued job is skipped
+	require.NoError(t, tm.Pause(queued))
+	require.Error(t, tm.Resume(running))
+	require.NoError(t, tm.Pause(running))
+	require.Eventually(t, func() bool {
+		return transferStates(tm)["running"] == TransferPaused
+	}, time.Second, 10*time.Millisecond)
+	ft.requireIdle(t)
+
+	require.NoError(t, tm.Resume(running))
+	ft.requireStarted(t, "running")
+	require.NoError(t, tm.Cancel(running))
+	require.ErrorIs(t, tm.Wait(context.Background(), running), ErrTransferCanceled)
+
+	require.NoError(t, tm.Resume(queued))
+	ft.requireStarted(t, "queued")
+	ft.release <- context.DeadlineExceeded
+	require.ErrorIs(t, tm.Wait(context.Background(), queued), context.DeadlineExceeded)
+
+	states := transferStates(tm)
+	require.Equal(t, TransferCanceled, states["running"])
+	require.Equal(t, TransferFailed, states["queued"])
+	require.Error(t, tm.Cancel(queued))
+	require.Error(t, tm.Pause("unknown"))
+}
@@ -0,0 +1,265 @@
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/0chain/errors"
+	"github.com/0chain/gosdk/constants"
+	"github.com/0chain/gosdk/zboxcore/fileref"
+	"github.com/0chain/gosdk/zboxcore/logger"
+	"github.com/0chain/gosdk/zboxcore/zboxutil"
+	"go.uber.org/zap"
+)
+
+// TrashDir is the hidden remote directory receiving the objects deleted in
+// soft-delete mode. An object deleted with entry id E is moved into
+// /.trash/E/, and the custom meta of /.trash/E records its original path.
+const TrashDir = "/.trash"
+
+// TrashEntry is an object moved to the trash.
+type TrashEntry struct {
+	// ID identifies the entry in the trash.
+	ID string `json:"id"`
+
+	// OriginalPath is the path of the object before it was deleted.
+	OriginalPath string `json:"original_path"`
+
+	// TrashPath is the remote path of the object in the trash.
+	TrashPath string `json:"trash_path"`
+
+	// Type is the type of the object, fileref.FILE or fileref.DIRECTORY.
+	Type string `json:"type"`
+
+	// Size is the actual size of a file, or the size of a directory.
+	Size int64 `json:"size"`
+
+	// DeletedAt is the time the object was moved to the trash.
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// trashEntryMeta is the custom meta of a trash entry directory.
+type trashEntryMeta struct {
+	OriginalPath string `json:"original_path"`
+	Type         string `json:"type"`
+	Size         int64  `json:"size"`
+	DeletedAt    int64  `json:"deleted_at"`
+}
+
+// EnableSoftDelete turns on soft-delete mode for the allocation. From then on,
+// DeleteFile and the delete operations of DoMultiOperation move the objects to
+// TrashDir instead of removing them. It should not be called while operations
+// are running.
+func (a *Allocation) EnableSoftDelete() {
+	a.softDelete = true
+}
+
+// DisableSoftDelete turns off soft-delete mode. The trash is kept.
+func (a *Allocation) DisableSoftDelete() {
+	a.softDelete = false
+}
+
+// MoveToTrash moves the given files or directories to the trash, whether
+// soft-delete mode is enabled or not.
+//   - paths: the remote paths of the objects to delete.
+func (a *Allocation) MoveToTrash(paths ...string) error {
+	ops := make([]OperationRequest, 0, len(paths))
+	for _, p := range paths {
+		ops = append(ops, OperationRequest{
+			OperationType: constants.FileOperationDelete,
+			RemotePath:    p,
+		})
+	}
+	ops, err := a.trashOperations(ops)
+	if err != nil {
+		return err
+	}
+	return a.DoMultiOperation(ops)
+}
+
+// ListTrash lists the objects of the trash, most recently deleted first.
+func (a *Allocation) ListTrash() ([]TrashEntry, error) {
+	if !a.isInitialized() {
+		return nil, notInitialized
+	}
+	var refs []ORef
+	for ref := range a.ListObjects(a.ctx, TrashDir, "", "", "", "", fileref.REGULAR, 0, getRefPageLimit) {
+		if ref.Err != nil {
+			return nil, ref.Err
+		}
+		refs = append(refs, ref)
+	}
+	return trashEntries(refs), nil
+}
+
+// trashEntries builds the trash entries from the refs of the trash directory
+// tree, most recently deleted first.
+func trashEntries(refs []ORef) []TrashEntry {
+	metas := make(map[string]trashEntryMeta)
+	objects := make(map[string]ORef)
+	for _, ref := range refs {
+		switch {
+		case path.Dir(ref.Path) == TrashDir:
+			var meta trashEntryMeta
+			if err := json.Unmarshal([]byte(ref.CustomMeta), &meta); err != nil || meta.OriginalPath == "" {
+				logger.Logger.Error("trash: invalid entry meta", zap.String("path", ref.Path))
+				continue
+			}
+			metas[path.Base(ref.Path)] = meta
+		case path.Dir(path.Dir(ref.Path)) == TrashDir:
+			objects[path.Base(path.Dir(ref.Path))] = ref
+		}
+	}
+
+	entries := make([]TrashEntry, 0, len(metas))
+	for id, meta := range metas {
+		ref, ok := objects[id]
+		if !ok || ref.Name != path.Base(meta.OriginalPath) {
+			continue
+		}
+		entries = append(entries, TrashEntry{
+			ID:           id,
+			OriginalPath: meta.OriginalPath,
+			TrashPath:    ref.Path,
+			Type:         meta.Type,
+			Size:         meta.Size,
+			DeletedAt:    time.Unix(0, meta.DeletedAt),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ID > entries[j].ID
+	})
+	return entries
+}
+
+// RestoreFromTrash moves an object of the trash back to its original path.
+// It fails if an object was created at that path in the meantime.
+//   - entryID: the id of the entry, as returned by ListTrash.
+func (a *Allocation) RestoreFromTrash(entryID string) error {
+	entries, err := a.ListTrash()
+	if err != nil {
+		return err
+	}
+	var entry *TrashEntry
+	for i := range entries {
+		if entries[i].ID == entryID {
+			entry = &entries[i]
+			break
+		}
+	}
+	if entry == nil {
+		return errors.New("trash_entry_not_found", "trash entry "+entryID+" not found")
+	}
+
+	parent := path.Dir(entry.OriginalPath)
+	if parent != "/" {
+		err = a.DoMultiOperation([]OperationRequest{{
+			OperationType: constants.FileOperationCreateDir,
+			RemotePath:    parent,
+		}})
+		if err != nil {
+			return err
+		}
+	}
+	err = a.DoMultiOperation([]OperationRequest{{
+		OperationType: constants.FileOperationMove,
+		RemotePath:    entry.TrashPath,
+		DestPath:      parent,
+	}})
+	if err != nil {
+		return err
+	}
+	return a.DoMultiOperation([]OperationRequest{{
+		OperationType: constants.FileOperationDelete,
+		RemotePath:    path.Join(TrashDir, entry.ID),
+	}})
+}
+
+// EmptyTrash permanently deletes the objects moved to the trash for longer than olderThan.
+//   - olderThan: the minimum time spent in the trash, 0 to empty the whole trash.
+func (a *Allocation) EmptyTrash(olderThan time.Duration) error {
+	entries, err := a.ListTrash()
+	if err != nil {
+		return err
+	}
+	cutoff := time.Now().Add(-olderThan)
+	var ops []OperationRequest
+	for _, e := range entries {
+		if olderThan > 0 && e.DeletedAt.After(cutoff) {
+			continue
+		}
+		ops = append(ops, OperationRequest{
+			OperationType: constants.FileOperationDelete,
+			RemotePath:    path.Join(TrashDir, e.ID),
+		})
+	}
+	return a.DoMultiOperation(ops)
+}
+
+// isHiddenManagedPath reports whether the path is kept by the trash or the
+// versioning, and is deleted for good.
+func isHiddenManagedPath(p string) bool {
+	return isRemoteSubPath(p, TrashDir) || isRemoteSubPath(p, VersionsDir)
+}
+
+// skipHiddenManagedPath reports whether a walk of the root skips the path,
+// the trash and the versions being hidden unless they are walked themselves.
+func skipHiddenManagedPath(root, p string) bool {
+	return isHiddenManagedPath(p) && !isHiddenManagedPath(root)
+}
+
+// trashOperations replaces the delete operations with moves into new trash
+// entries, after creating the entry directories.
+func (a *Allocation) trashOperations(operations []OperationRequest) ([]OperationRequest, error) {
+	now := time.Now()
+	entryPrefix := now.UTC().Format(versionIDLayout) + "-"
+	var dirOps []OperationRequest
+	rewritten := make([]OperationRequest, 0, len(operations))
+	for _, op := range operations {
+		remotePath := zboxutil.RemoteClean(op.RemotePath)
+		if op.OperationType != constants.FileOperationDelete || op.Mask != nil ||
+			remotePath == "/" || isHiddenManagedPath(remotePath) {
+			rewritten = append(rewritten, op)
+			continue
+		}
+		if !a.CanDelete() {
+			return nil, constants.ErrFileOptionNotPermitted
+		}
+		meta, _, err := a.getFileMetaWithMask(remotePath)
+		if err != nil {
+			return nil, errors.Wrap(err, "can't move "+remotePath+" to the trash")
+		}
+		size := meta.ActualFileSize
+		if meta.Type == fileref.DIRECTORY {
+			size = meta.Size
+		}
+		customMeta, err := json.Marshal(trashEntryMeta{
+			OriginalPath: remotePath,
+			Type:         meta.Type,
+			Size:         size,
+			DeletedAt:    now.UnixNano(),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		entryDir := path.Join(TrashDir, fmt.Sprintf("%s%04d", entryPrefix, len(dirOps)))
+		dirOps = append(dirOps, OperationRequest{
+			OperationType: constants.FileOperationCreateDir,
+			RemotePath:    entryDir,
+			FileMeta:      FileMeta{CustomMeta: string(customMeta)},
+		})
+		rewritten = append(rewritten, OperationRequest{
+			OperationType: constants.FileOperationMove,
+			RemotePath:    remotePath,
+			DestPath:      entryDir,
+		})
+	}
+	if err := a.DoMultiOperation(dirOps); err != nil {
+		return nil, errors.Wrap(err, "failed to create the trash entries")
+	}
+	return rewritten, nil
+}
@@ -0,0 +1,83 @@
+package sdk
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/0chain/gosdk/zboxcore/fileref"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrashEntries(t *testing.T) {
+	ref := func(p, customMeta string) ORef {
+		var r ORef
+		r.Path = p
+		r.Name = path.Base(p)
+		r.CustomMeta = customMeta
+		return r
+	}
+	refs := []ORef{
+		ref("/.trash/20240501T100000.000000000Z-0000", `{"original_path":"/docs/a.txt","type":"f","size":5,"deleted_at":1714557600000000000}`),
+		ref("/.trash/20240501T100000.000000000Z-0000/a.txt", ""),
+		ref("/.trash/20240502T100000.000000000Z-0000", `{"original_path":"/photos","type":"d","size":10,"deleted_at":1714644000000000000}`),
+		ref("/.trash/20240502T100000.000000000Z-0000/photos", ""),
+		ref("/.trash/20240502T100000.000000000Z-0000/photos/b.jpg", ""),
+		// entry without its object, restored or partially deleted
+		ref("/.trash/20240503T100000.000000000Z-0000", `{"original_path":"/c.txt","type":"f"}`),
+		// invalid meta
+		ref("/.trash/junk", "not json"),
+		ref("/.trash/junk/x", ""),
+	}
+
+	entries := trashEntries(refs)
+	require.Len(t, entries, 2)
+	require.Equal(t, "20240502T100000.000000000Z-0000", entries[0].ID)
+	require.Equal(t, "/photos", entries[0].OriginalPath)
+	require.Equal(t, "/.trash/20240502T100000.000000000Z-0000/photos", entries[0].TrashPath)
+	require.Equal(t, fileref.DIRECTORY, entries[0].Type)
+	require.Equal(t, "/docs/a.txt", entries[1].OriginalPath)
+	require.Equal(t, int64(1714557600), entries[1].DeletedAt.Unix())
+}
+
+func TestIsHiddenManagedPath(t *testing.T) {
+	require.True(t, isHiddenManagedPath("/.trash/x/a.txt"))
+	require.True(t, isHiddenManagedPath("/.versions"))
+	require.False(t, isHiddenManagedPath("/.trashcan"))
+	require.False(t, isHiddenManagedPath("/docs/.trash"))
+}
+
+func TestAllocation_SyncSkipsTrash(t *testing.T) {
+	ref := func(p, refType, hash string) ORef {
+		var r ORef
+		r.Path, r.Type, r.ActualFileHash = p, refType, hash
+		r.FileMetaHash = "meta " + p
+		return r
+	}
+	blobbers := &directoryTransferBlobbers{refs: []ORef{
+		ref("/a.txt", fileref.FILE, "hash a"),
+		ref(TrashDir, fileref.DIRECTORY, ""),
+		ref("/.trash/entry", fileref.DIRECTORY, ""),
+		ref("/.trash/entry/b.txt", fileref.FILE, "hash b"),
+	}}
+	a := setupDirectoryTransferAllocation(t, blobbers)
+	a.EnableSoftDelete()
+	ops := fakeSyncRemote(t, nil)
+
+	// a local copy of the trash, e.g. made by an older sync, isn't uploaded
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, ".trash", "old"), 0744))
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".trash", "old", "c.txt"), []byte("c"), 0644))
+
+	require.NoError(t, a.sync(context.Background(), SyncOptions{LocalRootPath: root, RemotePath: "/"}, nil))
+	require.Empty(t, *ops)
+
+	// the trashed file isn't downloaded
+	content, err := os.ReadFile(filepath.Join(root, "a.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "remote /a.txt", string(content))
+	_, err = os.Stat(filepath.Join(root, ".trash", "entry"))
+	require.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,157 @@
+package sdk
+
+import (
+	"io"
+	"net/http"
+	"path"
+
+	"github.com/0chain/gosdk/constants"
+	"github.com/0chain/gosdk/zboxcore/fileref"
+	l "github.com/0chain/gosdk/zboxcore/logger"
+	"go.uber.org/zap"
+)
+
+// dedupReadSize is the size of the buffer used to hash the content of a deduplicated upload.
+const dedupReadSize = 64 * 1024
+
+// dedupMultiOperation commits the copy of a deduplicated single upload.
+var dedupMultiOperation = (*Allocation).DoMultiOperation
+
+// DedupOperation uploads a new file by copying an identical file of the allocation.
+type DedupOperation struct {
+	*CopyOperation
+	chunkedUpload *ChunkedUpload
+	srcPath       string
+}
+
+func (do *DedupOperation) Completed(allocObj *Allocation) {
+	do.CopyOperation.Completed(allocObj)
+	su := do.chunkedUpload
+	do.cleanup()
+	l.Logger.Info("Upload deduplicated", zap.String("path", su.fileMeta.RemotePath), zap.String("source", do.srcPath))
+	if su.statusCallback != nil {
+		su.statusCallback.Completed(allocObj.ID, su.fileMeta.RemotePath, su.fileMeta.RemoteName, su.fileMeta.MimeType, int(su.fileMeta.ActualSize), su.opCode)
+	}
+}
+
+func (do *DedupOperation) Error(allocObj *Allocation, consensus int, err error) {
+	do.CopyOperation.Error(allocObj, consensus, err)
+	su := do.chunkedUpload
+	do.cleanup()
+	if su.statusCallback != nil {
+		su.statusCallback.Error(allocObj.ID, su.fileMeta.RemotePath, su.opCode, err)
+	}
+}
+
+func (do *DedupOperation) cleanup() {
+	su := do.chunkedUpload
+	if su.progressStorer != nil {
+		su.removeProgress()
+	}
+	cancelLock.Lock()
+	delete(CancelOpCtx, su.fileMeta.RemotePath)
+	cancelLock.Unlock()
+}
+
+// deduplicateUpload replaces the upload operation by a copy of an identical file
+// of the allocation when the upload has deduplication on and such a file exists.
+func (mo *MultiOperation) deduplicateUpload(uo *UploadOperation) Operationer {
+	su := uo.chunkedUpload
+	srcPath, ok := su.findDuplicate()
+	if !ok {
+		return uo
+	}
+	return &DedupOperation{
+		CopyOperation: NewCopyOperation(srcPath, path.Dir(su.fileMeta.RemotePath), mo.operationMask, mo.maskMU, mo.consensusThresh, mo.fullconsensus, mo.ctx),
+		chunkedUpload: su,
+		srcPath:       srcPath,
+	}
+}
+
+// findDuplicate looks for a file of the allocation with the same name and content as
+// the new file to upload. Deduplication is skipped for updates, repairs, resumed
+// uploads and readers which can't be rewound.
+func (su *ChunkedUpload) findDuplicate() (string, bool) {
+	if !su.deduplicate || su.isRepair || su.httpMethod != http.MethodPost || su.progress.ChunkIndex >= 0 ||
+		su.fileMeta.ActualSize <= 0 || !su.allocationObj.CanCopy() {
+		return "", false
+	}
+
+	hash := su.fileMeta.ActualHash
+	if hash == "" {
+		rs, ok := su.fileReader.(io.ReadSeeker)
+		if !ok {
+			return "", false
+		}
+		var err error
+		if hash, err = readerContentHash(rs); err != nil {
+			l.Logger.Error("Upload deduplication: failed to hash the content", zap.String("path", su.fileMeta.RemotePath), zap.Error(err))
+			return "", false
+		}
+	}
+
+	metas, err := su.allocationObj.GetFileMetaByName(su.fileMeta.RemoteName)
+	if err != nil {
+		// no file with that name
+		return "", false
+	}
+	for _, meta := range metas {
+		if meta.Type == fileref.FILE && meta.Hash == hash && meta.ActualFileSize == su.fileMeta.ActualSize &&
+			(meta.EncryptedKey != "") == su.encryptOnUpload && meta.Path != su.fileMeta.RemotePath {
+			return meta.Path, true
+		}
+	}
+	return "", false
+}
+
+// copyDuplicate completes a single upload by copying the identical file srcPath.
+func (su *ChunkedUpload) copyDuplicate(srcPath string) error {
+	err := dedupMultiOperation(su.allocationObj, []OperationRequest{{
+		OperationType: constants.FileOperationCopy,
+		RemotePath:    srcPath,
+		DestPath:      path.Dir(su.fileMeta.RemotePath),
+	}})
+	if su.progressStorer != nil {
+		su.removeProgress()
+	}
+	if err != nil {
+		if su.statusCallback != nil {
+			su.statusCallback.Error(su.allocationObj.ID, su.fileMeta.RemotePath, su.opCode, err)
+		}
+		return err
+	}
+	l.Logger.Info("Upload deduplicated", zap.String("path", su.fileMeta.RemotePath), zap.String("source", srcPath))
+	if su.statusCallback != nil {
+		su.statusCallback.Completed(su.allocationObj.ID, su.fileMeta.RemotePath, su.fileMeta.RemoteName, su.fileMeta.MimeType, int(su.fileMeta.ActualSize), su.opCode)
+	}
+	return nil
+}
+
+// readerContentHash computes the actual hash of the remaining content of the reader
+// with a file hasher, then rewinds the reader to its current offset.
+func readerContentHash(rs io.ReadSeeker) (string, error) {
+	offset, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", err
+	}
+	h := CreateFileHasher()
+	buf := make([]byte, dedupReadSize)
+	for {
+		n, err := rs.Read(buf)
+		if n > 0 {
+			if err := h.WriteToFile(buf[:n]); err != nil {
+				return "", err
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	if _, err := rs.Seek(offset, io.SeekStart); err != nil {
+		return "", err
+	}
+	return h.GetFileHash()
+}
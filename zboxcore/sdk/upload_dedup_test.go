@@ -0,0 +1,141 @@
+package sdk
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"testing"
+
+	"github.com/0chain/gosdk/constants"
+	"github.com/0chain/gosdk/zboxcore/fileref"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReaderContentHash(t *testing.T) {
+	r := bytes.NewReader([]byte("xxhello"))
+	_, err := r.Seek(2, io.SeekStart)
+	require.NoError(t, err)
+
+	hash, err := readerContentHash(r)
+	require.NoError(t, err)
+	require.Equal(t, "5d41402abc4b2a76b9719d911017c592", hash)
+
+	rest, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(rest))
+}
+
+func TestFindDuplicate_Skipped(t *testing.T) {
+	newUpload := func() *ChunkedUpload {
+		return &ChunkedUpload{
+			allocationObj: &Allocation{},
+			deduplicate:   true,
+			httpMethod:    http.MethodPost,
+			fileReader:    bytes.NewReader([]byte("hello")),
+			fileMeta:      FileMeta{ActualSize: 5, RemoteName: "a.txt", RemotePath: "/a.txt"},
+			progress:      UploadProgress{ChunkIndex: -1},
+		}
+	}
+
+	su := newUpload()
+	su.deduplicate = false
+	_, ok := su.findDuplicate()
+	require.False(t, ok)
+
+	su = newUpload()
+	su.httpMethod = http.MethodPut
+	_, ok = su.findDuplicate()
+	require.False(t, ok)
+
+	su = newUpload()
+	su.progress.ChunkIndex = 3
+	_, ok = su.findDuplicate()
+	require.False(t, ok)
+
+	su = newUpload()
+	su.fileReader = io.LimitReader(bytes.NewReader([]byte("hello")), 5)
+	su.allocationObj.FileOptions = 63
+	_, ok = su.findDuplicate()
+	require.False(t, ok)
+}
+
+func newDedupUpload(a *Allocation, remotePath string) *ChunkedUpload {
+	return &ChunkedUpload{
+		allocationObj: a,
+		deduplicate:   true,
+		httpMethod:    http.MethodPost,
+		fileReader:    bytes.NewReader([]byte("hello")),
+		fileMeta:      FileMeta{ActualSize: 5, RemoteName: path.Base(remotePath), RemotePath: remotePath},
+		progress:      UploadProgress{ChunkIndex: -1},
+	}
+}
+
+func TestFindDuplicate_Match(t *testing.T) {
+	const helloHash = "5d41402abc4b2a76b9719d911017c592"
+	ref := func(p, hash string, size int64, encryptedKey string) ORef {
+		var r ORef
+		r.Path, r.Type, r.ActualFileHash, r.ActualFileSize, r.EncryptedKey = p, fileref.FILE, hash, size, encryptedKey
+		r.FileMetaHash = "meta " + p
+		return r
+	}
+	blobbers := &directoryTransferBlobbers{refs: []ORef{
+		ref("/other/a.txt", "other hash", 5, ""),
+		ref("/bigger/a.txt", helloHash, 6, ""),
+		ref("/encrypted/a.txt", helloHash, 5, "key"),
+		ref("/src/a.txt", helloHash, 5, ""),
+		ref("/src/b.txt", helloHash, 5, ""),
+	}}
+	a := setupDirectoryTransferAllocation(t, blobbers)
+
+	srcPath, ok := newDedupUpload(a, "/dst/a.txt").findDuplicate()
+	require.True(t, ok)
+	require.Equal(t, "/src/a.txt", srcPath)
+
+	// the file itself isn't a duplicate
+	_, ok = newDedupUpload(a, "/src/a.txt").findDuplicate()
+	require.False(t, ok)
+
+	_, ok = newDedupUpload(a, "/dst/c.txt").findDuplicate()
+	require.False(t, ok)
+}
+
+func fakeDedupMultiOperation(t *testing.T, err error) *[]OperationRequest {
+	var ops []OperationRequest
+	prev := dedupMultiOperation
+	t.Cleanup(func() { dedupMultiOperation = prev })
+	dedupMultiOperation = func(a *Allocation, operations []OperationRequest, _ ...MultiOperationOption) error {
+		ops = append(ops, operations...)
+		return err
+	}
+	return &ops
+}
+
+func TestCopyDuplicate(t *testing.T) {
+	copyOp := []OperationRequest{
+		{OperationType: constants.FileOperationCopy, RemotePath: "/src/a.txt", DestPath: "/dst"},
+	}
+
+	t.Run("copied", func(t *testing.T) {
+		ops := fakeDedupMultiOperation(t, nil)
+		cb := &recordingStatusCallback{}
+		su := newDedupUpload(&Allocation{ID: mockAllocationId}, "/dst/a.txt")
+		su.statusCallback = cb
+
+		require.NoError(t, su.copyDuplicate("/src/a.txt"))
+		require.Equal(t, copyOp, *ops)
+		require.Equal(t, []string{"completed /dst/a.txt 5"}, cb.calls)
+	})
+
+	t.Run("copy failed", func(t *testing.T) {
+		ops := fakeDedupMultiOperation(t, errors.New("copy failed"))
+		cb := &recordingStatusCallback{}
+		su := newDedupUpload(&Allocation{ID: mockAllocationId}, "/dst/a.txt")
+		su.statusCallback = cb
+
+		require.EqualError(t, su.copyDuplicate("/src/a.txt"), "copy failed")
+		require.Equal(t, copyOp, *ops)
+		require.Equal(t, []string{"error /dst/a.txt"}, cb.calls)
+	})
+}
@@ -0,0 +1,260 @@
+package sdk
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/0chain/errors"
+	"github.com/0chain/gosdk/constants"
+	"github.com/0chain/gosdk/core/pathutil"
+	"github.com/0chain/gosdk/core/sys"
+	"github.com/0chain/gosdk/zboxcore/fileref"
+	"github.com/0chain/gosdk/zboxcore/logger"
+	"github.com/0chain/gosdk/zboxcore/zboxutil"
+	"go.uber.org/zap"
+)
+
+// uploadDirectoryMultiOperation commits the operations of a directory upload.
+var uploadDirectoryMultiOperation = (*Allocation).DoMultiOperation
+
+// UploadDirectoryOptions holds the parameters of Allocation.UploadDirectory.
+type UploadDirectoryOptions struct {
+	// Workdir is the working directory where the upload progress is stored.
+	Workdir string
+
+	// Encrypt turns on encryption for the uploaded files.
+	Encrypt bool
+
+	// ThumbnailPaths maps the local path of a file to the local path of its thumbnail.
+	ThumbnailPaths map[string]string
+
+	// ChunkNumber is the number of chunks sent per request, the default is used if 0.
+	ChunkNumber int
+
+	// Overwrite updates the files already present in the remote directory instead of failing.
+	Overwrite bool
+
+	// IgnoreRules excludes matching local paths, relative to the local directory.
+	IgnoreRules *pathutil.IgnoreRules
+
+	// StatusCallback receives the status of every uploaded file.
+	StatusCallback StatusCallback
+}
+
+// UploadDirectory uploads the local directory tree into the remote directory.
+// Remote directories are created first, then files are uploaded in multi-operation
+// batches of MultiOpBatchSize. When progress saving is enabled, the files of each
+// committed batch are recorded in a manifest under the working directory, so that
+// an interrupted upload skips them when restarted with the same arguments, while
+// partially uploaded files resume from their chunked upload progress. The files of
+// a batch committed but not recorded are found in the remote directory: they are
+// skipped if their content is the same, and updated otherwise.
+//   - ctx: the context of the upload, used to stop it between batches.
+//   - localDir: the local directory to upload.
+//   - remoteDir: the remote directory to upload into. It's created if missing.
+//   - opts: the upload options.
+func (a *Allocation) UploadDirectory(ctx context.Context, localDir, remoteDir string, opts UploadDirectoryOptions) error {
+	if !a.isInitialized() {
+		return notInitialized
+	}
+	if !a.CanUpload() {
+		return constants.ErrFileOptionNotPermitted
+	}
+	if opts.Overwrite && !a.CanUpdate() {
+		return constants.ErrFileOptionNotPermitted
+	}
+	remoteDir = zboxutil.RemoteClean(remoteDir)
+	if !zboxutil.IsRemoteAbs(remoteDir) {
+		return errors.New("invalid_path", "Path should be valid and absolute")
+	}
+	localDir = filepath.Clean(localDir)
+	info, err := sys.Files.Stat(localDir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return errors.New("invalid_path", localDir+" is not a directory")
+	}
+
+	dirs, files, err := walkUploadDirectory(localDir, remoteDir, opts.IgnoreRules)
+	if err != nil {
+		return err
+	}
+
+	progress := loadDirectoryProgress(a.directoryProgressID("upload", localDir, remoteDir, opts.Workdir))
+
+	var dirOps []OperationRequest
+	for _, d := range dirs {
+		dirOps = append(dirOps, OperationRequest{
+			OperationType: constants.FileOperationCreateDir,
+			RemotePath:    d,
+		})
+	}
+	if err := uploadDirectoryMultiOperation(a, dirOps); err != nil {
+		return err
+	}
+
+	var existing map[string]FileInfo
+	if opts.Overwrite || progress.resumed {
+		existing, err = a.GetRemoteFileMap(nil, remoteDir)
+		if err != nil {
+			return err
+		}
+	}
+	// the manifest is saved before the first batch, so that a restart after
+	// its commit knows to check the remote files
+	saveDirectoryProgress(progress)
+	remoteRoot := strings.TrimRight(remoteDir, "/")
+
+	var pending []uploadDirectoryFile
+	for _, f := range files {
+		if done, ok := progress.Completed[f.remotePath]; ok && done == f.state {
+			continue
+		}
+		if remote, ok := existing[strings.TrimPrefix(f.remotePath, remoteRoot)]; ok && remote.Type == fileref.FILE {
+			if progress.resumed {
+				hash, err := calcFileHash(f.localPath)
+				if err != nil {
+					return err
+				}
+				if hash == remote.Hash {
+					// committed by the interrupted upload
+					progress.Completed[f.remotePath] = f.state
+					continue
+				}
+			}
+			f.isUpdate = true
+		}
+		pending = append(pending, f)
+	}
+
+	for start := 0; start < len(pending); start += MultiOpBatchSize {
+		if contextCanceled(ctx) {
+			return ctx.Err()
+		}
+		end := start + MultiOpBatchSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+		batch := pending[start:end]
+		if err := a.uploadDirectoryBatch(batch, opts); err != nil {
+			return err
+		}
+		for _, f := range batch {
+			progress.Completed[f.remotePath] = f.state
+		}
+		saveDirectoryProgress(progress)
+	}
+
+	removeDirectoryProgress(progress)
+	return nil
+}
+
+type uploadDirectoryFile struct {
+	localPath  string
+	remotePath string
+	state      LocalIndexEntry
+	isUpdate   bool
+}
+
+// walkUploadDirectory lists the remote directories to create and the files to
+// upload for a local directory tree.
+func walkUploadDirectory(localDir, remoteDir string, ignore *pathutil.IgnoreRules) ([]string, []uploadDirectoryFile, error) {
+	var (
+		dirs  []string
+		files []uploadDirectoryFile
+	)
+	err := filepath.Walk(localDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(localDir, p)
+		if err != nil {
+			return err
+		}
+		rel = "/" + filepath.ToSlash(rel)
+		if rel == "/." {
+			dirs = append(dirs, remoteDir)
+			return nil
+		}
+		if ignore.Match(rel, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		remotePath := path.Join(remoteDir, rel)
+		if info.IsDir() {
+			dirs = append(dirs, remotePath)
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		files = append(files, uploadDirectoryFile{
+			localPath:  p,
+			remotePath: remotePath,
+			state: LocalIndexEntry{
+				Size:    info.Size(),
+				ModTime: info.ModTime().UnixNano(),
+				Inode:   fileInode(info),
+			},
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return dirs, files, nil
+}
+
+func (a *Allocation) uploadDirectoryBatch(batch []uploadDirectoryFile, opts UploadDirectoryOptions) error {
+	ops := make([]OperationRequest, 0, len(batch))
+	defer func() {
+		for _, op := range ops {
+			if f, ok := op.FileReader.(*os.File); ok {
+				f.Close() //nolint: errcheck
+			}
+		}
+	}()
+
+	for _, f := range batch {
+		uploadOpts := []ChunkedUploadOption{
+			WithEncrypt(opts.Encrypt),
+			WithStatusCallback(opts.StatusCallback),
+		}
+		if opts.ChunkNumber != 0 {
+			uploadOpts = append(uploadOpts, WithChunkNumber(opts.ChunkNumber))
+		}
+		if thumbnailPath := opts.ThumbnailPaths[f.localPath]; thumbnailPath != "" {
+			buf, err := sys.Files.ReadFile(thumbnailPath)
+			if err != nil {
+				return err
+			}
+			uploadOpts = append(uploadOpts, WithThumbnail(buf))
+		}
+
+		operationType := constants.FileOperationInsert
+		if f.isUpdate {
+			operationType = constants.FileOperationUpdate
+		}
+		_, op, err := openUploadOperation(OperationRequest{
+			OperationType: operationType,
+			LocalPath:     f.localPath,
+			RemotePath:    f.remotePath,
+		}, opts.Workdir, uploadOpts...)
+		if err != nil {
+			return err
+		}
+		ops = append(ops, op)
+	}
+
+	if err := uploadDirectoryMultiOperation(a, ops); err != nil {
+		logger.Logger.Error("upload directory batch failed", zap.Int("files", len(ops)), zap.Error(err))
+		return err
+	}
+	return nil
+}
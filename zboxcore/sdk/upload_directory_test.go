@@ -0,0 +1,137 @@
+package sdk
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/0chain/gosdk/constants"
+	"github.com/0chain/gosdk/core/pathutil"
+	"github.com/0chain/gosdk/zboxcore/fileref"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalkUploadDirectory(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "docs", "empty"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "node_modules", "lib"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a.txt"), []byte("a"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "docs", "b.md"), []byte("bb"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "docs", "b.tmp"), []byte("tmp"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "node_modules", "lib", "x.js"), []byte("x"), 0644))
+
+	rules, err := pathutil.NewIgnoreRules("node_modules/", "*.tmp")
+	require.NoError(t, err)
+
+	dirs, files, err := walkUploadDirectory(root, "/backup", rules)
+	require.NoError(t, err)
+	require.Equal(t, []string{"/backup", "/backup/docs", "/backup/docs/empty"}, dirs)
+	require.Len(t, files, 2)
+	require.Equal(t, "/backup/a.txt", files[0].remotePath)
+	require.Equal(t, filepath.Join(root, "a.txt"), files[0].localPath)
+	require.Equal(t, int64(1), files[0].state.Size)
+	require.Equal(t, "/backup/docs/b.md", files[1].remotePath)
+}
+
+// fakeUploadDirectoryMultiOperation records the committed batches as "operation path" lists.
+func fakeUploadDirectoryMultiOperation(t *testing.T) *[][]string {
+	var batches [][]string
+	prev := uploadDirectoryMultiOperation
+	t.Cleanup(func() { uploadDirectoryMultiOperation = prev })
+	uploadDirectoryMultiOperation = func(a *Allocation, operations []OperationRequest, _ ...MultiOperationOption) error {
+		var batch []string
+		for _, op := range operations {
+			batch = append(batch, op.OperationType+" "+op.RemotePath)
+		}
+		batches = append(batches, batch)
+		return nil
+	}
+	return &batches
+}
+
+func TestAllocation_UploadDirectory(t *testing.T) {
+	prevBatchSize := MultiOpBatchSize
+	t.Cleanup(func() { MultiOpBatchSize = prevBatchSize })
+	MultiOpBatchSize = 2
+
+	root := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), []byte(name), 0644))
+	}
+	hashA, err := calcFileHash(filepath.Join(root, "a.txt"))
+	require.NoError(t, err)
+	remoteFile := func(p, hash string) ORef {
+		var r ORef
+		r.Path, r.Type, r.ActualFileHash = p, fileref.FILE, hash
+		r.FileMetaHash = "meta " + p
+		return r
+	}
+	createDir := []string{constants.FileOperationCreateDir + " /backup"}
+	insert := func(p string) string { return constants.FileOperationInsert + " " + p }
+	update := func(p string) string { return constants.FileOperationUpdate + " " + p }
+
+	t.Run("batches", func(t *testing.T) {
+		a := setupDirectoryTransferAllocation(t, &directoryTransferBlobbers{})
+		batches := fakeUploadDirectoryMultiOperation(t)
+		opts := UploadDirectoryOptions{Workdir: t.TempDir()}
+
+		require.NoError(t, a.UploadDirectory(context.Background(), root, "/backup", opts))
+		require.Equal(t, [][]string{
+			createDir,
+			{insert("/backup/a.txt"), insert("/backup/b.txt")},
+			{insert("/backup/c.txt")},
+		}, *batches)
+		// the manifest of a complete upload is removed
+		require.False(t, loadDirectoryProgress(a.directoryProgressID("upload", root, "/backup", opts.Workdir)).resumed)
+	})
+
+	t.Run("recorded files skipped", func(t *testing.T) {
+		a := setupDirectoryTransferAllocation(t, &directoryTransferBlobbers{})
+		batches := fakeUploadDirectoryMultiOperation(t)
+		opts := UploadDirectoryOptions{Workdir: t.TempDir()}
+		_, files, err := walkUploadDirectory(root, "/backup", nil)
+		require.NoError(t, err)
+		progress := loadDirectoryProgress(a.directoryProgressID("upload", root, "/backup", opts.Workdir))
+		progress.Completed["/backup/a.txt"] = files[0].state
+		saveDirectoryProgress(progress)
+
+		require.NoError(t, a.UploadDirectory(context.Background(), root, "/backup", opts))
+		require.Equal(t, [][]string{
+			createDir,
+			{insert("/backup/b.txt"), insert("/backup/c.txt")},
+		}, *batches)
+	})
+
+	t.Run("committed batch not recorded", func(t *testing.T) {
+		// the upload stopped after committing a.txt and b.txt, then b.txt changed locally
+		a := setupDirectoryTransferAllocation(t, &directoryTransferBlobbers{refs: []ORef{
+			remoteFile("/backup/a.txt", hashA),
+			remoteFile("/backup/b.txt", "previous hash"),
+		}})
+		batches := fakeUploadDirectoryMultiOperation(t)
+		opts := UploadDirectoryOptions{Workdir: t.TempDir()}
+		saveDirectoryProgress(loadDirectoryProgress(a.directoryProgressID("upload", root, "/backup", opts.Workdir)))
+
+		require.NoError(t, a.UploadDirectory(context.Background(), root, "/backup", opts))
+		require.Equal(t, [][]string{
+			createDir,
+			{update("/backup/b.txt"), insert("/backup/c.txt")},
+		}, *batches)
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		a := setupDirectoryTransferAllocation(t, &directoryTransferBlobbers{refs: []ORef{
+			remoteFile("/backup/a.txt", hashA),
+		}})
+		batches := fakeUploadDirectoryMultiOperation(t)
+		opts := UploadDirectoryOptions{Workdir: t.TempDir(), Overwrite: true}
+
+		require.NoError(t, a.UploadDirectory(context.Background(), root, "/backup", opts))
+		require.Equal(t, [][]string{
+			createDir,
+			{update("/backup/a.txt"), insert("/backup/b.txt")},
+			{insert("/backup/c.txt")},
+		}, *batches)
+	})
+}
@@ -1,5 +1,12 @@
 package sdk
 
+import (
+	"os"
+	"path"
+
+	"github.com/0chain/gosdk/zboxcore/zboxutil"
+)
+
 type UploadFileMeta struct {
 	// Name remote file name
 	Name string
@@ -23,3 +30,36 @@ type UploadResult struct {
 	Hash       string `json:"content_hash,omitempty"`
 	MerkleRoot string `json:"merkle_root,omitempty"`
 }
+
+// openUploadOperation opens the local file of an insert or update operation
+// request and fills in its file meta, working directory and upload options.
+// The returned file must be closed by the caller once the operation is done.
+func openUploadOperation(op OperationRequest, workdir string, uploadOpts ...ChunkedUploadOption) (*os.File, OperationRequest, error) {
+	fileReader, err := os.Open(op.LocalPath)
+	if err != nil {
+		return nil, op, err
+	}
+	fileInfo, err := fileReader.Stat()
+	if err != nil {
+		fileReader.Close() //nolint: errcheck
+		return nil, op, err
+	}
+	_, fileName := path.Split(op.RemotePath)
+	mimeType, err := zboxutil.GetFileContentType(path.Ext(fileName), fileReader)
+	if err != nil {
+		fileReader.Close() //nolint: errcheck
+		return nil, op, err
+	}
+
+	op.FileReader = fileReader
+	op.Workdir = workdir
+	op.FileMeta = FileMeta{
+		Path:       op.LocalPath,
+		ActualSize: fileInfo.Size(),
+		MimeType:   mimeType,
+		RemoteName: fileName,
+		RemotePath: op.RemotePath,
+	}
+	op.Opts = uploadOpts
+	return fileReader, op, nil
+}
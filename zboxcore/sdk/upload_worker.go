@@ -39,7 +39,9 @@ func (uo *UploadOperation) Process(allocObj *Allocation, connectionID string) ([
 			}
 		}
 	}
+	_, span := uo.chunkedUpload.startSpan()
 	err := uo.chunkedUpload.process()
+	zboxutil.EndSpan(span, err)
 	if err != nil {
 		l.Logger.Error("UploadOperation Failed", zap.String("name", uo.chunkedUpload.fileMeta.RemoteName), zap.Error(err))
 		return nil, uo.chunkedUpload.uploadMask, err
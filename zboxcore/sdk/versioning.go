@@ -0,0 +1,280 @@
+package sdk
+
+import (
+	"path"
+	"sort"
+	"time"
+
+	"github.com/0chain/errors"
+	"github.com/0chain/gosdk/constants"
+	"github.com/0chain/gosdk/zboxcore/fileref"
+	"github.com/0chain/gosdk/zboxcore/logger"
+	"github.com/0chain/gosdk/zboxcore/zboxutil"
+	"go.uber.org/zap"
+)
+
+// VersionsDir is the hidden remote directory keeping the previous revisions of
+// the files of an allocation with versioning enabled. The revision of a file
+// /a/b.txt saved at version id V is stored at /.versions/a/b.txt/V/b.txt.
+const VersionsDir = "/.versions"
+
+// versionIDLayout is the time layout of the version ids, sortable as strings.
+const versionIDLayout = "20060102T150405.000000000Z"
+
+// versionMultiOperation commits the operations restoring a version.
+var versionMultiOperation = (*Allocation).DoMultiOperation
+
+// VersioningOptions holds the retention policy of the file versions.
+type VersioningOptions struct {
+	// MaxVersions is the maximum number of versions kept per file, 0 for unlimited.
+	MaxVersions int
+
+	// MaxAge is the maximum age of the versions kept, 0 for unlimited.
+	MaxAge time.Duration
+}
+
+// FileVersion is a previous revision of a file.
+type FileVersion struct {
+	// ID identifies the version among the versions of the file.
+	ID string `json:"id"`
+
+	// Path is the path of the versioned file.
+	Path string `json:"path"`
+
+	// VersionPath is the remote path where the revision is stored.
+	VersionPath string `json:"version_path"`
+
+	// Size is the actual size of the revision.
+	Size int64 `json:"size"`
+
+	// Hash is the actual hash of the revision.
+	Hash string `json:"hash"`
+
+	// CreatedAt is the time the revision was replaced or deleted.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// EnableVersioning turns on file versioning for the allocation. From then on,
+// files updated or deleted through this allocation object are first copied
+// under VersionsDir, and their versions are pruned with the given retention
+// policy. It should not be called while operations are running.
+//   - opts: the retention policy of the versions.
+func (a *Allocation) EnableVersioning(opts VersioningOptions) {
+	a.versioning = &opts
+}
+
+// DisableVersioning turns off file versioning. The existing versions are kept.
+func (a *Allocation) DisableVersioning() {
+	a.versioning = nil
+}
+
+// ListVersions lists the versions of a file, newest first.
+//   - remotePath: the path of the versioned file.
+func (a *Allocation) ListVersions(remotePath string) ([]FileVersion, error) {
+	if !a.isInitialized() {
+		return nil, notInitialized
+	}
+	remotePath = zboxutil.RemoteClean(remotePath)
+	if !zboxutil.IsRemoteAbs(remotePath) {
+		return nil, errors.New("invalid_path", "Path should be valid and absolute")
+	}
+
+	root := path.Join(VersionsDir, remotePath)
+	var versions []FileVersion
+	for ref := range a.ListObjects(a.ctx, root, "", "", "", fileref.FILE, fileref.REGULAR, 0, getRefPageLimit) {
+		if ref.Err != nil {
+			return nil, ref.Err
+		}
+		versionDir := path.Dir(ref.Path)
+		// skip the versions of the files of a directory with the same path
+		if path.Dir(versionDir) != root || path.Base(ref.Path) != path.Base(remotePath) {
+			continue
+		}
+		id := path.Base(versionDir)
+		createdAt, err := time.Parse(versionIDLayout, id)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, FileVersion{
+			ID:          id,
+			Path:        remotePath,
+			VersionPath: ref.Path,
+			Size:        ref.ActualFileSize,
+			Hash:        ref.ActualFileHash,
+			CreatedAt:   createdAt,
+		})
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].ID > versions[j].ID
+	})
+	return versions, nil
+}
+
+// RestoreVersion replaces a file with one of its versions. With versioning enabled,
+// the replaced file is itself kept as a new version.
+//   - remotePath: the path of the versioned file.
+//   - versionID: the id of the version to restore, as returned by ListVersions.
+func (a *Allocation) RestoreVersion(remotePath, versionID string) error {
+	if !a.CanCopy() {
+		return constants.ErrFileOptionNotPermitted
+	}
+	versions, err := a.ListVersions(remotePath)
+	if err != nil {
+		return err
+	}
+	remotePath = zboxutil.RemoteClean(remotePath)
+
+	var version *FileVersion
+	for i := range versions {
+		if versions[i].ID == versionID {
+			version = &versions[i]
+			break
+		}
+	}
+	if version == nil {
+		return errors.New("version_not_found", "version "+versionID+" of "+remotePath+" not found")
+	}
+
+	// the file is replaced in a single commit, so that it's kept if the copy fails
+	var ops []OperationRequest
+	if _, _, err := a.getFileMetaWithMask(remotePath); err == nil {
+		if !a.CanDelete() {
+			return constants.ErrFileOptionNotPermitted
+		}
+		ops = append(ops, OperationRequest{
+			OperationType: constants.FileOperationDelete,
+			RemotePath:    remotePath,
+		})
+	}
+	ops = append(ops, OperationRequest{
+		OperationType: constants.FileOperationCopy,
+		RemotePath:    version.VersionPath,
+		DestPath:      path.Dir(remotePath),
+	})
+	return versionMultiOperation(a, ops)
+}
+
+// PruneVersions deletes the versions of a file exceeding the retention policy.
+//   - remotePath: the path of the versioned file.
+//   - opts: the retention policy.
+func (a *Allocation) PruneVersions(remotePath string, opts VersioningOptions) error {
+	versions, err := a.ListVersions(remotePath)
+	if err != nil {
+		return err
+	}
+	var ops []OperationRequest
+	for _, v := range expiredVersions(versions, opts, time.Now()) {
+		ops = append(ops, OperationRequest{
+			OperationType: constants.FileOperationDelete,
+			RemotePath:    path.Dir(v.VersionPath),
+		})
+	}
+	return a.DoMultiOperation(ops)
+}
+
+// expiredVersions returns the versions, sorted newest first, exceeding the retention policy.
+func expiredVersions(versions []FileVersion, opts VersioningOptions, now time.Time) []FileVersion {
+	var expired []FileVersion
+	for i, v := range versions {
+		if (opts.MaxVersions > 0 && i >= opts.MaxVersions) ||
+			(opts.MaxAge > 0 && now.Sub(v.CreatedAt) > opts.MaxAge) {
+			expired = append(expired, v)
+		}
+	}
+	return expired
+}
+
+// versionedPaths returns the paths of the files replaced or deleted by the operations.
+func versionedPaths(operations []OperationRequest) []string {
+	var paths []string
+	for _, op := range operations {
+		if op.IsRepair || op.Mask != nil {
+			continue
+		}
+		switch op.OperationType {
+		case constants.FileOperationUpdate:
+			p := op.RemotePath
+			if p == "" {
+				p = op.FileMeta.RemotePath
+			}
+			paths = append(paths, p)
+		case constants.FileOperationDelete:
+			paths = append(paths, op.RemotePath)
+		}
+	}
+	return paths
+}
+
+// keepVersions copies the current revision of the given files, or of the files
+// of the given directories, under VersionsDir, then prunes their old versions.
+// It does nothing when versioning is disabled.
+func (a *Allocation) keepVersions(paths []string) error {
+	retention := a.versioning
+	if retention == nil || len(paths) == 0 {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var files []string
+	for _, p := range paths {
+		p = zboxutil.RemoteClean(p)
+		if isHiddenManagedPath(p) {
+			continue
+		}
+		meta, _, err := a.getFileMetaWithMask(p)
+		if err != nil {
+			// nothing to keep
+			continue
+		}
+		if meta.Type != fileref.DIRECTORY {
+			if !seen[p] {
+				seen[p] = true
+				files = append(files, p)
+			}
+			continue
+		}
+		for ref := range a.ListObjects(a.ctx, p, "", "", "", fileref.FILE, fileref.REGULAR, 0, getRefPageLimit) {
+			if ref.Err != nil {
+				return ref.Err
+			}
+			if !seen[ref.Path] && !isHiddenManagedPath(ref.Path) {
+				seen[ref.Path] = true
+				files = append(files, ref.Path)
+			}
+		}
+	}
+	if len(files) == 0 {
+		return nil
+	}
+
+	versionID := time.Now().UTC().Format(versionIDLayout)
+	dirOps := make([]OperationRequest, 0, len(files))
+	copyOps := make([]OperationRequest, 0, len(files))
+	for _, f := range files {
+		versionDir := path.Join(VersionsDir, f, versionID)
+		dirOps = append(dirOps, OperationRequest{
+			OperationType: constants.FileOperationCreateDir,
+			RemotePath:    versionDir,
+		})
+		copyOps = append(copyOps, OperationRequest{
+			OperationType: constants.FileOperationCopy,
+			RemotePath:    f,
+			DestPath:      versionDir,
+		})
+	}
+	if err := a.DoMultiOperation(dirOps); err != nil {
+		return errors.Wrap(err, "failed to create the version directories")
+	}
+	if err := a.DoMultiOperation(copyOps); err != nil {
+		return errors.Wrap(err, "failed to keep the file versions")
+	}
+
+	if retention.MaxVersions > 0 || retention.MaxAge > 0 {
+		for _, f := range files {
+			if err := a.PruneVersions(f, *retention); err != nil {
+				logger.Logger.Error("failed to prune file versions", zap.String("path", f), zap.Error(err))
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package sdk
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/0chain/gosdk/constants"
+	"github.com/0chain/gosdk/zboxcore/fileref"
+	"github.com/0chain/gosdk/zboxcore/zboxutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpiredVersions(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var versions []FileVersion
+	for i := 0; i < 4; i++ {
+		createdAt := now.Add(-time.Duration(i) * 24 * time.Hour)
+		versions = append(versions, FileVersion{
+			ID:        createdAt.Format(versionIDLayout),
+			CreatedAt: createdAt,
+		})
+	}
+
+	require.Empty(t, expiredVersions(versions, VersioningOptions{}, now))
+	require.Equal(t, versions[2:], expiredVersions(versions, VersioningOptions{MaxVersions: 2}, now))
+	require.Equal(t, versions[2:], expiredVersions(versions, VersioningOptions{MaxAge: 36 * time.Hour}, now))
+	require.Equal(t, versions[1:], expiredVersions(versions, VersioningOptions{MaxVersions: 3, MaxAge: 12 * time.Hour}, now))
+
+	// version ids sort in time order
+	require.Greater(t, versions[0].ID, versions[1].ID)
+	parsed, err := time.Parse(versionIDLayout, versions[1].ID)
+	require.NoError(t, err)
+	require.True(t, parsed.Equal(versions[1].CreatedAt))
+}
+
+func TestVersionedPaths(t *testing.T) {
+	mask := zboxutil.NewUint128(1)
+	paths := versionedPaths([]OperationRequest{
+		{OperationType: constants.FileOperationInsert, RemotePath: "/new.txt"},
+		{OperationType: constants.FileOperationUpdate, FileMeta: FileMeta{RemotePath: "/a.txt"}},
+		{OperationType: constants.FileOperationUpdate, RemotePath: "/b.txt", IsRepair: true},
+		{OperationType: constants.FileOperationDelete, RemotePath: "/dir"},
+		{OperationType: constants.FileOperationDelete, RemotePath: "/c.txt", Mask: &mask},
+		{OperationType: constants.FileOperationCopy, RemotePath: "/d.txt", DestPath: "/e"},
+	})
+	require.Equal(t, []string{"/a.txt", "/dir"}, paths)
+}
+
+func TestAllocation_RestoreVersion(t *testing.T) {
+	id := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC).Format(versionIDLayout)
+	var ref ORef
+	ref.Path = "/.versions/docs/a.txt/" + id + "/a.txt"
+	ref.Type = fileref.FILE
+	ref.ActualFileHash = "hash v1"
+	ref.FileMetaHash = "meta v1"
+	blobbers := &directoryTransferBlobbers{
+		refs: []ORef{ref},
+		meta: func(_ int, lookupHash string) string {
+			if lookupHash == fileref.GetReferenceLookup(mockAllocationId, "/docs/a.txt") {
+				return "hash v2"
+			}
+			return ""
+		},
+	}
+	a := setupDirectoryTransferAllocation(t, blobbers)
+
+	var ops [][]OperationRequest
+	prev := versionMultiOperation
+	t.Cleanup(func() { versionMultiOperation = prev })
+	versionMultiOperation = func(a *Allocation, operations []OperationRequest, _ ...MultiOperationOption) error {
+		ops = append(ops, operations)
+		return nil
+	}
+
+	require.EqualError(t, a.RestoreVersion("/docs/a.txt", "unknown"), "version_not_found: version unknown of /docs/a.txt not found")
+	require.Empty(t, ops)
+
+	// the file is deleted and replaced by the version in a single commit
+	require.NoError(t, a.RestoreVersion("/docs/a.txt", id))
+	require.Equal(t, [][]OperationRequest{{
+		{OperationType: constants.FileOperationDelete, RemotePath: "/docs/a.txt"},
+		{OperationType: constants.FileOperationCopy, RemotePath: ref.Path, DestPath: "/docs"},
+	}}, ops)
+}
+
+func TestAllocation_SyncSkipsVersions(t *testing.T) {
+	ref := func(p, refType, hash string) ORef {
+		var r ORef
+		r.Path, r.Type, r.ActualFileHash = p, refType, hash
+		r.FileMetaHash = "meta " + p
+		return r
+	}
+	id := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC).Format(versionIDLayout)
+	blobbers := &directoryTransferBlobbers{refs: []ORef{
+		ref("/a.txt", fileref.FILE, "hash a"),
+		ref(VersionsDir, fileref.DIRECTORY, ""),
+		ref("/.versions/a.txt", fileref.DIRECTORY, ""),
+		ref("/.versions/a.txt/"+id, fileref.DIRECTORY, ""),
+		ref("/.versions/a.txt/"+id+"/a.txt", fileref.FILE, "hash v1"),
+	}}
+	a := setupDirectoryTransferAllocation(t, blobbers)
+	a.EnableVersioning(VersioningOptions{MaxVersions: 2})
+	ops := fakeSyncRemote(t, nil)
+
+	root := t.TempDir()
+	require.NoError(t, a.sync(context.Background(), SyncOptions{LocalRootPath: root, RemotePath: "/"}, nil))
+	require.Empty(t, *ops)
+
+	// only the file is downloaded, its versions stay remote
+	entries, err := os.ReadDir(root)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	content, err := os.ReadFile(filepath.Join(root, "a.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "remote /a.txt", string(content))
+
+	// the listings of the allocation skip the versions too
+	var listed []string
+	for ref := range a.ListObjects(context.Background(), "/", "", "", "", "", fileref.REGULAR, 0, getRefPageLimit) {
+		require.NoError(t, ref.Err)
+		listed = append(listed, ref.Path)
+	}
+	require.Equal(t, []string{"/a.txt"}, listed)
+}
@@ -279,6 +279,8 @@ func (wmMu *WriteMarkerMutex) lockBlobber(
 			maskMu.Unlock()
 		}
 	}()
+	ctx, span := zboxutil.StartSpan(ctx, "writemarker_lock", zboxutil.BlobberSpanAttributes(b.ID, b.Baseurl)...)
+	defer func() { zboxutil.EndSpan(span, err) }()
 
 	var req *http.Request
 	req, err = zboxutil.NewWriteMarkerLockRequest(
@@ -167,7 +167,7 @@ var envProxy proxyFromEnv
 
 func init() {
 	Client = &http.Client{
-		Transport: DefaultTransport,
+		Transport: &blobberTransport{base: DefaultTransport},
 	}
 
 	FastHttpClient = &fasthttp.Client{
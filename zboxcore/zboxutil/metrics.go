@@ -0,0 +1,367 @@
+package zboxutil
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BlobberRequestMetric is the measure of a request sent to a blobber.
+type BlobberRequestMetric struct {
+	// BlobberID is the id of the blobber, empty if its url wasn't registered with RegisterBlobber.
+	BlobberID string
+
+	// BlobberURL is the base url of the blobber.
+	BlobberURL string
+
+	// Endpoint is the name of the blobber endpoint, see BlobberEndpoint.
+	Endpoint string
+
+	// Method is the http method of the request.
+	Method string
+
+	// StatusCode is the http status of the response, 0 if no response was received.
+	StatusCode int
+
+	// ErrorCode is empty for a successful request, otherwise "timeout", "canceled",
+	// "network" or "http_<status>".
+	ErrorCode string
+
+	// Duration is the time from the start of the request to the end of the response body.
+	Duration time.Duration
+
+	// BytesSent is the size of the request body.
+	BytesSent int64
+
+	// BytesReceived is the size of the response body.
+	BytesReceived int64
+}
+
+// BlobberMetrics receives the measures of the requests sent to the blobbers.
+// The applications export them by implementing it on top of their Prometheus
+// registry or OpenTelemetry meter, e.g. with a prometheus.HistogramVec observing
+// Duration with the BlobberID and Endpoint labels, or use a BlobberMetricsRecorder.
+// The implementations must be safe for concurrent use and should not block.
+type BlobberMetrics interface {
+	ObserveBlobberRequest(m BlobberRequestMetric)
+}
+
+var (
+	blobberMetrics atomic.Value // blobberMetricsHolder
+
+	blobberIDsMu sync.RWMutex
+	blobberIDs   = make(map[string]string)
+)
+
+type blobberMetricsHolder struct {
+	m BlobberMetrics
+}
+
+// SetBlobberMetrics sets the receiver of the measures of the blobber requests.
+//   - m: the receiver, nil to turn the instrumentation off.
+func SetBlobberMetrics(m BlobberMetrics) {
+	blobberMetrics.Store(blobberMetricsHolder{m: m})
+}
+
+func getBlobberMetrics() BlobberMetrics {
+	h, _ := blobberMetrics.Load().(blobberMetricsHolder)
+	return h.m
+}
+
+// RegisterBlobber records the id of the blobber with the given base url, so that
+// the measures of its requests are keyed by blobber id.
+//   - baseURL: the base url of the blobber.
+//   - id: the id of the blobber.
+func RegisterBlobber(baseURL, id string) {
+	blobberIDsMu.Lock()
+	blobberIDs[strings.TrimRight(baseURL, "/")] = id
+	blobberIDsMu.Unlock()
+}
+
+// blobberEndpoints maps the paths of the blobber endpoints to their names,
+// the longest paths first.
+var blobberEndpoints = []struct {
+	path string
+	name string
+}{
+	{LATEST_WRITE_MARKER_ENDPOINT, "latest_write_marker"},
+	{CREATE_CONNECTION_ENDPOINT, "create_connection"},
+	{PLAYLIST_LATEST_ENDPOINT, "playlist"},
+	{CALCULATE_HASH_ENDPOINT, "calculate_hash"},
+	{COLLABORATOR_ENDPOINT, "collaborator"},
+	{PLAYLIST_FILE_ENDPOINT, "playlist"},
+	{RECENT_REFS_ENDPOINT, "recent_refs"},
+	{CONNECTION_ENDPOINT, "connection_details"},
+	{REFERENCE_ENDPOINT, "reference_path"},
+	{OBJECT_TREE_ENDPOINT, "object_tree"},
+	{LATEST_READ_MARKER, "latest_read_marker"},
+	{ROLLBACK_ENDPOINT, "rollback"},
+	{WM_LOCK_ENDPOINT, "writemarker_lock"},
+	{DOWNLOAD_ENDPOINT, "download"},
+	{REDEEM_ENDPOINT, "redeem"},
+	{COMMIT_ENDPOINT, "commit"},
+	{SHARE_ENDPOINT, "share"},
+	{UPLOAD_ENDPOINT, "upload"},
+	{RENAME_ENDPOINT, "rename"},
+	{FILE_META_ENDPOINT, "file_meta"},
+	{FILE_STATS_ENDPOINT, "file_stats"},
+	{REFS_ENDPOINT, "refs"},
+	{COPY_ENDPOINT, "copy"},
+	{MOVE_ENDPOINT, "move"},
+	{LIST_ENDPOINT, "list"},
+	{DIR_ENDPOINT, "dir"},
+	{ALLOCATION_ENDPOINT, "allocation"},
+}
+
+// BlobberEndpoint splits the url of a blobber request into the base url of the
+// blobber and the name of the endpoint, "other" for an unknown endpoint.
+//   - rawURL: the url of the request.
+func BlobberEndpoint(rawURL string) (baseURL, endpoint string) {
+	if i := strings.IndexAny(rawURL, "?#"); i >= 0 {
+		rawURL = rawURL[:i]
+	}
+	for _, e := range blobberEndpoints {
+		if i := strings.Index(rawURL, e.path); i > 0 {
+			return rawURL[:i], e.name
+		}
+	}
+	return strings.TrimRight(rawURL, "/"), "other"
+}
+
+// ObserveBlobberRequest records a request sent to a blobber outside of Client, e.g. with
+// FastHttpClient. It does nothing when no BlobberMetrics is set.
+//   - rawURL: the url of the request.
+//   - method: the http method.
+//   - start: the start time of the request.
+//   - statusCode: the http status of the response, 0 if none.
+//   - err: the error of the request.
+//   - sent: the size of the request body.
+//   - received: the size of the response body.
+func ObserveBlobberRequest(rawURL, method string, start time.Time, statusCode int, err error, sent, received int64) {
+	m := getBlobberMetrics()
+	if m == nil {
+		return
+	}
+	m.ObserveBlobberRequest(newBlobberRequestMetric(rawURL, method, start, statusCode, err, sent, received))
+}
+
+func newBlobberRequestMetric(rawURL, method string, start time.Time, statusCode int, err error, sent, received int64) BlobberRequestMetric {
+	baseURL, endpoint := BlobberEndpoint(rawURL)
+	blobberIDsMu.RLock()
+	id := blobberIDs[baseURL]
+	blobberIDsMu.RUnlock()
+	return BlobberRequestMetric{
+		BlobberID:     id,
+		BlobberURL:    baseURL,
+		Endpoint:      endpoint,
+		Method:        method,
+		StatusCode:    statusCode,
+		ErrorCode:     requestErrorCode(statusCode, err),
+		Duration:      time.Since(start),
+		BytesSent:     sent,
+		BytesReceived: received,
+	}
+}
+
+func requestErrorCode(statusCode int, err error) string {
+	if err != nil {
+		var netErr net.Error
+		switch {
+		case errors.Is(err, context.Canceled):
+			return "canceled"
+		case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+			return "timeout"
+		}
+		return "network"
+	}
+	if statusCode >= http.StatusBadRequest {
+		return "http_" + strconv.Itoa(statusCode)
+	}
+	return ""
+}
+
+// blobberTransport propagates the trace context of the requests of Client when a
+// Tracer is set, and measures them when a BlobberMetrics is set.
+type blobberTransport struct {
+	base http.RoundTripper
+}
+
+func (t *blobberTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if tr := getTracer(); tr != nil {
+		// a RoundTripper must not modify the request
+		req = req.Clone(req.Context())
+		tr.Inject(req.Context(), req.Header)
+	}
+	m := getBlobberMetrics()
+	if m == nil {
+		return t.base.RoundTrip(req)
+	}
+	start := time.Now()
+	sent := req.ContentLength
+	if sent < 0 {
+		sent = 0
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.Body == nil {
+		m.ObserveBlobberRequest(newBlobberRequestMetric(req.URL.String(), req.Method, start, 0, err, sent, 0))
+		return resp, err
+	}
+	resp.Body = &metricsBody{
+		ReadCloser: resp.Body,
+		observe: func(received int64, err error) {
+			m.ObserveBlobberRequest(newBlobberRequestMetric(req.URL.String(), req.Method, start, resp.StatusCode, err, sent, received))
+		},
+	}
+	return resp, nil
+}
+
+// metricsBody observes a request when its response body is read or closed.
+type metricsBody struct {
+	io.ReadCloser
+	received int64
+	once     sync.Once
+	observe  func(received int64, err error)
+}
+
+func (b *metricsBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.received += int64(n)
+	if err == io.EOF {
+		b.done(nil)
+	} else if err != nil {
+		b.done(err)
+	}
+	return n, err
+}
+
+func (b *metricsBody) Close() error {
+	b.done(nil)
+	return b.ReadCloser.Close()
+}
+
+func (b *metricsBody) done(err error) {
+	b.once.Do(func() {
+		b.observe(b.received, err)
+	})
+}
+
+// DefaultLatencyBuckets are the upper bounds of the latency histograms of a
+// BlobberMetricsRecorder created without buckets.
+var DefaultLatencyBuckets = []time.Duration{
+	10 * time.Millisecond, 50 * time.Millisecond, 100 * time.Millisecond, 250 * time.Millisecond,
+	500 * time.Millisecond, time.Second, 2500 * time.Millisecond, 5 * time.Second, 10 * time.Second, 30 * time.Second,
+}
+
+// BlobberEndpointStats aggregates the requests sent to an endpoint of a blobber.
+type BlobberEndpointStats struct {
+	BlobberID  string `json:"blobber_id"`
+	BlobberURL string `json:"blobber_url"`
+	Endpoint   string `json:"endpoint"`
+
+	// Requests is the number of requests.
+	Requests int64 `json:"requests"`
+
+	// Errors is the number of failed requests by error code.
+	Errors map[string]int64 `json:"errors"`
+
+	// LatencyBuckets are the upper bounds of the latency histogram.
+	LatencyBuckets []time.Duration `json:"latency_buckets"`
+
+	// LatencyCounts are the cumulative counts of the requests faster than each bucket,
+	// as in a Prometheus histogram.
+	LatencyCounts []int64 `json:"latency_counts"`
+
+	// LatencySum is the total duration of the requests.
+	LatencySum time.Duration `json:"latency_sum"`
+
+	BytesSent     int64 `json:"bytes_sent"`
+	BytesReceived int64 `json:"bytes_received"`
+}
+
+// BlobberMetricsRecorder is a BlobberMetrics aggregating the measures in memory, by
+// blobber and endpoint. Its snapshots can be exported by a Prometheus collector or
+// OpenTelemetry observable instruments.
+type BlobberMetricsRecorder struct {
+	mu      sync.Mutex
+	buckets []time.Duration
+	stats   map[[2]string]*BlobberEndpointStats
+}
+
+// NewBlobberMetricsRecorder creates an in-memory recorder.
+//   - buckets: the upper bounds of the latency histograms, sorted, DefaultLatencyBuckets if empty.
+func NewBlobberMetricsRecorder(buckets []time.Duration) *BlobberMetricsRecorder {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	return &BlobberMetricsRecorder{
+		buckets: buckets,
+		stats:   make(map[[2]string]*BlobberEndpointStats),
+	}
+}
+
+// ObserveBlobberRequest implements BlobberMetrics.
+func (r *BlobberMetricsRecorder) ObserveBlobberRequest(m BlobberRequestMetric) {
+	blobber := m.BlobberID
+	if blobber == "" {
+		blobber = m.BlobberURL
+	}
+	key := [2]string{blobber, m.Endpoint}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.stats[key]
+	if !ok {
+		s = &BlobberEndpointStats{
+			BlobberID:      m.BlobberID,
+			BlobberURL:     m.BlobberURL,
+			Endpoint:       m.Endpoint,
+			Errors:         make(map[string]int64),
+			LatencyBuckets: r.buckets,
+			LatencyCounts:  make([]int64, len(r.buckets)),
+		}
+		r.stats[key] = s
+	}
+	s.Requests++
+	if m.ErrorCode != "" {
+		s.Errors[m.ErrorCode]++
+	}
+	for i, b := range r.buckets {
+		if m.Duration <= b {
+			s.LatencyCounts[i]++
+		}
+	}
+	s.LatencySum += m.Duration
+	s.BytesSent += m.BytesSent
+	s.BytesReceived += m.BytesReceived
+}
+
+// Snapshot returns a copy of the aggregated measures, sorted by blobber and endpoint.
+func (r *BlobberMetricsRecorder) Snapshot() []BlobberEndpointStats {
+	r.mu.Lock()
+	snapshot := make([]BlobberEndpointStats, 0, len(r.stats))
+	for _, s := range r.stats {
+		c := *s
+		c.Errors = make(map[string]int64, len(s.Errors))
+		for code, n := range s.Errors {
+			c.Errors[code] = n
+		}
+		c.LatencyCounts = append([]int64(nil), s.LatencyCounts...)
+		snapshot = append(snapshot, c)
+	}
+	r.mu.Unlock()
+	sort.Slice(snapshot, func(i, j int) bool {
+		if snapshot[i].BlobberURL != snapshot[j].BlobberURL {
+			return snapshot[i].BlobberURL < snapshot[j].BlobberURL
+		}
+		return snapshot[i].Endpoint < snapshot[j].Endpoint
+	})
+	return snapshot
+}
@@ -0,0 +1,88 @@
+package zboxutil
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlobberEndpoint(t *testing.T) {
+	for _, tc := range []struct {
+		url      string
+		base     string
+		endpoint string
+	}{
+		{"https://host/blobber01/v1/file/upload/abc", "https://host/blobber01", "upload"},
+		{"https://host/blobber01/v1/file/refs/recent/abc?offset=1", "https://host/blobber01", "recent_refs"},
+		{"https://host/blobber01/v1/file/refs/abc", "https://host/blobber01", "refs"},
+		{"http://127.0.0.1:5051/v1/writemarker/lock/abc/conn", "http://127.0.0.1:5051", "writemarker_lock"},
+		{"http://127.0.0.1:5051/v1/connection/commit/abc", "http://127.0.0.1:5051", "commit"},
+		{"http://127.0.0.1:5051/_health/", "http://127.0.0.1:5051/_health", "other"},
+	} {
+		base, endpoint := BlobberEndpoint(tc.url)
+		require.Equal(t, tc.base, base, tc.url)
+		require.Equal(t, tc.endpoint, endpoint, tc.url)
+	}
+}
+
+func TestBlobberMetricsRecorder(t *testing.T) {
+	r := NewBlobberMetricsRecorder([]time.Duration{time.Second, 2 * time.Second})
+	r.ObserveBlobberRequest(BlobberRequestMetric{BlobberID: "b1", Endpoint: "upload", Duration: 500 * time.Millisecond, BytesSent: 10})
+	r.ObserveBlobberRequest(BlobberRequestMetric{BlobberID: "b1", Endpoint: "upload", Duration: 1500 * time.Millisecond, ErrorCode: "http_500"})
+	r.ObserveBlobberRequest(BlobberRequestMetric{BlobberID: "b1", Endpoint: "commit", Duration: 3 * time.Second, ErrorCode: "timeout"})
+
+	snapshot := r.Snapshot()
+	require.Len(t, snapshot, 2)
+	commit, upload := snapshot[0], snapshot[1]
+	require.Equal(t, "commit", commit.Endpoint)
+	require.Equal(t, []int64{0, 0}, commit.LatencyCounts)
+	require.Equal(t, map[string]int64{"timeout": 1}, commit.Errors)
+	require.Equal(t, int64(2), upload.Requests)
+	require.Equal(t, []int64{1, 2}, upload.LatencyCounts)
+	require.Equal(t, 2*time.Second, upload.LatencySum)
+	require.Equal(t, int64(10), upload.BytesSent)
+	require.Equal(t, map[string]int64{"http_500": 1}, upload.Errors)
+}
+
+func TestMetricsTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "commit") {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		w.Write([]byte("hello")) //nolint: errcheck
+	}))
+	defer server.Close()
+
+	r := NewBlobberMetricsRecorder(nil)
+	SetBlobberMetrics(r)
+	defer SetBlobberMetrics(nil)
+	RegisterBlobber(server.URL+"/", "b1")
+
+	client := &http.Client{Transport: &blobberTransport{base: http.DefaultTransport}}
+	for _, endpoint := range []string{UPLOAD_ENDPOINT, COMMIT_ENDPOINT} {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, server.URL+endpoint+"alloc", strings.NewReader("data"))
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		_, err = io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	snapshot := r.Snapshot()
+	require.Len(t, snapshot, 2)
+	for _, s := range snapshot {
+		require.Equal(t, "b1", s.BlobberID)
+		require.Equal(t, int64(1), s.Requests)
+		require.Equal(t, int64(4), s.BytesSent)
+		require.Equal(t, int64(5), s.BytesReceived)
+	}
+	require.Equal(t, map[string]int64{"http_400": 1}, snapshot[0].Errors)
+	require.Empty(t, snapshot[1].Errors)
+}
@@ -0,0 +1,249 @@
+package zboxutil
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TraceParentHeader is the W3C trace context header propagated to the blobbers.
+const TraceParentHeader = "traceparent"
+
+// SpanAttribute is a key-value pair describing a span.
+type SpanAttribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Attribute creates a span attribute.
+//   - key: the key of the attribute, e.g. "blobber.id".
+//   - value: the value of the attribute.
+func Attribute(key string, value interface{}) SpanAttribute {
+	return SpanAttribute{Key: key, Value: value}
+}
+
+// BlobberSpanAttributes returns the attributes identifying a blobber in a span.
+//   - id: the id of the blobber.
+//   - baseURL: the base url of the blobber.
+func BlobberSpanAttributes(id, baseURL string) []SpanAttribute {
+	return []SpanAttribute{Attribute("blobber.id", id), Attribute("blobber.url", baseURL)}
+}
+
+// Span is a timed operation of a trace.
+type Span interface {
+	SetAttributes(attrs ...SpanAttribute)
+	RecordError(err error)
+	End()
+}
+
+// TraceCarrier receives the headers carrying the trace context of a request.
+// Both http.Header and *fasthttp.RequestHeader implement it.
+type TraceCarrier interface {
+	Set(key, value string)
+}
+
+// Tracer creates the spans of the sdk operations and propagates their context to the blobbers.
+// It is compatible with OpenTelemetry: an adapter starts the spans with trace.Tracer.Start and
+// injects the context with the propagation.TextMapPropagator of the application, or use a SpanRecorder.
+// The implementations must be safe for concurrent use.
+type Tracer interface {
+	// Start starts a span, child of the span of ctx if any, and returns a context holding it.
+	Start(ctx context.Context, name string, attrs ...SpanAttribute) (context.Context, Span)
+
+	// Inject writes the trace context of the span of ctx into the headers of a request.
+	Inject(ctx context.Context, carrier TraceCarrier)
+}
+
+var tracer atomic.Value // tracerHolder
+
+type tracerHolder struct {
+	t Tracer
+}
+
+// SetTracer sets the tracer of the sdk operations.
+//   - t: the tracer, nil to turn the tracing off.
+func SetTracer(t Tracer) {
+	tracer.Store(tracerHolder{t: t})
+}
+
+func getTracer() Tracer {
+	h, _ := tracer.Load().(tracerHolder)
+	return h.t
+}
+
+// StartSpan starts a span with the tracer set by SetTracer. Without a tracer
+// it returns ctx and a span doing nothing.
+//   - ctx: the context holding the parent span.
+//   - name: the name of the span.
+//   - attrs: the attributes of the span.
+func StartSpan(ctx context.Context, name string, attrs ...SpanAttribute) (context.Context, Span) {
+	t := getTracer()
+	if t == nil {
+		return ctx, noopSpan{}
+	}
+	return t.Start(ctx, name, attrs...)
+}
+
+// EndSpan records err, if not nil, in the span and ends it.
+//   - span: the span to end.
+//   - err: the error of the traced operation.
+func EndSpan(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// InjectTraceContext writes the trace context of ctx into the headers of a
+// request sent outside of Client, e.g. with FastHttpClient.
+//   - ctx: the context holding the span of the request.
+//   - carrier: the headers of the request.
+func InjectTraceContext(ctx context.Context, carrier TraceCarrier) {
+	if t := getTracer(); t != nil {
+		t.Inject(ctx, carrier)
+	}
+}
+
+// WithTraceContext returns a copy of ctx holding the span of traceCtx, with the
+// deadline and the cancellation of ctx.
+//   - ctx: the context to copy.
+//   - traceCtx: the context holding the span.
+func WithTraceContext(ctx, traceCtx context.Context) context.Context {
+	return traceValueContext{Context: ctx, trace: traceCtx}
+}
+
+type traceValueContext struct {
+	context.Context
+	trace context.Context
+}
+
+func (c traceValueContext) Value(key interface{}) interface{} {
+	if v := c.Context.Value(key); v != nil {
+		return v
+	}
+	return c.trace.Value(key)
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...SpanAttribute) {}
+func (noopSpan) RecordError(error)              {}
+func (noopSpan) End()                           {}
+
+// RecordedSpan is a span recorded by a SpanRecorder.
+type RecordedSpan struct {
+	TraceID    string
+	SpanID     string
+	ParentID   string
+	Name       string
+	Start      time.Time
+	End        time.Time
+	Attributes map[string]interface{}
+	Errors     []string
+}
+
+// SpanRecorder is a Tracer keeping the ended spans in memory and propagating
+// them in the W3C traceparent header, for the applications without OpenTelemetry.
+type SpanRecorder struct {
+	mu    sync.Mutex
+	spans []RecordedSpan
+}
+
+// NewSpanRecorder creates a span recorder.
+func NewSpanRecorder() *SpanRecorder {
+	return &SpanRecorder{}
+}
+
+type recorderSpanKey struct{}
+
+// Start starts a span, child of the span of ctx if any.
+func (r *SpanRecorder) Start(ctx context.Context, name string, attrs ...SpanAttribute) (context.Context, Span) {
+	s := &recorderSpan{
+		recorder: r,
+		span: RecordedSpan{
+			SpanID:     randomHex(8),
+			Name:       name,
+			Start:      time.Now(),
+			Attributes: make(map[string]interface{}, len(attrs)),
+		},
+	}
+	if parent, ok := ctx.Value(recorderSpanKey{}).(*recorderSpan); ok {
+		s.span.TraceID, s.span.ParentID = parent.span.TraceID, parent.span.SpanID
+	} else {
+		s.span.TraceID = randomHex(16)
+	}
+	s.SetAttributes(attrs...)
+	return context.WithValue(ctx, recorderSpanKey{}, s), s
+}
+
+// Inject writes the traceparent header of the span of ctx.
+func (r *SpanRecorder) Inject(ctx context.Context, carrier TraceCarrier) {
+	if s, ok := ctx.Value(recorderSpanKey{}).(*recorderSpan); ok {
+		carrier.Set(TraceParentHeader, "00-"+s.span.TraceID+"-"+s.span.SpanID+"-01")
+	}
+}
+
+// Spans returns the ended spans in the order they ended.
+func (r *SpanRecorder) Spans() []RecordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]RecordedSpan(nil), r.spans...)
+}
+
+// Reset drops the recorded spans.
+func (r *SpanRecorder) Reset() {
+	r.mu.Lock()
+	r.spans = nil
+	r.mu.Unlock()
+}
+
+type recorderSpan struct {
+	recorder *SpanRecorder
+	mu       sync.Mutex
+	span     RecordedSpan
+	ended    bool
+}
+
+func (s *recorderSpan) SetAttributes(attrs ...SpanAttribute) {
+	s.mu.Lock()
+	for _, a := range attrs {
+		s.span.Attributes[a.Key] = a.Value
+	}
+	s.mu.Unlock()
+}
+
+func (s *recorderSpan) RecordError(err error) {
+	s.mu.Lock()
+	s.span.Errors = append(s.span.Errors, err.Error())
+	s.mu.Unlock()
+}
+
+func (s *recorderSpan) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.span.End = time.Now()
+	span := s.span
+	span.Attributes = make(map[string]interface{}, len(s.span.Attributes))
+	for k, v := range s.span.Attributes {
+		span.Attributes[k] = v
+	}
+	span.Errors = append([]string(nil), s.span.Errors...)
+	s.mu.Unlock()
+
+	s.recorder.mu.Lock()
+	s.recorder.spans = append(s.recorder.spans, span)
+	s.recorder.mu.Unlock()
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b) //nolint: errcheck
+	return hex.EncodeToString(b)
+}
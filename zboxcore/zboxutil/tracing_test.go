@@ -0,0 +1,100 @@
+package zboxutil
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hitenjain14/fasthttp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartSpanWithoutTracer(t *testing.T) {
+	ctx := context.Background()
+	spanCtx, span := StartSpan(ctx, "op")
+	require.Equal(t, ctx, spanCtx)
+	EndSpan(span, errors.New("ignored"))
+
+	header := http.Header{}
+	InjectTraceContext(spanCtx, header)
+	require.Empty(t, header)
+}
+
+func TestSpanRecorder(t *testing.T) {
+	r := NewSpanRecorder()
+	SetTracer(r)
+	defer SetTracer(nil)
+
+	ctx, parent := StartSpan(context.Background(), "DoMultiOperation", Attribute("allocation.id", "alloc"))
+	childCtx, child := StartSpan(ctx, "commit", BlobberSpanAttributes("b1", "http://b1")...)
+
+	header := &fasthttp.RequestHeader{}
+	InjectTraceContext(childCtx, header)
+
+	EndSpan(child, errors.New("commit_failed"))
+	EndSpan(parent, nil)
+	parent.End()
+
+	spans := r.Spans()
+	require.Len(t, spans, 2)
+	c, p := spans[0], spans[1]
+	require.Equal(t, "commit", c.Name)
+	require.Equal(t, p.TraceID, c.TraceID)
+	require.Equal(t, p.SpanID, c.ParentID)
+	require.Empty(t, p.ParentID)
+	require.Equal(t, "b1", c.Attributes["blobber.id"])
+	require.Equal(t, []string{"commit_failed"}, c.Errors)
+	require.Equal(t, "alloc", p.Attributes["allocation.id"])
+	require.Equal(t, "00-"+c.TraceID+"-"+c.SpanID+"-01", string(header.Peek(TraceParentHeader)))
+
+	r.Reset()
+	require.Empty(t, r.Spans())
+}
+
+func TestWithTraceContext(t *testing.T) {
+	r := NewSpanRecorder()
+	SetTracer(r)
+	defer SetTracer(nil)
+
+	spanCtx, span := StartSpan(context.Background(), "ChunkedUpload")
+	defer span.End()
+	canceledCtx, cancel := context.WithCancel(spanCtx)
+	cancel()
+
+	ctx, cancelCause := context.WithCancelCause(WithTraceContext(context.Background(), canceledCtx))
+	defer cancelCause(nil)
+	require.NoError(t, ctx.Err())
+
+	header := http.Header{}
+	InjectTraceContext(ctx, header)
+	require.NotEmpty(t, header.Get(TraceParentHeader))
+}
+
+func TestBlobberTransportPropagatesTraceContext(t *testing.T) {
+	var traceParent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceParent = r.Header.Get(TraceParentHeader)
+	}))
+	defer server.Close()
+
+	r := NewSpanRecorder()
+	SetTracer(r)
+	defer SetTracer(nil)
+
+	ctx, span := StartSpan(context.Background(), "writemarker_lock")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+WM_LOCK_ENDPOINT+"alloc", nil)
+	require.NoError(t, err)
+
+	client := &http.Client{Transport: &blobberTransport{base: http.DefaultTransport}}
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	span.End()
+
+	require.True(t, strings.HasPrefix(traceParent, "00-"+r.Spans()[0].TraceID))
+	// the request of the caller is not modified
+	require.Empty(t, req.Header.Get(TraceParentHeader))
+}
@@ -0,0 +1,290 @@
+package zcncore
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/0chain/errors"
+	"github.com/0chain/gosdk/core/block"
+	"github.com/0chain/gosdk/core/transaction"
+	"github.com/0chain/gosdk/core/util"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	defaultFeeOracleTTL = 10 * time.Minute
+
+	// feeOracleQueryTimeout bounds a query shared by the concurrent callers.
+	feeOracleQueryTimeout = time.Minute
+)
+
+// FeeStrategy selects the fee of a transaction estimated by a FeeOracle.
+type FeeStrategy int
+
+const (
+	// FeeStrategyMin is the minimum fee accepted by the miners, from the fee table.
+	FeeStrategyMin FeeStrategy = iota
+
+	// FeeStrategyMedian is at least the mean fee of the transactions of the recent blocks.
+	FeeStrategyMedian
+
+	// FeeStrategyFast is at least the maximum fee of the transactions of the recent blocks.
+	FeeStrategyFast
+)
+
+// FeeOracleOption configures a FeeOracle.
+type FeeOracleOption func(*FeeOracle)
+
+// WithFeeOracleTTL sets the duration the fee table and the fee stats are cached for.
+//   - ttl: the cache duration.
+func WithFeeOracleTTL(ttl time.Duration) FeeOracleOption {
+	return func(o *FeeOracle) {
+		if ttl > 0 {
+			o.ttl = ttl
+		}
+	}
+}
+
+// FeeOracle estimates the fee of the transactions from the fee table of the miners, falling back
+// to the fee stats of the recent blocks if the table is unavailable. Both are cached, so that the
+// miners aren't queried for every transaction. It's safe for concurrent use.
+type FeeOracle struct {
+	ttl time.Duration
+
+	// guard protects the cache, the concurrent fetches are deduplicated by fetches
+	guard   sync.Mutex
+	table   map[string]map[string]int64
+	tableAt time.Time
+	stats   *block.FeeStats
+	statsAt time.Time
+	fetches singleflight.Group
+
+	getTable func(ctx context.Context) (map[string]map[string]int64, error)
+	getStats func(ctx context.Context) (*block.FeeStats, error)
+	now      func() time.Time
+}
+
+var defaultFeeOracle = NewFeeOracle()
+
+// NewFeeOracle creates a fee oracle querying the miners of the sdk config.
+//   - opts: the options of the oracle.
+func NewFeeOracle(opts ...FeeOracleOption) *FeeOracle {
+	o := &FeeOracle{
+		ttl: defaultFeeOracleTTL,
+		getTable: func(ctx context.Context) (map[string]map[string]int64, error) {
+			return transaction.GetFeesTableContext(ctx, _config.chain.Miners, 0.2)
+		},
+		getStats: getFeeStats,
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Invalidate clears the cached fee table and fee stats.
+func (o *FeeOracle) Invalidate() {
+	o.guard.Lock()
+	defer o.guard.Unlock()
+	o.table, o.stats = nil, nil
+}
+
+func (o *FeeOracle) feesTable(ctx context.Context) (map[string]map[string]int64, error) {
+	o.guard.Lock()
+	table, tableAt := o.table, o.tableAt
+	o.guard.Unlock()
+	if table != nil && o.now().Sub(tableAt) < o.ttl {
+		return table, nil
+	}
+	v, err := o.fetch(ctx, "table", func(ctx context.Context) (interface{}, error) {
+		table, err := o.getTable(ctx)
+		if err != nil {
+			return nil, err
+		}
+		o.guard.Lock()
+		o.table, o.tableAt = table, o.now()
+		o.guard.Unlock()
+		return table, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(map[string]map[string]int64), nil
+}
+
+func (o *FeeOracle) feeStats(ctx context.Context) (*block.FeeStats, error) {
+	o.guard.Lock()
+	stats, statsAt := o.stats, o.statsAt
+	o.guard.Unlock()
+	if stats != nil && o.now().Sub(statsAt) < o.ttl {
+		return stats, nil
+	}
+	v, err := o.fetch(ctx, "stats", func(ctx context.Context) (interface{}, error) {
+		stats, err := o.getStats(ctx)
+		if err != nil {
+			return nil, err
+		}
+		o.guard.Lock()
+		o.stats, o.statsAt = stats, o.now()
+		o.guard.Unlock()
+		return stats, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*block.FeeStats), nil
+}
+
+// fetch runs the query of the key once for the concurrent callers, each of them
+// waiting for its result until its own ctx is done. The shared query isn't
+// canceled with the ctx of the caller starting it, so that it doesn't fail the
+// other callers.
+func (o *FeeOracle) fetch(ctx context.Context, key string, query func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	shared := func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), feeOracleQueryTimeout)
+		defer cancel()
+		return query(ctx)
+	}
+	select {
+	case r := <-o.fetches.DoChan(key, shared):
+		return r.Val, r.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Estimate returns the fee of the transaction for the strategy.
+//   - ctx: the context of the queries to the miners.
+//   - txn: the transaction, its data must be a transaction.SmartContractTxnData.
+//   - strategy: the fee strategy.
+func (o *FeeOracle) Estimate(ctx context.Context, txn *transaction.Transaction, strategy FeeStrategy) (uint64, error) {
+	table, err := o.feesTable(ctx)
+	var fee uint64
+	if err == nil {
+		fee, err = transaction.LookupFee(table, txn)
+	}
+	if err == nil && strategy == FeeStrategyMin {
+		return fee, nil
+	}
+
+	stats, serr := o.feeStats(ctx)
+	if serr != nil {
+		if err != nil {
+			return 0, err
+		}
+		logging.Info("fee stats unavailable, using the minimum fee: ", serr)
+		return fee, nil
+	}
+	if err != nil {
+		logging.Info("fee table unavailable, using the fee stats: ", err)
+	}
+
+	var statsFee uint64
+	switch strategy {
+	case FeeStrategyMin:
+		statsFee = uint64(stats.MinFees)
+	case FeeStrategyMedian:
+		statsFee = uint64(stats.MeanFees)
+	case FeeStrategyFast:
+		statsFee = uint64(stats.MaxFees)
+	default:
+		return 0, errors.Newf("fee_oracle", "unknown fee strategy %d", strategy)
+	}
+	if statsFee > fee {
+		fee = statsFee
+	}
+	return fee, nil
+}
+
+// WithFeeStrategy estimates the fee of the transaction with the strategy, FeeStrategyMin by default.
+//   - strategy: the fee strategy.
+func WithFeeStrategy(strategy FeeStrategy) FeeOption {
+	return func(o *TxnFeeOption) {
+		o.strategy = strategy
+	}
+}
+
+// WithFeeOracle estimates the fee of the transaction with the oracle instead of the default one.
+//   - oracle: the fee oracle.
+func WithFeeOracle(oracle *FeeOracle) FeeOption {
+	return func(o *TxnFeeOption) {
+		o.oracle = oracle
+	}
+}
+
+func (o *TxnFeeOption) estimateFee(txn *transaction.Transaction) (uint64, error) {
+	oracle := o.oracle
+	if oracle == nil {
+		oracle = defaultFeeOracle
+	}
+	return oracle.Estimate(context.Background(), txn, o.strategy)
+}
+
+// PreviewSmartContractFee returns the fee which would be set to a smart contract transaction
+// created with the same arguments, without creating nor signing it.
+//   - address: the smart contract address.
+//   - methodName: the smart contract method.
+//   - input: the input of the method.
+//   - opts: the fee options, e.g. WithFeeStrategy.
+func PreviewSmartContractFee(address, methodName string, input interface{}, opts ...FeeOption) (uint64, error) {
+	snBytes, err := json.Marshal(transaction.SmartContractTxnData{Name: methodName, InputArgs: input})
+	if err != nil {
+		return 0, errors.Wrap(err, "create smart contract failed due to invalid data")
+	}
+	txn := &transaction.Transaction{
+		TransactionType: transaction.TxnTypeSmartContract,
+		ToClientID:      address,
+		TransactionData: string(snBytes),
+	}
+
+	tf := &TxnFeeOption{}
+	for _, opt := range opts {
+		opt(tf)
+	}
+	if tf.noEstimateFee {
+		return 0, nil
+	}
+	return tf.estimateFee(txn)
+}
+
+func getFeeStats(ctx context.Context) (b *block.FeeStats, err error) {
+
+	var numMiners = 4
+
+	if numMiners > len(_config.chain.Miners) {
+		numMiners = len(_config.chain.Miners)
+	}
+
+	var result = make(chan *util.GetResponse, numMiners)
+
+	queryFromMinersContext(ctx, numMiners, GET_FEE_STATS, result)
+	var rsp *util.GetResponse
+
+loop:
+	for i := 0; i < numMiners; i++ {
+		select {
+		case x := <-result:
+			if x.StatusCode != http.StatusOK {
+				continue
+			}
+			rsp = x
+			if rsp != nil {
+				break loop
+			}
+		case <-ctx.Done():
+			err = ctx.Err()
+			return nil, err
+		}
+	}
+	if rsp == nil {
+		return nil, errors.New("http_request_failed", "Request failed with status not 200")
+	}
+	if err = json.Unmarshal([]byte(rsp.Body), &b); err != nil {
+		return nil, err
+	}
+	return
+}
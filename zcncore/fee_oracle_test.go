@@ -0,0 +1,137 @@
+package zcncore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/0chain/gosdk/core/block"
+	"github.com/0chain/gosdk/core/transaction"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeeOracleEstimate(t *testing.T) {
+	now := time.Now()
+	var tableCalls, statsCalls int
+	var tableErr error
+	o := NewFeeOracle(WithFeeOracleTTL(time.Minute))
+	o.now = func() time.Time { return now }
+	o.getTable = func(ctx context.Context) (map[string]map[string]int64, error) {
+		tableCalls++
+		if tableErr != nil {
+			return nil, tableErr
+		}
+		return map[string]map[string]int64{
+			StorageSmartContractAddress: {"new_allocation_request": 50},
+		}, nil
+	}
+	o.getStats = func(ctx context.Context) (*block.FeeStats, error) {
+		statsCalls++
+		return &block.FeeStats{MinFees: 10, MeanFees: 80, MaxFees: 200}, nil
+	}
+
+	data, err := json.Marshal(transaction.SmartContractTxnData{Name: "new_allocation_request"})
+	require.NoError(t, err)
+	txn := &transaction.Transaction{ToClientID: StorageSmartContractAddress, TransactionData: string(data)}
+
+	for strategy, want := range map[FeeStrategy]uint64{
+		FeeStrategyMin:    50,
+		FeeStrategyMedian: 80,
+		FeeStrategyFast:   200,
+	} {
+		fee, err := o.Estimate(context.Background(), txn, strategy)
+		require.NoError(t, err)
+		require.Equal(t, want, fee)
+	}
+	require.Equal(t, 1, tableCalls)
+	require.Equal(t, 1, statsCalls)
+
+	// the cache expired and the miners don't return the fee table
+	now = now.Add(2 * time.Minute)
+	tableErr = errors.New("no miner")
+	fee, err := o.Estimate(context.Background(), txn, FeeStrategyMin)
+	require.NoError(t, err)
+	require.Equal(t, uint64(10), fee)
+	require.Equal(t, 2, tableCalls)
+	require.Equal(t, 2, statsCalls)
+
+	o.getStats = func(ctx context.Context) (*block.FeeStats, error) {
+		return nil, errors.New("no stats")
+	}
+	o.Invalidate()
+	_, err = o.Estimate(context.Background(), txn, FeeStrategyFast)
+	require.EqualError(t, err, "no miner")
+}
+
+func TestFeeOracleConcurrentFetch(t *testing.T) {
+	var calls int32
+	started, release := make(chan struct{}), make(chan struct{})
+	o := NewFeeOracle()
+	o.getTable = func(ctx context.Context) (map[string]map[string]int64, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+		}
+		<-release
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return map[string]map[string]int64{StorageSmartContractAddress: {"new_allocation_request": 50}}, nil
+	}
+
+	type result struct {
+		table map[string]map[string]int64
+		err   error
+	}
+	results := make(chan result, 5)
+	query := func(ctx context.Context) {
+		table, err := o.feesTable(ctx)
+		results <- result{table, err}
+	}
+	// the caller starting the query gives up with its context
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	go query(firstCtx)
+	<-started
+
+	// the lock isn't held during the query
+	o.Invalidate()
+	for i := 0; i < 4; i++ {
+		go query(context.Background())
+	}
+	cancelFirst()
+	first := <-results
+	require.ErrorIs(t, first.err, context.Canceled)
+
+	// the shared query isn't canceled with it
+	close(release)
+	for i := 0; i < 4; i++ {
+		r := <-results
+		require.NoError(t, r.err)
+		require.Equal(t, int64(50), r.table[StorageSmartContractAddress]["new_allocation_request"])
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestPreviewSmartContractFee(t *testing.T) {
+	o := NewFeeOracle()
+	o.getTable = func(ctx context.Context) (map[string]map[string]int64, error) {
+		return map[string]map[string]int64{
+			StorageSmartContractAddress: {"new_allocation_request": 50},
+			"transfer":                  {"transfer": 5},
+		}, nil
+	}
+
+	fee, err := PreviewSmartContractFee(StorageSmartContractAddress, "new_allocation_request", nil, WithFeeOracle(o))
+	require.NoError(t, err)
+	require.Equal(t, uint64(50), fee)
+
+	fee, err = PreviewSmartContractFee(MinerSmartContractAddress, "transfer", nil, WithFeeOracle(o))
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), fee)
+
+	fee, err = PreviewSmartContractFee(StorageSmartContractAddress, "new_allocation_request", nil, WithFeeOracle(o), WithNoEstimateFee())
+	require.NoError(t, err)
+	require.Zero(t, fee)
+}
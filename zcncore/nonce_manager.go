@@ -0,0 +1,461 @@
+//go:build !mobile
+// +build !mobile
+
+package zcncore
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0chain/errors"
+	"github.com/0chain/gosdk/core/common"
+	"github.com/0chain/gosdk/core/transaction"
+)
+
+const (
+	// defaultNonceStuckAfter is the time after which a pending transaction is resubmitted,
+	// the miners drop the transactions older than defaultTxnExpirationSeconds.
+	defaultNonceStuckAfter = defaultTxnExpirationSeconds * time.Second
+	// defaultNonceFeeBump is the fee increase of a resubmitted transaction.
+	defaultNonceFeeBump = 0.1
+	// defaultNonceMaxAttempts is the number of submissions of a transaction before giving up.
+	defaultNonceMaxAttempts = 5
+)
+
+// NonceManagerOption configures a NonceManager.
+type NonceManagerOption func(*NonceManager)
+
+// WithNonceStuckAfter sets the time after which a pending transaction is re-signed and resubmitted.
+//   - d: the time since the last submission, one minute by default.
+func WithNonceStuckAfter(d time.Duration) NonceManagerOption {
+	return func(m *NonceManager) {
+		m.stuckAfter = d
+	}
+}
+
+// WithNonceFeeBump sets the fee increase of the resubmitted transactions.
+//   - bump: the fraction of the fee added on each resubmission, 0.1 by default.
+func WithNonceFeeBump(bump float64) NonceManagerOption {
+	return func(m *NonceManager) {
+		m.feeBump = bump
+	}
+}
+
+// WithNonceMaxAttempts sets the number of submissions of a transaction before the manager gives up on it.
+//   - n: the number of submissions, 5 by default.
+func WithNonceMaxAttempts(n int) NonceManagerOption {
+	return func(m *NonceManager) {
+		m.maxAttempts = n
+	}
+}
+
+// WithNonceGapFiller sets the transaction filling a nonce gap, left by a transaction the miners
+// rejected after the submission of the next nonces. By default it's a transfer of 0 tokens to the wallet.
+//   - filler: creates the transaction with the fee set, the manager sets its nonce and signs it.
+func WithNonceGapFiller(filler func(nonce int64) (*transaction.Transaction, error)) NonceManagerOption {
+	return func(m *NonceManager) {
+		m.gapFiller = filler
+	}
+}
+
+// NonceManager allocates the nonces of the transactions of a wallet, so that many goroutines can submit
+// transactions concurrently, and persists the pending transactions in a NonceStore.
+// Reconcile, or Run, compares them with the nonce of the wallet on the chain: the included transactions
+// are dropped, the stuck ones are re-signed and resubmitted with a bumped fee and the gaps are filled.
+type NonceManager struct {
+	clientID    string
+	store       NonceStore
+	stuckAfter  time.Duration
+	feeBump     float64
+	maxAttempts int
+	gapFiller   func(nonce int64) (*transaction.Transaction, error)
+
+	// the chain and the wallet, replaced by the tests
+	getNonce func(clientID string) (int64, error)
+	submit   func(ctx context.Context, txn *transaction.Transaction) (string, error)
+	confirm  func(ctx context.Context, r *Receipt, chainLength int64) error
+	sign     func(txn *transaction.Transaction) error
+	now      func() time.Time
+
+	guard sync.Mutex
+	// next is the next nonce to allocate, 0 when it must be synced with the chain
+	next    int64
+	pending map[int64]*PendingTxn
+	// allocating are the nonces of the transactions being signed and persisted
+	allocating map[int64]bool
+	// confirming are the last submissions of the transactions confirmed by SubmitAndConfirm
+	confirming map[int64]*transaction.Transaction
+}
+
+// NewNonceManager creates the nonce manager of the wallet of the sdk and loads its pending transactions.
+//   - store: the store of the pending transactions, e.g. NewFileNonceStore.
+//   - opts: the options of the manager.
+func NewNonceManager(store NonceStore, opts ...NonceManagerOption) (*NonceManager, error) {
+	if err := CheckConfig(); err != nil {
+		return nil, err
+	}
+	m := newNonceManager(_config.wallet.ClientID, store, opts...)
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func newNonceManager(clientID string, store NonceStore, opts ...NonceManagerOption) *NonceManager {
+	m := &NonceManager{
+		clientID:    clientID,
+		store:       store,
+		stuckAfter:  defaultNonceStuckAfter,
+		feeBump:     defaultNonceFeeBump,
+		maxAttempts: defaultNonceMaxAttempts,
+		getNonce:    GetWalletNonce,
+		submit:      submitTransaction,
+		confirm:     confirmTransaction,
+		sign: func(txn *transaction.Transaction) error {
+			return txn.ComputeHashAndSign(SignFn)
+		},
+		now:        time.Now,
+		pending:    make(map[int64]*PendingTxn),
+		allocating: make(map[int64]bool),
+		confirming: make(map[int64]*transaction.Transaction),
+	}
+	m.gapFiller = m.transferToSelf
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+func (m *NonceManager) load() error {
+	list, err := m.store.Load(m.clientID)
+	if err != nil {
+		return errors.Wrap(err, "failed to load the pending transactions")
+	}
+	m.guard.Lock()
+	defer m.guard.Unlock()
+	for _, p := range list {
+		m.pending[p.Nonce] = p
+	}
+	return nil
+}
+
+// Pending returns the pending transactions sorted by nonce.
+func (m *NonceManager) Pending() []*PendingTxn {
+	m.guard.Lock()
+	defer m.guard.Unlock()
+	list := make([]*PendingTxn, 0, len(m.pending))
+	for _, p := range m.pending {
+		cp := *p
+		list = append(list, &cp)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Nonce < list[j].Nonce })
+	return list
+}
+
+// Submit allocates the next nonce of the wallet to the transaction, signs it, persists it as
+// pending and sends it to the miners. If the miners reject the nonce, e.g. because the wallet
+// was used by another application, the nonce is synced with the chain and the transaction
+// submitted again once. If the submission fails without a rejection, e.g. on a timeout, the
+// miners may have accepted the transaction, so it stays pending and Reconcile drops or
+// resubmits it. The transaction fee must be set.
+//   - ctx: the context of the submission.
+//   - txn: the transaction, its nonce, creation date and signature are overwritten.
+//
+// returns the receipt of the transaction accepted by a miner, or an error.
+func (m *NonceManager) Submit(ctx context.Context, txn *transaction.Transaction) (*Receipt, error) {
+	for attempt := 0; ; attempt++ {
+		nonce, err := m.allocate(txn)
+		if err != nil {
+			return nil, err
+		}
+		out, err := m.submit(ctx, txn)
+		if err == nil {
+			return &Receipt{
+				Hash:         txn.Hash,
+				Nonce:        nonce,
+				SubmitOutput: out,
+				Transaction:  txn,
+			}, nil
+		}
+		if !errors.Is(err, ErrTxnRejected) {
+			return nil, err
+		}
+		resync := isNonceError(err)
+		m.release(nonce, resync)
+		if !resync || attempt > 0 {
+			return nil, err
+		}
+	}
+}
+
+// SubmitAndConfirm submits the transaction like Submit and waits for its confirmation like
+// TransactionClient.SubmitAndConfirm, then drops it from the pending transactions. If the
+// transaction is resubmitted by Reconcile meanwhile, the resubmitted copy is confirmed, and
+// the receipt is the one of the copy.
+func (m *NonceManager) SubmitAndConfirm(ctx context.Context, txn *transaction.Transaction, confirmations int) (*Receipt, error) {
+	r, err := m.Submit(ctx, txn)
+	if err != nil {
+		return nil, err
+	}
+	m.guard.Lock()
+	m.confirming[r.Nonce] = r.Transaction
+	m.guard.Unlock()
+	defer func() {
+		m.guard.Lock()
+		delete(m.confirming, r.Nonce)
+		m.guard.Unlock()
+	}()
+
+	chainLength := int64(confirmations)
+	if chainLength <= 0 {
+		chainLength = getMinRequiredChainLength()
+	}
+	for confirming := r.Transaction; ; {
+		err = m.confirm(ctx, r, chainLength)
+		if !errors.Is(err, ErrTxnExpired) {
+			break
+		}
+		m.guard.Lock()
+		last := m.confirming[r.Nonce]
+		m.guard.Unlock()
+		if last == confirming {
+			break
+		}
+		// the expired transaction was resubmitted, with another hash
+		confirming = last
+		r.Hash, r.Transaction = last.Hash, last
+	}
+	if r.Status != Undefined {
+		m.guard.Lock()
+		m.dropLocked(r.Nonce)
+		m.guard.Unlock()
+	}
+	return r, err
+}
+
+// allocate sets the next nonce to the transaction, signs it and persists it as pending.
+// Only the nonce is reserved under the lock, the signature and the store may be slow.
+func (m *NonceManager) allocate(txn *transaction.Transaction) (int64, error) {
+	nonce, err := m.reserve()
+	if err != nil {
+		return 0, err
+	}
+
+	txn.TransactionNonce = nonce
+	txn.CreationDate = int64(common.Now())
+	txn.Signature = ""
+	if err := m.sign(txn); err != nil {
+		m.release(nonce, false)
+		return 0, newTxnError(ErrTxnSignFailed, "", err)
+	}
+	p := &PendingTxn{Nonce: nonce, Txn: txn, SubmittedAt: m.now(), Attempts: 1}
+	if err := m.store.Save(m.clientID, p); err != nil {
+		m.release(nonce, false)
+		return 0, errors.Wrap(err, "failed to persist the pending transaction")
+	}
+
+	m.guard.Lock()
+	defer m.guard.Unlock()
+	delete(m.allocating, nonce)
+	m.pending[nonce] = p
+	return nonce, nil
+}
+
+// reserve returns the next nonce, synced with the chain if needed.
+func (m *NonceManager) reserve() (int64, error) {
+	m.guard.Lock()
+	defer m.guard.Unlock()
+	if m.next == 0 {
+		n, err := m.getNonce(m.clientID)
+		if err != nil {
+			return 0, errors.Wrap(err, "failed to get the nonce of the wallet")
+		}
+		m.syncLocked(n)
+	}
+	nonce := m.next
+	m.next++
+	m.allocating[nonce] = true
+	return nonce, nil
+}
+
+// release drops the pending transaction the miners rejected, or which couldn't be submitted.
+// The nonce is allocated again if no later nonce was, otherwise the gap is filled by Reconcile.
+func (m *NonceManager) release(nonce int64, resync bool) {
+	m.guard.Lock()
+	defer m.guard.Unlock()
+	delete(m.allocating, nonce)
+	m.dropLocked(nonce)
+	if nonce == m.next-1 {
+		m.next--
+	}
+	if resync {
+		m.next = 0
+	}
+}
+
+// syncLocked drops the transactions included in the chain up to the nonce n of the wallet
+// and moves the next nonce after n and the pending and allocating transactions.
+func (m *NonceManager) syncLocked(n int64) {
+	for nonce := range m.pending {
+		if nonce <= n {
+			m.dropLocked(nonce)
+		}
+	}
+	if m.next <= n {
+		m.next = n + 1
+	}
+	for nonce := range m.pending {
+		if nonce >= m.next {
+			m.next = nonce + 1
+		}
+	}
+	for nonce := range m.allocating {
+		if nonce >= m.next {
+			m.next = nonce + 1
+		}
+	}
+}
+
+func (m *NonceManager) dropLocked(nonce int64) {
+	if _, ok := m.pending[nonce]; !ok {
+		return
+	}
+	delete(m.pending, nonce)
+	if err := m.store.Delete(m.clientID, nonce); err != nil {
+		logging.Error("failed to delete the pending transaction ", nonce, ": ", err)
+	}
+}
+
+// Reconcile compares the pending transactions with the nonce of the wallet on the chain. The included
+// transactions are dropped, the transactions pending for longer than the stuck time are re-signed and
+// resubmitted with a bumped fee, and the nonces missing before the pending transactions are filled.
+//   - ctx: the context of the resubmissions.
+func (m *NonceManager) Reconcile(ctx context.Context) error {
+	n, err := m.getNonce(m.clientID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get the nonce of the wallet")
+	}
+
+	// the gap fillers are created without the lock, the default one estimates its fee
+	m.guard.Lock()
+	m.syncLocked(n)
+	var gaps []int64
+	for nonce := n + 1; nonce < m.next; nonce++ {
+		if _, ok := m.pending[nonce]; !ok && !m.allocating[nonce] {
+			gaps = append(gaps, nonce)
+		}
+	}
+	m.guard.Unlock()
+	fillers := make(map[int64]*transaction.Transaction, len(gaps))
+	for _, nonce := range gaps {
+		filler, err := m.gapFiller(nonce)
+		if err != nil {
+			return errors.Wrap(err, "failed to create the nonce gap filler")
+		}
+		fillers[nonce] = filler
+	}
+
+	m.guard.Lock()
+	m.syncLocked(n)
+	var resubmit []*PendingTxn
+	for nonce := n + 1; nonce < m.next; nonce++ {
+		p, ok := m.pending[nonce]
+		if ok && m.now().Sub(p.SubmittedAt) < m.stuckAfter {
+			continue
+		}
+		if ok && p.Attempts >= m.maxAttempts {
+			logging.Error("transaction ", p.Txn.Hash, " with nonce ", nonce, " is still pending after ", p.Attempts, " attempts")
+			continue
+		}
+
+		var txn transaction.Transaction
+		if ok {
+			// the submitted transaction may still be used by its sender, resubmit a copy
+			txn = *p.Txn
+			txn.TransactionFee += uint64(math.Max(1, math.Ceil(float64(txn.TransactionFee)*m.feeBump)))
+		} else {
+			filler, ok := fillers[nonce]
+			if !ok {
+				// the gap appeared meanwhile, it's filled by the next reconciliation
+				continue
+			}
+			txn = *filler
+			p = &PendingTxn{Nonce: nonce}
+		}
+		txn.TransactionNonce = nonce
+		txn.CreationDate = int64(common.Now())
+		txn.Signature = ""
+		if err := m.sign(&txn); err != nil {
+			m.guard.Unlock()
+			return newTxnError(ErrTxnSignFailed, "", err)
+		}
+
+		resubmitted := &PendingTxn{Nonce: nonce, Txn: &txn, SubmittedAt: m.now(), Attempts: p.Attempts + 1}
+		if err := m.store.Save(m.clientID, resubmitted); err != nil {
+			m.guard.Unlock()
+			return errors.Wrap(err, "failed to persist the pending transaction")
+		}
+		m.pending[nonce] = resubmitted
+		if _, ok := m.confirming[nonce]; ok {
+			m.confirming[nonce] = &txn
+		}
+		resubmit = append(resubmit, resubmitted)
+	}
+	m.guard.Unlock()
+
+	for _, p := range resubmit {
+		logging.Info("resubmitting transaction ", p.Txn.Hash, " with nonce ", p.Nonce, ", attempt ", p.Attempts)
+		if _, err := m.submit(ctx, p.Txn); err != nil {
+			logging.Error("failed to resubmit transaction ", p.Txn.Hash, ": ", err)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+		}
+	}
+	return nil
+}
+
+// Run reconciles the pending transactions periodically until ctx is done.
+//   - ctx: the context of the manager.
+//   - interval: the time between two reconciliations.
+func (m *NonceManager) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Reconcile(ctx); err != nil {
+				logging.Error("nonce reconciliation failed: ", err)
+			}
+		}
+	}
+}
+
+// transferToSelf creates a transfer of 0 tokens to the wallet, the default nonce gap filler.
+func (m *NonceManager) transferToSelf(nonce int64) (*transaction.Transaction, error) {
+	txn := transaction.NewTransactionEntity(m.clientID, _config.chain.ChainID, _config.wallet.ClientKey, nonce)
+	txnData, err := json.Marshal(transaction.SmartContractTxnData{Name: "transfer", InputArgs: SendTxnData{Note: "nonce gap"}})
+	if err != nil {
+		return nil, err
+	}
+	txn.TransactionType = transaction.TxnTypeSend
+	txn.ToClientID = m.clientID
+	txn.TransactionData = string(txnData)
+	txn.TransactionFee, err = transaction.EstimateFee(txn, _config.chain.Miners, 0.2)
+	if err != nil {
+		return nil, err
+	}
+	return txn, nil
+}
+
+// isNonceError reports whether the miners rejected the transaction because of its nonce.
+func isNonceError(err error) bool {
+	return errors.Is(err, ErrTxnRejected) && strings.Contains(strings.ToLower(err.Error()), "nonce")
+}
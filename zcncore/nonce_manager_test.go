@@ -0,0 +1,263 @@
+package zcncore
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/0chain/gosdk/core/transaction"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeNonceChain struct {
+	mu        sync.Mutex
+	nonce     int64
+	submitted []*transaction.Transaction
+	reject    func(txn *transaction.Transaction) error
+}
+
+func (c *fakeNonceChain) manager(store NonceStore, opts ...NonceManagerOption) *NonceManager {
+	m := newNonceManager("client", store, opts...)
+	m.getNonce = func(string) (int64, error) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return c.nonce, nil
+	}
+	m.sign = func(txn *transaction.Transaction) error {
+		txn.ComputeHashData()
+		txn.Signature = "signature"
+		return nil
+	}
+	m.submit = func(ctx context.Context, txn *transaction.Transaction) (string, error) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.reject != nil {
+			if err := c.reject(txn); err != nil {
+				return "", err
+			}
+		}
+		cp := *txn
+		c.submitted = append(c.submitted, &cp)
+		return "{}", nil
+	}
+	return m
+}
+
+func TestNonceManagerConcurrentSubmit(t *testing.T) {
+	chain := &fakeNonceChain{nonce: 10}
+	store := NewMemoryNonceStore()
+	m := chain.manager(store)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := m.Submit(context.Background(), transaction.NewTransactionEntity("client", "chain", "key", 0))
+			require.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	seen := make(map[int64]bool)
+	for _, txn := range chain.submitted {
+		require.False(t, seen[txn.TransactionNonce])
+		seen[txn.TransactionNonce] = true
+		require.True(t, txn.TransactionNonce > 10 && txn.TransactionNonce <= 30)
+	}
+	require.Len(t, seen, 20)
+	pending, err := store.Load("client")
+	require.NoError(t, err)
+	require.Len(t, pending, 20)
+}
+
+func TestNonceManagerSignsWithoutLock(t *testing.T) {
+	chain := &fakeNonceChain{nonce: 10}
+	m := chain.manager(NewMemoryNonceStore(), WithNonceGapFiller(func(nonce int64) (*transaction.Transaction, error) {
+		return nil, errors.New("unexpected gap")
+	}))
+	sign := m.sign
+	signing, release := make(chan struct{}), make(chan struct{})
+	m.sign = func(txn *transaction.Transaction) error {
+		close(signing)
+		<-release
+		return sign(txn)
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := m.Submit(context.Background(), transaction.NewTransactionEntity("client", "chain", "key", 0))
+		errs <- err
+	}()
+	<-signing
+
+	// the manager isn't locked, and the nonce being signed isn't a gap
+	require.Empty(t, m.Pending())
+	require.NoError(t, m.Reconcile(context.Background()))
+	require.Empty(t, chain.submitted)
+
+	close(release)
+	require.NoError(t, <-errs)
+	require.Len(t, chain.submitted, 1)
+	require.Equal(t, int64(11), chain.submitted[0].TransactionNonce)
+}
+
+func TestNonceManagerConfirmsResubmitted(t *testing.T) {
+	now := time.Now()
+	chain := &fakeNonceChain{nonce: 10}
+	m := chain.manager(NewMemoryNonceStore(), WithNonceStuckAfter(time.Minute))
+	m.now = func() time.Time { return now }
+
+	var confirmed []string
+	m.confirm = func(ctx context.Context, r *Receipt, chainLength int64) error {
+		confirmed = append(confirmed, r.Hash)
+		if len(confirmed) == 1 {
+			// the transaction is stuck and resubmitted while it's confirmed
+			now = now.Add(2 * time.Minute)
+			require.NoError(t, m.Reconcile(ctx))
+			return newTxnError(ErrTxnExpired, r.Hash, nil)
+		}
+		r.Status = Success
+		return nil
+	}
+
+	txn := transaction.NewTransactionEntity("client", "chain", "key", 0)
+	txn.TransactionFee = 100
+	r, err := m.SubmitAndConfirm(context.Background(), txn, 1)
+	require.NoError(t, err)
+	require.Len(t, chain.submitted, 2)
+	resubmitted := chain.submitted[1]
+	require.Equal(t, []string{txn.Hash, resubmitted.Hash}, confirmed)
+	require.Equal(t, resubmitted.Hash, r.Hash)
+	require.Equal(t, uint64(110), r.Transaction.TransactionFee)
+	require.Empty(t, m.Pending())
+}
+
+func TestNonceManagerResyncsUsedNonce(t *testing.T) {
+	chain := &fakeNonceChain{nonce: 10}
+	m := chain.manager(NewMemoryNonceStore())
+	_, err := m.Submit(context.Background(), transaction.NewTransactionEntity("client", "chain", "key", 0))
+	require.NoError(t, err)
+
+	// another application used the wallet
+	chain.nonce = 40
+	chain.reject = func(txn *transaction.Transaction) error {
+		if txn.TransactionNonce <= 40 {
+			return newTxnError(ErrTxnRejected, txn.Hash, errors.New("nonce already used"))
+		}
+		return nil
+	}
+	r, err := m.Submit(context.Background(), transaction.NewTransactionEntity("client", "chain", "key", 0))
+	require.NoError(t, err)
+	require.Equal(t, int64(41), r.Nonce)
+	require.Len(t, m.Pending(), 1)
+
+	// a rejected transaction releases its nonce
+	chain.reject = func(txn *transaction.Transaction) error {
+		return newTxnError(ErrTxnRejected, txn.Hash, errors.New("insufficient balance"))
+	}
+	_, err = m.Submit(context.Background(), transaction.NewTransactionEntity("client", "chain", "key", 0))
+	require.True(t, errors.Is(err, ErrTxnRejected))
+	require.Len(t, m.Pending(), 1)
+	require.Equal(t, int64(42), m.next)
+
+	// a transaction maybe accepted by the miners keeps its nonce
+	chain.reject = func(txn *transaction.Transaction) error {
+		return newTxnError(context.Canceled, txn.Hash, nil)
+	}
+	_, err = m.Submit(context.Background(), transaction.NewTransactionEntity("client", "chain", "key", 0))
+	require.True(t, errors.Is(err, context.Canceled))
+	require.Len(t, m.Pending(), 2)
+	require.Equal(t, int64(43), m.next)
+}
+
+func TestNonceManagerReconcile(t *testing.T) {
+	now := time.Now()
+	chain := &fakeNonceChain{nonce: 10}
+	store := NewMemoryNonceStore()
+	var m *NonceManager
+	m = chain.manager(store,
+		WithNonceStuckAfter(time.Minute),
+		WithNonceMaxAttempts(2),
+		WithNonceGapFiller(func(nonce int64) (*transaction.Transaction, error) {
+			// the filler may query the chain, it runs without the lock of the manager
+			require.NotEmpty(t, m.Pending())
+			txn := transaction.NewTransactionEntity("client", "chain", "key", nonce)
+			txn.TransactionFee = 1
+			return txn, nil
+		}))
+	m.now = func() time.Time { return now }
+
+	for i := 0; i < 4; i++ {
+		txn := transaction.NewTransactionEntity("client", "chain", "key", 0)
+		txn.TransactionFee = 100
+		_, err := m.Submit(context.Background(), txn)
+		require.NoError(t, err)
+	}
+	// the submission of 13 failed while 14 was submitted concurrently
+	m.release(13, false)
+	// 11, 12 and 14 are pending, 13 is a gap
+	require.Len(t, m.Pending(), 3)
+
+	chain.nonce = 11
+	chain.submitted = nil
+	require.NoError(t, m.Reconcile(context.Background()))
+	require.Len(t, chain.submitted, 1)
+	require.Equal(t, int64(13), chain.submitted[0].TransactionNonce)
+	require.Equal(t, uint64(1), chain.submitted[0].TransactionFee)
+
+	// all are stuck
+	now = now.Add(2 * time.Minute)
+	chain.submitted = nil
+	require.NoError(t, m.Reconcile(context.Background()))
+	require.Len(t, chain.submitted, 3)
+	require.Equal(t, int64(12), chain.submitted[0].TransactionNonce)
+	require.Equal(t, uint64(110), chain.submitted[0].TransactionFee)
+	require.Equal(t, uint64(2), chain.submitted[1].TransactionFee)
+	require.Equal(t, int64(14), chain.submitted[2].TransactionNonce)
+
+	// 12 is included, the maximum attempts of 13 and 14 are reached
+	now = now.Add(2 * time.Minute)
+	chain.nonce = 12
+	chain.submitted = nil
+	require.NoError(t, m.Reconcile(context.Background()))
+	require.Empty(t, chain.submitted)
+
+	pending, err := store.Load("client")
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	require.Equal(t, int64(13), pending[0].Nonce)
+	require.Equal(t, 2, pending[0].Attempts)
+}
+
+func TestFileNonceStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileNonceStore(dir)
+	require.NoError(t, err)
+
+	for _, nonce := range []int64{3, 1, 2} {
+		txn := transaction.NewTransactionEntity("client", "chain", "key", nonce)
+		require.NoError(t, store.Save("client", &PendingTxn{Nonce: nonce, Txn: txn, Attempts: 1}))
+	}
+	require.NoError(t, store.Delete("client", 2))
+	require.NoError(t, store.Delete("client", 5))
+
+	// a new store, e.g. after a restart, loads the pending transactions
+	store, err = NewFileNonceStore(dir)
+	require.NoError(t, err)
+	pending, err := store.Load("client")
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	require.Equal(t, int64(1), pending[0].Nonce)
+	require.Equal(t, int64(3), pending[1].Txn.TransactionNonce)
+
+	m := newNonceManager("client", store)
+	require.NoError(t, m.load())
+	require.Len(t, m.Pending(), 2)
+
+	pending, err = store.Load("other")
+	require.NoError(t, err)
+	require.Empty(t, pending)
+}
@@ -0,0 +1,183 @@
+//go:build !mobile
+// +build !mobile
+
+package zcncore
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/0chain/errors"
+	"github.com/0chain/gosdk/core/transaction"
+)
+
+// PendingTxn is a transaction submitted by a NonceManager and not yet included in a block.
+type PendingTxn struct {
+	// Nonce of the transaction.
+	Nonce int64 `json:"nonce"`
+
+	// Txn is the signed transaction.
+	Txn *transaction.Transaction `json:"txn"`
+
+	// SubmittedAt is the time of the last submission of the transaction.
+	SubmittedAt time.Time `json:"submitted_at"`
+
+	// Attempts is the number of submissions of the transaction.
+	Attempts int `json:"attempts"`
+}
+
+// NonceStore persists the pending transactions of a NonceManager, so that they
+// are resubmitted after a restart. The implementations must be safe for concurrent use.
+type NonceStore interface {
+	// Load returns the pending transactions of the client, sorted by nonce.
+	Load(clientID string) ([]*PendingTxn, error)
+
+	// Save adds or replaces the pending transaction of the client with the same nonce.
+	Save(clientID string, p *PendingTxn) error
+
+	// Delete removes the pending transaction of the client with the nonce, if any.
+	Delete(clientID string, nonce int64) error
+}
+
+type memoryNonceStore struct {
+	guard   sync.Mutex
+	pending map[string]map[int64]PendingTxn
+}
+
+// NewMemoryNonceStore creates a nonce store keeping the pending transactions in memory.
+func NewMemoryNonceStore() NonceStore {
+	return &memoryNonceStore{pending: make(map[string]map[int64]PendingTxn)}
+}
+
+func (s *memoryNonceStore) Load(clientID string) ([]*PendingTxn, error) {
+	s.guard.Lock()
+	defer s.guard.Unlock()
+	return sortedPending(s.pending[clientID]), nil
+}
+
+func (s *memoryNonceStore) Save(clientID string, p *PendingTxn) error {
+	s.guard.Lock()
+	defer s.guard.Unlock()
+	if s.pending[clientID] == nil {
+		s.pending[clientID] = make(map[int64]PendingTxn)
+	}
+	s.pending[clientID][p.Nonce] = *p
+	return nil
+}
+
+func (s *memoryNonceStore) Delete(clientID string, nonce int64) error {
+	s.guard.Lock()
+	defer s.guard.Unlock()
+	delete(s.pending[clientID], nonce)
+	return nil
+}
+
+type fileNonceStore struct {
+	guard sync.Mutex
+	dir   string
+}
+
+// NewFileNonceStore creates a nonce store keeping the pending transactions of each client
+// in a json file of the directory. The files are replaced atomically, so that a crash
+// never leaves a partially written file.
+//   - dir: the directory of the files, created if it doesn't exist.
+func NewFileNonceStore(dir string) (NonceStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.Wrap(err, "failed to create the nonce store directory")
+	}
+	return &fileNonceStore{dir: dir}, nil
+}
+
+func (s *fileNonceStore) path(clientID string) string {
+	return filepath.Join(s.dir, clientID+".json")
+}
+
+func (s *fileNonceStore) read(clientID string) (map[int64]PendingTxn, error) {
+	pending := make(map[int64]PendingTxn)
+	b, err := os.ReadFile(s.path(clientID))
+	if os.IsNotExist(err) {
+		return pending, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []PendingTxn
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil, errors.Wrap(err, "failed to decode the pending transactions")
+	}
+	for _, p := range list {
+		pending[p.Nonce] = p
+	}
+	return pending, nil
+}
+
+func (s *fileNonceStore) write(clientID string, pending map[int64]PendingTxn) error {
+	b, err := json.Marshal(sortedPending(pending))
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(s.dir, clientID+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //nolint: errcheck
+	if _, err = tmp.Write(b); err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(clientID))
+}
+
+func (s *fileNonceStore) Load(clientID string) ([]*PendingTxn, error) {
+	s.guard.Lock()
+	defer s.guard.Unlock()
+	pending, err := s.read(clientID)
+	if err != nil {
+		return nil, err
+	}
+	return sortedPending(pending), nil
+}
+
+func (s *fileNonceStore) Save(clientID string, p *PendingTxn) error {
+	s.guard.Lock()
+	defer s.guard.Unlock()
+	pending, err := s.read(clientID)
+	if err != nil {
+		return err
+	}
+	pending[p.Nonce] = *p
+	return s.write(clientID, pending)
+}
+
+func (s *fileNonceStore) Delete(clientID string, nonce int64) error {
+	s.guard.Lock()
+	defer s.guard.Unlock()
+	pending, err := s.read(clientID)
+	if err != nil {
+		return err
+	}
+	if _, ok := pending[nonce]; !ok {
+		return nil
+	}
+	delete(pending, nonce)
+	return s.write(clientID, pending)
+}
+
+func sortedPending(pending map[int64]PendingTxn) []*PendingTxn {
+	list := make([]*PendingTxn, 0, len(pending))
+	for _, p := range pending {
+		p := p
+		list = append(list, &p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Nonce < list[j].Nonce })
+	return list
+}
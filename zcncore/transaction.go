@@ -378,7 +378,7 @@ func (t *Transaction) createSmartContractTxn(address, methodName string, input i
 	}
 
 	// TODO: check if transaction is exempt to avoid unnecessary fee estimation
-	minFee, err := transaction.EstimateFee(t.txn, _config.chain.Miners, 0.2)
+	minFee, err := tf.estimateFee(t.txn)
 	if err != nil {
 		return err
 	}
@@ -1162,85 +1162,26 @@ func (t *Transaction) Verify() error {
 		t.txn.CreationDate = int64(common.Now())
 	}
 
-	tq, err := NewTransactionQuery(Sharders.Healthy(), _config.chain.Miners)
-	if err != nil {
+	if _, err := NewTransactionQuery(Sharders.Healthy(), _config.chain.Miners); err != nil {
 		logging.Error(err)
 		return err
 	}
 
 	go func() {
-
-		for {
-
-			tq.Reset()
-			// Get transaction confirmationBlock from a random sharder
-			confirmBlockHeader, confirmationBlock, lfbBlockHeader, err := tq.getFastConfirmation(context.TODO(), t.txnHash)
-			if err != nil {
-				now := int64(common.Now())
-
-				// maybe it is a network or server error
-				if lfbBlockHeader == nil {
-					logging.Info(err, " now: ", now)
-				} else {
-					logging.Info(err, " now: ", now, ", LFB creation time:", lfbBlockHeader.CreationDate)
-				}
-
-				// transaction is done or expired. it means random sharder might be outdated, try to query it from s/S sharders to confirm it
-				if util.MaxInt64(lfbBlockHeader.getCreationDate(now), now) >= (t.txn.CreationDate + int64(defaultTxnExpirationSeconds)) {
-					logging.Info("falling back to ", getMinShardersVerify(), " of ", len(_config.chain.Sharders), " Sharders")
-					confirmBlockHeader, confirmationBlock, lfbBlockHeader, err = tq.getConsensusConfirmation(context.TODO(), getMinShardersVerify(), t.txnHash)
-				}
-
-				// txn not found in fast confirmation/consensus confirmation
-				if err != nil {
-
-					if lfbBlockHeader == nil {
-						// no any valid lfb on all sharders. maybe they are network/server errors. try it again
-						continue
-					}
-
-					// it is expired
-					if t.isTransactionExpired(lfbBlockHeader.getCreationDate(now), now) {
-						t.completeVerify(StatusError, "", errors.New("", `{"error": "verify transaction failed"}`))
-						return
-					}
-					continue
-				}
-			}
-
-			valid := validateChain(confirmBlockHeader)
-			if valid {
-				output, err := json.Marshal(confirmationBlock)
-				if err != nil {
-					t.completeVerify(StatusError, "", errors.New("", `{"error": "transaction confirmation json marshal error"`))
-					return
-				}
-				confJson := confirmationBlock["confirmation"]
-
-				var conf map[string]json.RawMessage
-				if err := json.Unmarshal(confJson, &conf); err != nil {
-					return
-				}
-				txnJson := conf["txn"]
-
-				tt := transaction.Transaction{}
-				if err := json.Unmarshal(txnJson, &tt); err != nil {
-					return
-				}
-
-				*t.txn = tt
-				txStatus := tt.Status
-
-				switch txStatus {
-				case 1:
-					t.completeVerifyWithConStatus(StatusSuccess, int(Success), string(output), nil)
-				case 2:
-					t.completeVerifyWithConStatus(StatusSuccess, int(ChargeableError), tt.TransactionOutput, nil)
-				default:
-					t.completeVerify(StatusError, string(output), nil)
-				}
-				return
-			}
+		r := &Receipt{Hash: t.txnHash, Transaction: t.txn}
+		err := confirmTransaction(context.Background(), r, getMinRequiredChainLength())
+		if r.Transaction != t.txn {
+			*t.txn = *r.Transaction
+		}
+		switch {
+		case r.Status == Success:
+			t.completeVerifyWithConStatus(StatusSuccess, int(Success), r.Confirmation, nil)
+		case r.Status == ChargeableError:
+			t.completeVerifyWithConStatus(StatusSuccess, int(ChargeableError), r.Transaction.TransactionOutput, nil)
+		case errors.Is(err, ErrTxnExpired):
+			t.completeVerify(StatusError, "", errors.New("", `{"error": "verify transaction failed"}`))
+		default:
+			t.completeVerify(StatusError, r.Confirmation, err)
 		}
 	}()
 	return nil
@@ -1381,42 +1322,9 @@ func GetChainStats(ctx context.Context) (b *block.ChainStats, err error) {
 	return
 }
 
-func GetFeeStats(ctx context.Context) (b *block.FeeStats, err error) {
-
-	var numMiners = 4
-
-	if numMiners > len(_config.chain.Miners) {
-		numMiners = len(_config.chain.Miners)
-	}
-
-	var result = make(chan *util.GetResponse, numMiners)
-
-	queryFromMinersContext(ctx, numMiners, GET_FEE_STATS, result)
-	var rsp *util.GetResponse
-
-loop:
-	for i := 0; i < numMiners; i++ {
-		select {
-		case x := <-result:
-			if x.StatusCode != http.StatusOK {
-				continue
-			}
-			rsp = x
-			if rsp != nil {
-				break loop
-			}
-		case <-ctx.Done():
-			err = ctx.Err()
-			return nil, err
-		}
-	}
-	if rsp == nil {
-		return nil, errors.New("http_request_failed", "Request failed with status not 200")
-	}
-	if err = json.Unmarshal([]byte(rsp.Body), &b); err != nil {
-		return nil, err
-	}
-	return
+// GetFeeStats returns the minimum, mean and maximum fees of the transactions of the recent blocks.
+func GetFeeStats(ctx context.Context) (*block.FeeStats, error) {
+	return getFeeStats(ctx)
 }
 
 func GetBlockByRound(ctx context.Context, numSharders int, round int64) (b *block.Block, err error) {
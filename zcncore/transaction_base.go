@@ -6,7 +6,6 @@ import (
 	stdErrors "errors"
 	"fmt"
 	"net/http"
-	"sync/atomic"
 	"time"
 
 	"github.com/0chain/errors"
@@ -308,13 +307,7 @@ func (t *Transaction) setNonceAndSubmit() {
 }
 
 func (t *Transaction) setNonce() {
-	nonce := t.txn.TransactionNonce
-	if nonce < 1 {
-		nonce = node.Cache.GetNextNonce(t.txn.ClientID)
-	} else {
-		node.Cache.Set(t.txn.ClientID, nonce)
-	}
-	t.txn.TransactionNonce = nonce
+	setTransactionNonce(t.txn)
 }
 
 func (t *Transaction) submitTxn() {
@@ -323,76 +316,12 @@ func (t *Transaction) submitTxn() {
 	t.txnOut = ""
 	t.txnError = nil
 
-	// If Signature is not passed compute signature
-	if t.txn.Signature == "" {
-		err := t.txn.ComputeHashAndSign(SignFn)
-		if err != nil {
-			t.completeTxn(StatusError, "", err)
-			node.Cache.Evict(t.txn.ClientID)
-			return
-		}
-	}
-
-	var (
-		randomMiners = GetStableMiners()
-		minersN      = len(randomMiners)
-		failedCount  int32
-		failC        = make(chan struct{})
-		resultC      = make(chan *util.PostResponse, minersN)
-	)
-
-	for _, miner := range randomMiners {
-		go func(minerurl string) {
-			url := minerurl + PUT_TRANSACTION
-			logging.Info("Submitting ", txnTypeString(t.txn.TransactionType), " transaction to ", minerurl, " with JSON ", string(t.txn.DebugJSON()))
-			req, err := util.NewHTTPPostRequest(url, t.txn)
-			if err != nil {
-				logging.Error(minerurl, " new post request failed. ", err.Error())
-
-				if int(atomic.AddInt32(&failedCount, 1)) == minersN {
-					close(failC)
-				}
-				return
-			}
-
-			res, err := req.Post()
-			if err != nil {
-				logging.Error(minerurl, " submit transaction error. ", err.Error())
-				if int(atomic.AddInt32(&failedCount, 1)) == minersN {
-					close(failC)
-				}
-				return
-			}
-
-			if res.StatusCode != http.StatusOK {
-				logging.Error(minerurl, " submit transaction failed with status code ", res.StatusCode)
-				if int(atomic.AddInt32(&failedCount, 1)) == minersN {
-					resultC <- res
-				}
-				return
-			}
-
-			resultC <- res
-		}(miner)
-	}
-
-	select {
-	case <-failC:
-		logging.Error("failed to submit transaction")
-		t.completeTxn(StatusError, "", fmt.Errorf("failed to submit transaction to all miners"))
-		node.Cache.Evict(t.txn.ClientID)
-		ResetStableMiners()
+	out, err := submitTransaction(context.Background(), t.txn)
+	if err != nil {
+		t.completeTxn(StatusError, "", err)
 		return
-	case ret := <-resultC:
-		logging.Debug("finish txn submitting, ", ret.Url, ", Status: ", ret.Status, ", output:", ret.Body)
-		if ret.StatusCode == http.StatusOK {
-			t.completeTxn(StatusSuccess, ret.Body, nil)
-		} else {
-			t.completeTxn(StatusError, "", fmt.Errorf("submit transaction failed. %s", ret.Body))
-			node.Cache.Evict(t.txn.ClientID)
-			ResetStableMiners()
-		}
 	}
+	t.completeTxn(StatusSuccess, out, nil)
 }
 
 // SetTransactionCallback implements storing the callback
@@ -428,6 +357,10 @@ type TxnFeeOption struct {
 	// estimate the txn fee by calling API from 0chain network. With this option, we could force
 	// the txn to have zero fee for those exempt transactions.
 	noEstimateFee bool
+
+	// strategy and oracle of the fee estimation, see WithFeeStrategy and WithFeeOracle.
+	strategy FeeStrategy
+	oracle   *FeeOracle
 }
 
 // FeeOption represents txn fee related option type
@@ -632,19 +565,31 @@ func isBlockExtends(prevHash string, block *blockHeader) bool {
 }
 
 func validateChain(confirmBlock *blockHeader) bool {
+	return validateChainContext(context.Background(), confirmBlock, getMinRequiredChainLength()) == nil
+}
+
+// validateChainContext waits for chainLength blocks extending the confirmation block, or until ctx is done.
+func validateChainContext(ctx context.Context, confirmBlock *blockHeader, chainLength int64) error {
 	confirmRound := confirmBlock.Round
 	logging.Debug("Confirmation round: ", confirmRound)
 	currentBlockHash := confirmBlock.Hash
 	round := confirmRound + 1
 	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		nextBlock, err := getBlockInfoByRound(round, "header")
 		if err != nil {
 			logging.Info(err, " after a second falling thru to ", getMinShardersVerify(), "of ", len(_config.chain.Sharders), "Sharders", len(Sharders.Healthy()), "Healthy sharders")
-			sys.Sleep(1 * time.Second)
+			if err := sleepContext(ctx, time.Second); err != nil {
+				return err
+			}
 			nextBlock, err = getBlockInfoByRound(round, "header")
 			if err != nil {
 				logging.Error(err, " block chain stalled. waiting", defaultWaitSeconds, "...")
-				sys.Sleep(defaultWaitSeconds)
+				if err := sleepContext(ctx, defaultWaitSeconds); err != nil {
+					return err
+				}
 				continue
 			}
 		}
@@ -652,17 +597,26 @@ func validateChain(confirmBlock *blockHeader) bool {
 			currentBlockHash = nextBlock.Hash
 			round++
 		}
-		if (round > confirmRound) && (round-confirmRound < getMinRequiredChainLength()) {
+		if round-confirmRound < chainLength {
 			continue
 		}
-		if round < confirmRound {
-			return false
-		}
 		// Validation success
-		break
+		return nil
 	}
-	return true
 }
+
+// sleepContext pauses the current goroutine for the duration d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
 func (t *Transaction) isTransactionExpired(lfbCreationTime, currentTime int64) bool {
 	// latest finalized block zero implies no response. use currentTime as lfb
 	if lfbCreationTime == 0 {
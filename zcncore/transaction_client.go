@@ -0,0 +1,192 @@
+//go:build !mobile
+// +build !mobile
+
+package zcncore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/0chain/gosdk/core/common"
+	"github.com/0chain/gosdk/core/transaction"
+	"github.com/0chain/gosdk/core/util"
+)
+
+// Receipt is the result of a transaction submitted by a TransactionClient.
+type Receipt struct {
+	// Hash is the hash of the transaction.
+	Hash string
+
+	// Nonce is the nonce of the transaction.
+	Nonce int64
+
+	// SubmitOutput is the response of the miner which accepted the transaction.
+	SubmitOutput string
+
+	// Transaction is the submitted transaction. Once confirmed, it is the transaction
+	// stored in the block, with its status and output.
+	Transaction *transaction.Transaction
+
+	// Status is the confirmation status of the transaction, Undefined until it's confirmed.
+	Status ConfirmationStatus
+
+	// Round and BlockHash identify the block including the confirmed transaction.
+	Round     int64
+	BlockHash string
+
+	// Confirmation is the json of the confirmation block of the transaction.
+	Confirmation string
+}
+
+// TransactionClient submits and confirms transactions synchronously, honouring the
+// cancellation of their context. It's the blocking counterpart of TransactionScheme,
+// which is kept for the callback based applications.
+type TransactionClient struct{}
+
+// NewTransactionClient creates a transaction client, the sdk must be initialized.
+func NewTransactionClient() (*TransactionClient, error) {
+	if err := CheckConfig(); err != nil {
+		return nil, err
+	}
+	return &TransactionClient{}, nil
+}
+
+// Submit sets the nonce of the transaction if it's not set, signs it if it has no signature and
+// sends it to the miners. The transaction fee must be set.
+//   - ctx: the context of the submission, canceling it stops waiting for the miners.
+//   - txn: the transaction, e.g. created with transaction.NewTransactionEntity.
+//
+// returns the receipt of the transaction accepted by a miner, or a *TxnError.
+func (c *TransactionClient) Submit(ctx context.Context, txn *transaction.Transaction) (*Receipt, error) {
+	if txn.Signature == "" {
+		setTransactionNonce(txn)
+	}
+	out, err := submitTransaction(ctx, txn)
+	if err != nil {
+		return nil, err
+	}
+	return &Receipt{
+		Hash:         txn.Hash,
+		Nonce:        txn.TransactionNonce,
+		SubmitOutput: out,
+		Transaction:  txn,
+	}, nil
+}
+
+// SubmitAndConfirm submits the transaction like Submit and waits until it's included in a
+// block followed by the given number of blocks.
+//   - ctx: the context of the submission and the confirmation.
+//   - txn: the transaction.
+//   - confirmations: the number of blocks to wait for after the block of the transaction,
+//     the confirmation chain length of the sdk config if not positive.
+//
+// returns the receipt of the confirmed transaction, and a *TxnError if the transaction failed,
+// with the receipt if the failure is charged (ErrTxnFailed), or couldn't be confirmed.
+func (c *TransactionClient) SubmitAndConfirm(ctx context.Context, txn *transaction.Transaction, confirmations int) (*Receipt, error) {
+	r, err := c.Submit(ctx, txn)
+	if err != nil {
+		return nil, err
+	}
+	chainLength := int64(confirmations)
+	if chainLength <= 0 {
+		chainLength = getMinRequiredChainLength()
+	}
+	if err := confirmTransaction(ctx, r, chainLength); err != nil {
+		return r, err
+	}
+	return r, nil
+}
+
+// confirmTransaction waits for the confirmation of the transaction of the receipt by the sharders
+// and for chainLength blocks after it, then fills the receipt with the confirmation.
+func confirmTransaction(ctx context.Context, r *Receipt, chainLength int64) error {
+	txn := r.Transaction
+	// If transaction is verify only start from current time
+	if txn.CreationDate == 0 {
+		txn.CreationDate = int64(common.Now())
+	}
+
+	tq, err := NewTransactionQuery(Sharders.Healthy(), _config.chain.Miners)
+	if err != nil {
+		logging.Error(err)
+		return newTxnError(ErrTxnConfirmation, r.Hash, err)
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return newTxnError(err, r.Hash, nil)
+		}
+
+		tq.Reset()
+		// Get transaction confirmationBlock from a random sharder
+		confirmBlockHeader, confirmationBlock, lfbBlockHeader, err := tq.getFastConfirmation(ctx, r.Hash)
+		if err != nil {
+			now := int64(common.Now())
+
+			// maybe it is a network or server error
+			if lfbBlockHeader == nil {
+				logging.Info(err, " now: ", now)
+			} else {
+				logging.Info(err, " now: ", now, ", LFB creation time:", lfbBlockHeader.CreationDate)
+			}
+
+			// transaction is done or expired. it means random sharder might be outdated, try to query it from s/S sharders to confirm it
+			if util.MaxInt64(lfbBlockHeader.getCreationDate(now), now) >= (txn.CreationDate + int64(defaultTxnExpirationSeconds)) {
+				logging.Info("falling back to ", getMinShardersVerify(), " of ", len(_config.chain.Sharders), " Sharders")
+				confirmBlockHeader, confirmationBlock, lfbBlockHeader, err = tq.getConsensusConfirmation(ctx, getMinShardersVerify(), r.Hash)
+			}
+
+			// txn not found in fast confirmation/consensus confirmation
+			if err != nil {
+				// no any valid lfb on all sharders means network/server errors, try it again
+				if lfbBlockHeader != nil &&
+					util.MinInt64(lfbBlockHeader.getCreationDate(now), now) > (txn.CreationDate+int64(defaultTxnExpirationSeconds)) {
+					return newTxnError(ErrTxnExpired, r.Hash, err)
+				}
+				// Wait for next retry
+				if err := sleepContext(ctx, defaultWaitSeconds); err != nil {
+					return newTxnError(err, r.Hash, nil)
+				}
+				continue
+			}
+		}
+
+		if err := validateChainContext(ctx, confirmBlockHeader, chainLength); err != nil {
+			return newTxnError(err, r.Hash, nil)
+		}
+		return fillReceipt(r, confirmBlockHeader, confirmationBlock)
+	}
+}
+
+// fillReceipt fills the receipt with the confirmation block of its transaction.
+func fillReceipt(r *Receipt, header *blockHeader, confirmationBlock map[string]json.RawMessage) error {
+	output, err := json.Marshal(confirmationBlock)
+	if err != nil {
+		return newTxnError(ErrTxnConfirmation, r.Hash, err)
+	}
+	r.Confirmation = string(output)
+	r.Round = header.Round
+	r.BlockHash = header.Hash
+
+	var conf map[string]json.RawMessage
+	if err := json.Unmarshal(confirmationBlock["confirmation"], &conf); err != nil {
+		return newTxnError(ErrTxnConfirmation, r.Hash, err)
+	}
+	tt := &transaction.Transaction{}
+	if err := json.Unmarshal(conf["txn"], tt); err != nil {
+		return newTxnError(ErrTxnConfirmation, r.Hash, err)
+	}
+	r.Transaction = tt
+
+	switch tt.Status {
+	case 1:
+		r.Status = Success
+		return nil
+	case 2:
+		r.Status = ChargeableError
+		return newTxnError(ErrTxnFailed, r.Hash, errors.New(tt.TransactionOutput))
+	default:
+		return newTxnError(ErrTxnFailed, r.Hash, nil)
+	}
+}
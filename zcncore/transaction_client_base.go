@@ -0,0 +1,144 @@
+package zcncore
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/0chain/gosdk/core/node"
+	"github.com/0chain/gosdk/core/transaction"
+	"github.com/0chain/gosdk/core/util"
+)
+
+var (
+	ErrTxnSignFailed   = errors.New("zcn: transaction signing failed")
+	ErrTxnNotSubmitted = errors.New("zcn: failed to submit transaction to all miners")
+	ErrTxnRejected     = errors.New("zcn: transaction rejected by the miners")
+	ErrTxnExpired      = errors.New("zcn: transaction expired before its confirmation")
+	ErrTxnFailed       = errors.New("zcn: transaction failed")
+	ErrTxnConfirmation = errors.New("zcn: invalid transaction confirmation")
+)
+
+// TxnError is the error of a transaction submitted or confirmed by a TransactionClient.
+// Use errors.Is with the ErrTxn errors, or context.Canceled and context.DeadlineExceeded,
+// to check its kind.
+type TxnError struct {
+	// Kind is one of the ErrTxn errors, or the error of the context.
+	Kind error
+
+	// Hash is the hash of the transaction, empty if it wasn't signed.
+	Hash string
+
+	// Err is the cause of the error, may be nil.
+	Err error
+}
+
+func newTxnError(kind error, hash string, err error) *TxnError {
+	return &TxnError{Kind: kind, Hash: hash, Err: err}
+}
+
+func (e *TxnError) Error() string {
+	if e.Err == nil {
+		return e.Kind.Error()
+	}
+	return e.Kind.Error() + ": " + e.Err.Error()
+}
+
+// Is reports whether target is the kind of the error.
+func (e *TxnError) Is(target error) bool {
+	return target == e.Kind
+}
+
+// Unwrap returns the cause of the error.
+func (e *TxnError) Unwrap() error {
+	return e.Err
+}
+
+func setTransactionNonce(txn *transaction.Transaction) {
+	nonce := txn.TransactionNonce
+	if nonce < 1 {
+		nonce = node.Cache.GetNextNonce(txn.ClientID)
+	} else {
+		node.Cache.Set(txn.ClientID, nonce)
+	}
+	txn.TransactionNonce = nonce
+}
+
+// submitTransaction signs the transaction if it has no signature and sends it to the stable miners.
+// It returns the response of the first miner accepting it.
+func submitTransaction(ctx context.Context, txn *transaction.Transaction) (string, error) {
+	// If Signature is not passed compute signature
+	if txn.Signature == "" {
+		err := txn.ComputeHashAndSign(SignFn)
+		if err != nil {
+			node.Cache.Evict(txn.ClientID)
+			return "", newTxnError(ErrTxnSignFailed, "", err)
+		}
+	}
+
+	var (
+		randomMiners = GetStableMiners()
+		minersN      = len(randomMiners)
+		failedCount  int32
+		failC        = make(chan struct{})
+		resultC      = make(chan *util.PostResponse, minersN)
+	)
+
+	for _, miner := range randomMiners {
+		go func(minerurl string) {
+			url := minerurl + PUT_TRANSACTION
+			logging.Info("Submitting ", txnTypeString(txn.TransactionType), " transaction to ", minerurl, " with JSON ", string(txn.DebugJSON()))
+			req, err := util.NewHTTPPostRequestContext(ctx, url, txn)
+			if err != nil {
+				logging.Error(minerurl, " new post request failed. ", err.Error())
+
+				if int(atomic.AddInt32(&failedCount, 1)) == minersN {
+					close(failC)
+				}
+				return
+			}
+
+			res, err := req.Post()
+			if err != nil {
+				logging.Error(minerurl, " submit transaction error. ", err.Error())
+				if int(atomic.AddInt32(&failedCount, 1)) == minersN {
+					close(failC)
+				}
+				return
+			}
+
+			if res.StatusCode != http.StatusOK {
+				logging.Error(minerurl, " submit transaction failed with status code ", res.StatusCode)
+				if int(atomic.AddInt32(&failedCount, 1)) == minersN {
+					resultC <- res
+				}
+				return
+			}
+
+			resultC <- res
+		}(miner)
+	}
+
+	select {
+	case <-ctx.Done():
+		node.Cache.Evict(txn.ClientID)
+		return "", newTxnError(ctx.Err(), txn.Hash, nil)
+	case <-failC:
+		logging.Error("failed to submit transaction")
+		node.Cache.Evict(txn.ClientID)
+		if err := ctx.Err(); err != nil {
+			return "", newTxnError(err, txn.Hash, nil)
+		}
+		ResetStableMiners()
+		return "", newTxnError(ErrTxnNotSubmitted, txn.Hash, nil)
+	case ret := <-resultC:
+		logging.Debug("finish txn submitting, ", ret.Url, ", Status: ", ret.Status, ", output:", ret.Body)
+		if ret.StatusCode != http.StatusOK {
+			node.Cache.Evict(txn.ClientID)
+			ResetStableMiners()
+			return "", newTxnError(ErrTxnRejected, txn.Hash, errors.New(ret.Body))
+		}
+		return ret.Body, nil
+	}
+}
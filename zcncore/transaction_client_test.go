@@ -0,0 +1,91 @@
+package zcncore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/0chain/gosdk/core/transaction"
+	"github.com/stretchr/testify/require"
+)
+
+func withStableMiners(t *testing.T, urls ...string) {
+	mGuard.Lock()
+	prev := miners
+	miners = urls
+	mGuard.Unlock()
+	t.Cleanup(func() {
+		mGuard.Lock()
+		miners = prev
+		mGuard.Unlock()
+	})
+}
+
+func TestTransactionClientSubmit(t *testing.T) {
+	var status int32 = http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+		w.Write([]byte(`{"entity":{"hash":"txn_hash"}}`)) //nolint: errcheck
+	}))
+	defer server.Close()
+	withStableMiners(t, server.URL)
+
+	newTxn := func() *transaction.Transaction {
+		txn := transaction.NewTransactionEntity("client", "chain", "key", 7)
+		txn.Hash, txn.Signature = "txn_hash", "signature"
+		return txn
+	}
+	c := &TransactionClient{}
+
+	r, err := c.Submit(context.Background(), newTxn())
+	require.NoError(t, err)
+	require.Equal(t, "txn_hash", r.Hash)
+	require.Equal(t, int64(7), r.Nonce)
+	require.Equal(t, `{"entity":{"hash":"txn_hash"}}`, r.SubmitOutput)
+
+	atomic.StoreInt32(&status, http.StatusBadRequest)
+	_, err = c.Submit(context.Background(), newTxn())
+	require.True(t, errors.Is(err, ErrTxnRejected))
+	var txnErr *TxnError
+	require.True(t, errors.As(err, &txnErr))
+	require.Equal(t, "txn_hash", txnErr.Hash)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = c.Submit(ctx, newTxn())
+	require.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestFillReceipt(t *testing.T) {
+	confirmation := func(status int) map[string]json.RawMessage {
+		txn, _ := json.Marshal(map[string]interface{}{
+			"hash":               "txn_hash",
+			"transaction_status": status,
+			"transaction_output": "insufficient balance",
+		})
+		conf, _ := json.Marshal(map[string]json.RawMessage{"txn": txn})
+		return map[string]json.RawMessage{"confirmation": conf}
+	}
+	header := &blockHeader{Hash: "block_hash", Round: 12}
+
+	r := &Receipt{Hash: "txn_hash"}
+	require.NoError(t, fillReceipt(r, header, confirmation(1)))
+	require.Equal(t, Success, r.Status)
+	require.Equal(t, int64(12), r.Round)
+	require.Equal(t, "block_hash", r.BlockHash)
+	require.Equal(t, "txn_hash", r.Transaction.Hash)
+	require.NotEmpty(t, r.Confirmation)
+
+	r = &Receipt{Hash: "txn_hash"}
+	err := fillReceipt(r, header, confirmation(2))
+	require.True(t, errors.Is(err, ErrTxnFailed))
+	require.Equal(t, ChargeableError, r.Status)
+	require.Contains(t, err.Error(), "insufficient balance")
+
+	err = fillReceipt(&Receipt{}, header, map[string]json.RawMessage{})
+	require.True(t, errors.Is(err, ErrTxnConfirmation))
+}
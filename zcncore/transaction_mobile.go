@@ -456,7 +456,7 @@ func (t *Transaction) createSmartContractTxn(address, methodName string, input i
 	}
 
 	// TODO: check if transaction is exempt to avoid unnecessary fee estimation
-	minFee, err := transaction.EstimateFee(t.txn, _config.chain.Miners, 0.2)
+	minFee, err := tf.estimateFee(t.txn)
 	if err != nil {
 		return err
 	}